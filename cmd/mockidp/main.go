@@ -0,0 +1,265 @@
+// Command mockidp avvia un identity provider OpenID Connect minimale per provare il login SSO
+// in locale, senza un provider reale.
+//
+// Approva automaticamente ogni richiesta di autorizzazione: l'utente é preso dal parametro
+// login_hint (o email) dell'URL di autorizzazione. Supporta solo authorization code + PKCE S256
+// e firma gli ID token con una chiave RSA generata all'avvio.
+//
+//	go run ./cmd/mockidp -addr :9000
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=merendels go run .
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"merendels-backend/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockKeyID   = "mock-idp-1"
+	codeTTL     = time.Minute
+	idTokenTTL  = 5 * time.Minute
+	defaultUser = "mario.rossi@example.com"
+)
+
+// authorizationCode dati legati a un codice emesso e non ancora scambiato
+type authorizationCode struct {
+	ClientID      string
+	RedirectURI   string
+	CodeChallenge string
+	Nonce         string
+	Email         string
+	Name          string
+	ExpiresAt     time.Time
+}
+
+type mockProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorizationCode
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "indirizzo di ascolto")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer pubblicato (deve coincidere con OIDC_ISSUER)")
+	clientID := flag.String("client-id", "merendels", "client ID accettato")
+	clientSecret := flag.String("client-secret", "", "segreto del client (vuoto = client pubblico, solo PKCE)")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("Error generating signing key:", err)
+	}
+
+	provider := &mockProvider{
+		issuer:       strings.TrimRight(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        make(map[string]authorizationCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	mux.HandleFunc("GET /authorize", provider.authorize)
+	mux.HandleFunc("POST /token", provider.token)
+
+	log.Printf("Mock OIDC provider listening on %s (issuer %s, client %s)", *addr, provider.issuer, provider.clientID)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// discovery gestisce GET /.well-known/openid-configuration
+func (p *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+// jwks gestisce GET /jwks
+func (p *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := p.key.PublicKey
+	writeJSON(w, http.StatusOK, utils.JWKSet{Keys: []utils.JWK{{
+		Kty: "RSA",
+		Kid: mockKeyID,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}}})
+}
+
+// authorize gestisce GET /authorize: approva subito e rimanda al client con code e state
+func (p *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != p.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(query.Get("login_hint")))
+	if email == "" {
+		email = strings.ToLower(strings.TrimSpace(query.Get("email")))
+	}
+	if email == "" {
+		email = defaultUser
+	}
+	name := query.Get("name")
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	code, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		http.Error(w, "error generating code", http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authorizationCode{
+		ClientID:      p.clientID,
+		RedirectURI:   redirectURI.String(),
+		CodeChallenge: query.Get("code_challenge"),
+		Nonce:         query.Get("nonce"),
+		Email:         email,
+		Name:          name,
+		ExpiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+
+	log.Printf("Authorized %s, redirecting to %s", email, redirectURI.Host+redirectURI.Path)
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token gestisce POST /token: scambia il codice (monouso) verificando PKCE e restituisce l'ID token
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", "malformed form")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	clientID, clientSecret, hasBasicAuth := r.BasicAuth()
+	if hasBasicAuth {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.clientSecret)) != 1 {
+		tokenError(w, "invalid_client", "client authentication failed")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	grant, exists := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !exists || time.Now().After(grant.ExpiresAt) {
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+	if grant.RedirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant", "redirect_uri mismatch")
+		return
+	}
+	if utils.PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.CodeChallenge {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"aud":            grant.ClientID,
+		"sub":            "mock|" + grant.Email,
+		"iat":            now.Unix(),
+		"exp":            now.Add(idTokenTTL).Unix(),
+		"nonce":          grant.Nonce,
+		"email":          grant.Email,
+		"email_verified": true,
+		"name":           grant.Name,
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = mockKeyID
+
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		tokenError(w, "server_error", "error signing id token")
+		return
+	}
+
+	accessToken, _ := utils.GenerateRandomToken(16)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     signed,
+	})
+}
+
+// tokenError risponde con un errore OAuth 2.0 (RFC 6749, sezione 5.2)
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,93 @@
+package config
+
+import "time"
+
+// AccessTokenTTL durata dei JWT di accesso
+func AccessTokenTTL() time.Duration {
+	return GetEnvDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL durata dei refresh token (rinnovati ad ogni utilizzo)
+func RefreshTokenTTL() time.Duration {
+	return GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// LoginMaxFailedAttempts tentativi falliti consecutivi prima del blocco dell'account
+func LoginMaxFailedAttempts() int {
+	return GetEnvInt("LOGIN_MAX_FAILED_ATTEMPTS", 5)
+}
+
+// LoginAttemptWindow finestra in cui vengono contati i tentativi falliti
+func LoginAttemptWindow() time.Duration {
+	return GetEnvDuration("LOGIN_ATTEMPT_WINDOW", time.Hour)
+}
+
+// LoginLockoutBase durata del primo blocco, raddoppia ad ogni ulteriore fallimento
+func LoginLockoutBase() time.Duration {
+	return GetEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute)
+}
+
+// LoginLockoutMax durata massima del blocco
+func LoginLockoutMax() time.Duration {
+	return GetEnvDuration("LOGIN_LOCKOUT_MAX", 30*time.Minute)
+}
+
+// OpenRegistrationEnabled abilita POST /api/auth/register (default: solo su invito)
+func OpenRegistrationEnabled() bool {
+	return GetEnvBool("OPEN_REGISTRATION", false)
+}
+
+// OpenRegistrationRoleID ruolo assegnato agli utenti auto-registrati (0 = nessun ruolo)
+func OpenRegistrationRoleID() int {
+	return GetEnvInt("OPEN_REGISTRATION_ROLE_ID", 0)
+}
+
+// PermissionCacheTTL per quanto tempo i permessi di un ruolo restano in cache
+func PermissionCacheTTL() time.Duration {
+	return GetEnvDuration("PERMISSION_CACHE_TTL", time.Minute)
+}
+
+// SecurityVersionCacheTTL per quanto il middleware si fida della versione di sicurezza in cache
+func SecurityVersionCacheTTL() time.Duration {
+	return GetEnvDuration("SECURITY_VERSION_CACHE_TTL", 30*time.Second)
+}
+
+// ImpersonationTTL durata dei token di impersonificazione (non rinnovabili)
+func ImpersonationTTL() time.Duration {
+	return GetEnvDuration("IMPERSONATION_TTL", 30*time.Minute)
+}
+
+// SessionCacheTTL per quanto il middleware si fida dello stato di una sessione in cache
+func SessionCacheTTL() time.Duration {
+	return GetEnvDuration("SESSION_CACHE_TTL", 30*time.Second)
+}
+
+// InvitationTTL validitá di default degli inviti
+func InvitationTTL() time.Duration {
+	return GetEnvDuration("INVITATION_TTL", 7*24*time.Hour)
+}
+
+// InvitationURL pagina del frontend che riceve il token dell'invito
+func InvitationURL() string {
+	return GetEnv("INVITATION_URL", "http://localhost:3000/accept-invitation")
+}
+
+// PasswordResetTokenTTL validitá dei link di reset della password
+func PasswordResetTokenTTL() time.Duration {
+	return GetEnvDuration("PASSWORD_RESET_TOKEN_TTL", 30*time.Minute)
+}
+
+// PasswordResetURL pagina del frontend che riceve il token di reset
+func PasswordResetURL() string {
+	return GetEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password")
+}
+
+// MFAChallengeTTL durata del token di challenge tra password e codice TOTP
+func MFAChallengeTTL() time.Duration {
+	return GetEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute)
+}
+
+// MFAIssuer nome mostrato nell'app di autenticazione
+func MFAIssuer() string {
+	return GetEnv("MFA_ISSUER", "Merendels")
+}
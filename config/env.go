@@ -0,0 +1,57 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"time"
+)
+
+// GetEnv legge una variabile d'ambiente con valore di fallback
+func GetEnv(key, defaultValue string) string {
+	return getEnv(key, defaultValue)
+}
+
+// GetEnvInt legge una variabile d'ambiente intera con valore di fallback
+func GetEnvInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s (%q), using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvBool legge una variabile d'ambiente booleana con valore di fallback
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s (%q), using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvDuration legge una durata (es. "15m", "720h") con valore di fallback
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,43 @@
+package config
+
+import "strings"
+
+// OIDCIssuer URL dell'identity provider aziendale (vuoto = SSO disattivato)
+func OIDCIssuer() string {
+	return strings.TrimRight(GetEnv("OIDC_ISSUER", ""), "/")
+}
+
+// OIDCClientID client ID registrato presso l'identity provider
+func OIDCClientID() string {
+	return GetEnv("OIDC_CLIENT_ID", "")
+}
+
+// OIDCClientSecret segreto del client (vuoto per client pubblici che usano solo PKCE)
+func OIDCClientSecret() string {
+	return GetEnv("OIDC_CLIENT_SECRET", "")
+}
+
+// OIDCRedirectURL pagina del frontend su cui l'identity provider rimanda con code e state
+func OIDCRedirectURL() string {
+	return GetEnv("OIDC_REDIRECT_URL", "http://localhost:3000/sso/callback")
+}
+
+// OIDCScopes scope richiesti all'identity provider
+func OIDCScopes() []string {
+	return strings.Fields(GetEnv("OIDC_SCOPES", "openid email profile"))
+}
+
+// OIDCJITProvisioning crea automaticamente l'utente al primo login SSO
+func OIDCJITProvisioning() bool {
+	return GetEnvBool("OIDC_JIT_PROVISIONING", false)
+}
+
+// OIDCDefaultRoleID ruolo assegnato agli utenti creati al primo login SSO (0 = nessun ruolo)
+func OIDCDefaultRoleID() int {
+	return GetEnvInt("OIDC_DEFAULT_ROLE_ID", 0)
+}
+
+// OIDCEnabled indica se il login SSO é configurato
+func OIDCEnabled() bool {
+	return OIDCIssuer() != "" && OIDCClientID() != ""
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitEnabled abilita il rate limiting (disattivabile es. per i test di carico)
+func RateLimitEnabled() bool {
+	return GetEnvBool("RATE_LIMIT_ENABLED", true)
+}
+
+// RateLimitFor limite di una policy: RATE_LIMIT_<NOME> nel formato "richieste/periodo"
+// (es. RATE_LIMIT_AUTH=30/1m), altrimenti i valori dichiarati nelle rotte. 0 richieste = nessun limite
+func RateLimitFor(name string, defaultRequests int, defaultPeriod time.Duration) (int, time.Duration) {
+	key := "RATE_LIMIT_" + strings.ToUpper(name)
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultRequests, defaultPeriod
+	}
+
+	parts := strings.SplitN(value, "/", 2)
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests < 0 {
+		log.Printf("Invalid rate limit for %s (%q), using default %d/%s", key, value, defaultRequests, defaultPeriod)
+		return defaultRequests, defaultPeriod
+	}
+	if len(parts) == 1 {
+		return requests, defaultPeriod
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		log.Printf("Invalid rate limit for %s (%q), using default %d/%s", key, value, defaultRequests, defaultPeriod)
+		return defaultRequests, defaultPeriod
+	}
+	return requests, period
+}
+
+// TrustedProxies reverse proxy di cui fidarsi per X-Forwarded-For (IP o CIDR separati da virgola).
+// Vuoto = nessuno: l'IP del client é quello della connessione e non puó essere falsificato con l'header
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
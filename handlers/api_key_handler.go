@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyHandler crea una nuova istanza dell'handler
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{
+		service: services.NewAPIKeyService(),
+	}
+}
+
+// CreateAPIKey gestisce POST /api/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	viewer := middleware.GetViewerFromContext(c)
+
+	key, err := h.service.CreateAPIKey(&request, userID, viewer.RoleID)
+	if err != nil {
+		switch err.Error() {
+		case "name is required", "at least one scope is required", "expires_in_days must be positive":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case "unknown scope":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unknown scope, use timbrature:write or reports:read",
+			})
+		case "cannot grant scopes with permissions you do not have":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully, store it now: it will not be shown again",
+		"data":    key,
+	})
+}
+
+// GetAPIKeys gestisce GET /api/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.service.GetAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API keys fetched successfully",
+		"data":    keys,
+		"count":   len(keys),
+	})
+}
+
+// RevokeAPIKey gestisce DELETE /api/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(id, userID); err != nil {
+		switch err.Error() {
+		case "api key not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "API key not found",
+			})
+		case "api key already revoked":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "API key already revoked",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked successfully",
+	})
+}
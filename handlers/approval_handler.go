@@ -47,7 +47,8 @@ func (h *ApprovalHandler) CreateApproval(c *gin.Context) {
 	request.ApproverID = approverID
 
 	// Chiama il service
-	createdApproval, err := h.approvalService.CreateApproval(approverID, &request)
+	claims, _ := middleware.GetUserClaimsFromContext(c)
+	createdApproval, err := h.approvalService.CreateApproval(approverID, claims.RoleID, &request)
 	if err != nil {
 		// Gestione errori specifici
 		switch err.Error() {
@@ -67,6 +68,14 @@ func (h *ApprovalHandler) CreateApproval(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Cannot approve your own requests",
 			})
+		case "non autorizzato ad approvare questo tipo di richiesta":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not authorized to approve this request type",
+			})
+		case "la richiesta non appartiene al tuo team":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Request does not belong to your team",
+			})
 		case "hai già dato un'approvazione per questa richiesta":
 			c.JSON(http.StatusConflict, gin.H{
 				"error": "You have already provided an approval for this request",
@@ -105,8 +114,11 @@ func (h *ApprovalHandler) GetAllApprovals(c *gin.Context) {
 		offset = 0
 	}
 
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	approvals, err := h.approvalService.GetAllApprovals(limit, offset)
+	approvals, err := h.approvalService.GetAllApprovals(viewer, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch approvals",
@@ -142,8 +154,11 @@ func (h *ApprovalHandler) GetApprovalByID(c *gin.Context) {
 		return
 	}
 
+	// Visibile a chi l'ha data e a chi vede la richiesta
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	approval, err := h.approvalService.GetApprovalByID(id)
+	approval, err := h.approvalService.GetApprovalByID(id, viewer)
 	if err != nil {
 		switch err.Error() {
 		case "ID approvazione non valido":
@@ -181,14 +196,21 @@ func (h *ApprovalHandler) GetApprovalsByRequestID(c *gin.Context) {
 		return
 	}
 
+	// Solo per le proprie richieste o per quelle degli utenti nello scope
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	approvals, err := h.approvalService.GetApprovalsByRequestID(requestID)
+	approvals, err := h.approvalService.GetApprovalsByRequestID(requestID, viewer)
 	if err != nil {
 		switch err.Error() {
 		case "ID richiesta non valido":
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Invalid request ID",
 			})
+		case "richiesta non trovata":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to fetch approvals for request",
@@ -286,8 +308,11 @@ func (h *ApprovalHandler) GetApprovalsByStatus(c *gin.Context) {
 		offset = 0
 	}
 
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	approvals, err := h.approvalService.GetApprovalsByStatus(status, limit, offset)
+	approvals, err := h.approvalService.GetApprovalsByStatus(viewer, status, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch approvals by status",
@@ -360,7 +385,8 @@ func (h *ApprovalHandler) UpdateApprovalStatus(c *gin.Context) {
 	}
 
 	// Chiama il service
-	updatedApproval, err := h.approvalService.UpdateApprovalStatus(id, approverID, status, updateRequest.Comments)
+	claims, _ := middleware.GetUserClaimsFromContext(c)
+	updatedApproval, err := h.approvalService.UpdateApprovalStatus(id, approverID, claims.RoleID, status, updateRequest.Comments)
 	if err != nil {
 		// Gestione errori specifici
 		switch err.Error() {
@@ -376,6 +402,14 @@ func (h *ApprovalHandler) UpdateApprovalStatus(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Not authorized to modify this approval",
 			})
+		case "non autorizzato ad approvare questo tipo di richiesta":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not authorized to approve this request type",
+			})
+		case "richiesta non trovata":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
 		case "non è possibile modificare un'approvazione già accettata (solo revoca)":
 			c.JSON(http.StatusConflict, gin.H{
 				"error": "Cannot modify an approved approval (only revocation allowed)",
@@ -427,7 +461,8 @@ func (h *ApprovalHandler) RevokeApproval(c *gin.Context) {
 	c.ShouldBindJSON(&revokeRequest)
 
 	// Chiama il service
-	revokedApproval, err := h.approvalService.RevokeApproval(id, approverID, revokeRequest.Reason)
+	claims, _ := middleware.GetUserClaimsFromContext(c)
+	revokedApproval, err := h.approvalService.RevokeApproval(id, approverID, claims.RoleID, revokeRequest.Reason)
 	if err != nil {
 		// Gestione errori specifici
 		switch err.Error() {
@@ -443,6 +478,10 @@ func (h *ApprovalHandler) RevokeApproval(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Not authorized to revoke this approval",
 			})
+		case "non autorizzato ad approvare questo tipo di richiesta":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not authorized to approve this request type",
+			})
 		case "è possibile revocare solo approvazioni accettate":
 			c.JSON(http.StatusConflict, gin.H{
 				"error": "Can only revoke approved approvals",
@@ -561,8 +600,11 @@ func (h *ApprovalHandler) GetRequestApprovalStatus(c *gin.Context) {
 
 // GetApprovalStatistics gestisce GET /api/approvals/statistics
 func (h *ApprovalHandler) GetApprovalStatistics(c *gin.Context) {
+	// Statistiche limitate alle richieste del team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	stats, err := h.approvalService.GetApprovalStatistics()
+	stats, err := h.approvalService.GetApprovalStatistics(viewer)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch approval statistics",
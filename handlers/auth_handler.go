@@ -1,22 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"merendels-backend/middleware"
 	"merendels-backend/models"
 	"merendels-backend/services"
+	"merendels-backend/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
 	authService *services.AuthService
+	passwordResetService *services.PasswordResetService
+	permissionService *services.PermissionService
 }
 
 // NewAuthHandler crea una nuova istanza dell'handler
 func NewAuthHandler() *AuthHandler {
+	authService := services.NewAuthService()
+
 	return &AuthHandler{
-		authService: services.NewAuthService(),
+		authService: authService,
+		passwordResetService: services.NewPasswordResetService(authService, utils.NewMailerFromConfig()),
+		permissionService: services.NewPermissionService(),
 	}
 }
 
@@ -34,7 +44,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Chiama il service
-	response, err := h.authService.Login(&request)
+	response, err := h.authService.Login(&request, clientInfo(c))
 	if err != nil {
 		// Gestione errori business
 		switch err.Error() {
@@ -46,7 +56,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid email or password",
 			})
+		case "account is disabled":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Account is disabled",
+			})
 		case "too many failed attempts, please try again later":
+			setRetryAfter(c, err)
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Too many failed attempts, please try again later",
 			})
@@ -59,6 +74,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Password corretta ma serve il secondo fattore (POST /api/auth/mfa/verify)
+	if response.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "MFA verification required",
+			"data": response,
+		})
+		return
+	}
+
 	// Login riuscito
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
@@ -66,6 +90,54 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// RefreshToken gestisce POST /api/auth/refresh
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var request models.RefreshTokenRequest
+
+	// Binding del JSON alla request
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Chiama il service
+	response, err := h.authService.RefreshToken(&request)
+	if err != nil {
+		switch err.Error() {
+		case "refresh token is required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Refresh token is required",
+			})
+		case "invalid refresh token", "refresh token expired":
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired refresh token",
+			})
+		case "account is disabled":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Account is disabled",
+			})
+		case "refresh token reuse detected":
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Refresh token already used, please log in again",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token refreshed successfully",
+		"data": response,
+	})
+}
+
 // Register gestisce POST api/auth/register
 func (h *AuthHandler) Register(c *gin.Context) {
 	// Struct per ricevere i dati completi di registrazione
@@ -83,6 +155,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			"error": "Invalid request format",
 			"details": err.Error(),
 		})
+		return
 	}
 
 	// Trasforma in strutture separate per il service
@@ -98,18 +171,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Chiama il service
-	response, err := h.authService.Register(authRequest, userRequest)
+	response, err := h.authService.Register(authRequest, userRequest, clientInfo(c))
 	if err != nil {
 		// Gestione errori business
 		switch err.Error() {
+		case "registration is disabled":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Registration is by invitation only",
+			})
 			case "password cannot be empty":
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Password cannot be empty",
 			})
-		case "password must be at least 6 characters":
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Password must be at least 6 characters",
-			})
 		case "name and email are required":
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Name and email are required",
@@ -123,10 +196,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 				"error": "Invalid role ID",
 			})
 		default:
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Internal server error",
-				"details": err.Error(),
-			})
+			if !writePasswordPolicyError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Internal server error",
+					"details": err.Error(),
+				})
+			}
 		}
 		return
 	}
@@ -165,7 +240,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Chiama il service
-	err := h.authService.ChangePassword(userID, request.CurrentPassword, request.NewPassword)
+	response, err := h.authService.ChangePassword(userID, request.CurrentPassword, request.NewPassword, clientInfo(c))
 	if err != nil {
 		// Gestione errori business
 		switch err.Error() {
@@ -173,22 +248,106 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Password must not be empty",
 			})
-		case "password must have at least 6 characters":
+		case "current password is incorrect":
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Password must have at least 6 characters",
+				"error": "Current password is incorrect",
+			})
+		case "too many failed attempts, please try again later":
+			setRetryAfter(c, err)
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many failed attempts, please try again later",
+			})
+		default:
+			if !writePasswordPolicyError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Internal server error",
+					"details": err.Error(),
+				})
+			}
+		}
+		return
+	}
+
+	// Cambio password riuscito, le altre sessioni sono state chiuse
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+		"data": response,
+	})
+}
+
+// RequestPasswordReset gestisce POST /api/auth/password-reset/request
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var request models.PasswordResetRequest
+
+	// Binding del JSON
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Chiama il service
+	err := h.passwordResetService.RequestReset(&request, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "email is required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Email is required",
 			})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Internal server error",
-				"details": err.Error(),
 			})
 		}
 		return
 	}
 
-	// Cambio password riuscito
+	// Stessa risposta sia per email esistenti che inesistenti
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// ConfirmPasswordReset gestisce POST /api/auth/password-reset/confirm
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var request models.PasswordResetConfirmRequest
+
+	// Binding del JSON
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Chiama il service
+	err := h.passwordResetService.ConfirmReset(&request, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "token and new password are required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Token and new password are required",
+			})
+		case "invalid or expired reset token":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid or expired reset token",
+			})
+		default:
+			if !writePasswordPolicyError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Internal server error",
+					"details": err.Error(),
+				})
+			}
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password changed successfully",
+		"message": "Password has been reset, please log in with the new password",
 	})
 }
 
@@ -222,6 +381,16 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	// Permessi del ruolo, usati dal front-end per mostrare solo le funzioni consentite
+	permissions, err := h.permissionService.GetPermissionsForRole(claims.RoleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve permissions",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Costruisce il profilo completo
 	profile := gin.H{
 		"user_id":         user.ID,
@@ -230,6 +399,15 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		"role_id":         user.RoleID,
 		"manager_id":      user.ManagerID,   // ← BONUS ANCHE QUESTO
 		"hierarchy_level": claims.HierarchyLevel,
+		"permissions":     permissions,
+	}
+
+	// Token di impersonificazione: il front-end mostra chi sta operando per conto dell'utente
+	if actor, ok := middleware.GetActorFromContext(c); ok {
+		profile["impersonated_by"] = gin.H{
+			"user_id": actor.UserID,
+			"email":   actor.Email,
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -240,7 +418,60 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 // Logout gestiste POST /api/auth/logout (Protetto da JWT)
 func (h *AuthHandler) Logout(c *gin.Context) {
-	//TODO: Da fare, eliminando il token.
+	// Estrae claims del token corrente
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	// Revoca il token e i refresh token della sessione corrente
+	err := h.authService.Logout(claims)
+	if err != nil {
+		switch err.Error() {
+		case "token has no jti":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Token cannot be revoked, please log in again",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logout successful",
+	})
+}
+
+// LogoutAll gestisce POST /api/auth/logout-all (Protetto da JWT)
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	// Revoca tutti i token emessi finora per l'utente, compreso quello corrente
+	err := h.authService.LogoutAll(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all sessions",
+	})
 }
 
 // ValidateToken gestisce POST /api/auth/validate (per verificare se un token é valido)
@@ -256,4 +487,129 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 			"email": email,
 		},
 	})
+}
+
+// GetJWKS gestisce GET /.well-known/jwks.json (chiavi pubbliche per verificare i token)
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	// Le chiavi cambiano solo al riavvio, i client possono tenerle in cache brevemente
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.GetJWKS())
+}
+
+// GetLoginAttempts gestisce GET /api/auth/users/:id/login-attempts
+func (h *AuthHandler) GetLoginAttempts(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	// Parametri di paginazione
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	attempts, total, lockout, err := h.authService.GetLoginAttempts(userID, limit, offset)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login attempts fetched successfully",
+		"data": attempts,
+		"count": len(attempts),
+		"lockout": lockout,
+		"pagination": gin.H{
+			"limit": limit,
+			"offset": offset,
+			"total": total,
+		},
+	})
+}
+
+// UnlockUser gestisce POST /api/auth/users/:id/unlock
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	adminID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	if err := h.authService.UnlockUser(userID, adminID, c.ClientIP()); err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
+
+// clientInfo estrae IP e user agent della richiesta per i log di sicurezza
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// setRetryAfter imposta l'header Retry-After se l'errore é un blocco account
+func setRetryAfter(c *gin.Context, err error) {
+	var lockoutErr *services.LockoutError
+	if errors.As(err, &lockoutErr) {
+		c.Header("Retry-After", fmt.Sprint(lockoutErr.RetryAfter()))
+	}
+}
+
+// writePasswordPolicyError risponde 400 se l'errore é una violazione della password policy
+func writePasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "Password does not meet the password policy",
+		"details": policyErr.Message,
+	})
+	return true
 }
\ No newline at end of file
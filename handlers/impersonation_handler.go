@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationHandler struct {
+	service *services.ImpersonationService
+}
+
+// NewImpersonationHandler crea una nuova istanza dell'handler
+func NewImpersonationHandler() *ImpersonationHandler {
+	return &ImpersonationHandler{
+		service: services.NewImpersonationService(),
+	}
+}
+
+// StartImpersonation gestisce POST /api/impersonations
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.StartImpersonationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.StartImpersonation(claims, &request, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "reason is required", "cannot impersonate yourself":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User not found",
+			})
+		case "user is not active":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "User is not active",
+			})
+		case "cannot impersonate while impersonating", "cannot impersonate another administrator",
+			"cannot impersonate a user with a higher role", "cannot impersonate a user with permissions you do not have":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Impersonation started, the token is read-only",
+		"data":    response,
+	})
+}
+
+// GetSessions gestisce GET /api/impersonations
+func (h *ImpersonationHandler) GetSessions(c *gin.Context) {
+	// Parametri di paginazione
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	sessions, total, err := h.service.GetSessions(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Impersonation sessions fetched successfully",
+		"data":    sessions,
+		"count":   len(sessions),
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+		},
+	})
+}
+
+// GetSessionAuditLog gestisce GET /api/impersonations/:id
+func (h *ImpersonationHandler) GetSessionAuditLog(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	session, calls, err := h.service.GetSessionAuditLog(id)
+	if err != nil {
+		switch err.Error() {
+		case "impersonation session not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Impersonation session not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Impersonation audit log fetched successfully",
+		"data": gin.H{
+			"session": session,
+			"calls":   calls,
+		},
+		"count": len(calls),
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"merendels-backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationHandler struct {
+	service *services.InvitationService
+}
+
+// NewInvitationHandler crea una nuova istanza dell'handler
+func NewInvitationHandler() *InvitationHandler {
+	return &InvitationHandler{
+		service: services.NewInvitationService(services.NewAuthService(), utils.NewMailerFromConfig()),
+	}
+}
+
+// CreateInvitation gestisce POST /api/invitations
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	invitation, err := h.service.CreateInvitation(&request, userID)
+	if err != nil {
+		switch err.Error() {
+		case "name and email are required", "expires_in_hours must be greater than 0":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case "invalid role_id":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid role ID",
+			})
+		case "invalid manager_id":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid manager ID",
+			})
+		case "cannot invite with a role higher than your own", "cannot invite with a role that has permissions you do not have":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		case "email already registered":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Email already registered",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation sent successfully",
+		"data":    invitation,
+	})
+}
+
+// GetInvitations gestisce GET /api/invitations?status=pending
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	invitations, err := h.service.GetInvitations(c.Query("status"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid status filter":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid status filter, use PENDING, ACCEPTED, REVOKED or EXPIRED",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitations fetched successfully",
+		"data":    invitations,
+		"count":   len(invitations),
+	})
+}
+
+// RevokeInvitation gestisce DELETE /api/invitations/:id
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	if err := h.service.RevokeInvitation(id); err != nil {
+		switch err.Error() {
+		case "invitation not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Invitation not found",
+			})
+		case "invitation already accepted":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Invitation already accepted",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation revoked successfully",
+	})
+}
+
+// AcceptInvitation gestisce POST /api/auth/invitations/accept
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var request models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.AcceptInvitation(&request, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "token and password are required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Token and password are required",
+			})
+		case "invalid or expired invitation":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid or expired invitation",
+			})
+		case "email already registered":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Email already registered",
+			})
+		default:
+			if !writePasswordPolicyError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Internal server error",
+					"details": err.Error(),
+				})
+			}
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Account created successfully",
+		"data":    response,
+	})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	authService *services.AuthService
+	mfaService  *services.MFAService
+}
+
+// NewMFAHandler crea una nuova istanza dell'handler
+func NewMFAHandler() *MFAHandler {
+	authService := services.NewAuthService()
+
+	return &MFAHandler{
+		authService: authService,
+		mfaService:  services.NewMFAService(authService),
+	}
+}
+
+// Verify gestisce POST /api/auth/mfa/verify (secondo passo del login)
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var request models.MFAVerifyRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.VerifyMFA(&request, clientInfo(c))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"data":    response,
+	})
+}
+
+// GetStatus gestisce GET /api/auth/mfa
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	status, err := h.mfaService.GetStatus(userID)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "MFA status retrieved successfully",
+		"data":    status,
+	})
+}
+
+// Setup gestisce POST /api/auth/mfa/setup (avvio enrollment volontario)
+func (h *MFAHandler) Setup(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	enrollment, err := h.mfaService.BeginEnrollment(claims.UserID, claims.Email)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Scan the provisioning URI with an authenticator app, then confirm with a code",
+		"data":    enrollment,
+	})
+}
+
+// Activate gestisce POST /api/auth/mfa/activate
+func (h *MFAHandler) Activate(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.Activate(userID, request.Code, c.ClientIP())
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "MFA enabled successfully, store the recovery codes in a safe place",
+		"data":    gin.H{"recovery_codes": recoveryCodes},
+	})
+}
+
+// RegenerateRecoveryCodes gestisce POST /api/auth/mfa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(userID, request.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Recovery codes regenerated successfully",
+		"data":    gin.H{"recovery_codes": recoveryCodes},
+	})
+}
+
+// Disable gestisce POST /api/auth/mfa/disable
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.MFADisableRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.mfaService.Disable(userID, &request, c.ClientIP()); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "MFA disabled successfully",
+	})
+}
+
+// writeMFAError traduce gli errori di business della 2FA in risposte HTTP
+func writeMFAError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "mfa code is required":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "MFA code is required",
+		})
+	case "invalid mfa code":
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid MFA code",
+		})
+	case "invalid or expired mfa token":
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired MFA token, please log in again",
+		})
+	case "current password is incorrect":
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Current password is incorrect",
+		})
+	case "too many failed attempts, please try again later":
+		setRetryAfter(c, err)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many failed attempts, please try again later",
+		})
+	case "account is disabled":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Account is disabled",
+		})
+	case "mfa is required for your role":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "MFA is required for your role and cannot be disabled",
+		})
+	case "mfa already enabled", "mfa not enabled", "mfa enrollment not started":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OfficeSiteHandler struct {
+	service *services.OfficeSiteService
+}
+
+// NewOfficeSiteHandler crea una nuova istanza dell'handler
+func NewOfficeSiteHandler() *OfficeSiteHandler {
+	return &OfficeSiteHandler{
+		service: services.NewOfficeSiteService(),
+	}
+}
+
+// GetOfficeSites gestisce GET /api/office-sites
+func (h *OfficeSiteHandler) GetOfficeSites(c *gin.Context) {
+	sites, err := h.service.GetOfficeSites()
+	if err != nil {
+		writeOfficeSiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Office sites fetched successfully",
+		"data":    sites,
+		"count":   len(sites),
+	})
+}
+
+// CreateOfficeSite gestisce POST /api/office-sites
+func (h *OfficeSiteHandler) CreateOfficeSite(c *gin.Context) {
+	var request models.SaveOfficeSiteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	site, err := h.service.CreateOfficeSite(&request)
+	if err != nil {
+		writeOfficeSiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Office site created successfully",
+		"data":    site,
+	})
+}
+
+// UpdateOfficeSite gestisce PUT /api/office-sites/:id
+func (h *OfficeSiteHandler) UpdateOfficeSite(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	var request models.SaveOfficeSiteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	site, err := h.service.UpdateOfficeSite(id, &request)
+	if err != nil {
+		writeOfficeSiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Office site updated successfully",
+		"data":    site,
+	})
+}
+
+// writeOfficeSiteError traduce gli errori della gestione sedi in risposte HTTP
+func writeOfficeSiteError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "office site not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Office site not found",
+		})
+	case "name is required", "invalid coordinates", "latitude and longitude must be provided together",
+		"radius must be greater than 0":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "invalid geofence policy":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid policy. Use REJECT, FLAG or ALLOW",
+		})
+	case "office site name already exists":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	service *services.OIDCService
+}
+
+// NewOIDCHandler crea una nuova istanza dell'handler
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		service: services.NewOIDCService(services.NewAuthService()),
+	}
+}
+
+// BeginLogin gestisce GET /api/auth/oidc/login
+func (h *OIDCHandler) BeginLogin(c *gin.Context) {
+	response, err := h.service.BeginLogin()
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "SSO login started",
+		"data":    response,
+	})
+}
+
+// Callback gestisce POST /api/auth/oidc/callback
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var request models.OIDCCallbackRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.CompleteLogin(&request, clientInfo(c))
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	// Identitá verificata ma serve il secondo fattore (POST /api/auth/mfa/verify)
+	if response.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "MFA verification required",
+			"data":    response,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"data":    response,
+	})
+}
+
+// writeOIDCError traduce gli errori del login SSO in risposte HTTP
+func writeOIDCError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "sso is not configured":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "SSO is not configured",
+		})
+	case "invalid or expired sso state":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid or expired SSO state, please start the login again",
+		})
+	case "sso authentication failed":
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "SSO authentication failed",
+		})
+	case "sso user not provisioned":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "No Merendels account is linked to this identity",
+		})
+	case "account is disabled":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Account is disabled",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	service *services.PermissionService
+}
+
+// NewPermissionHandler crea una nuova istanza dell'handler
+func NewPermissionHandler() *PermissionHandler {
+	return &PermissionHandler{
+		service: services.NewPermissionService(),
+	}
+}
+
+// GetAllPermissions gestisce GET /api/permissions
+func (h *PermissionHandler) GetAllPermissions(c *gin.Context) {
+	permissions, err := h.service.GetAllPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Permissions fetched successfully",
+		"data":    permissions,
+		"count":   len(permissions),
+	})
+}
+
+// GetRolePermissions gestisce GET /api/user-roles/:id/permissions
+func (h *PermissionHandler) GetRolePermissions(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	rolePermissions, err := h.service.GetRolePermissions(roleID)
+	if err != nil {
+		writePermissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role permissions fetched successfully",
+		"data":    rolePermissions,
+	})
+}
+
+// UpdateRolePermissions gestisce PUT /api/user-roles/:id/permissions
+func (h *PermissionHandler) UpdateRolePermissions(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	var request models.UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rolePermissions, err := h.service.UpdateRolePermissions(roleID, claims.RoleID, &request)
+	if err != nil {
+		writePermissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Role permissions updated successfully",
+		"data":       rolePermissions,
+		"updated_by": claims.Email,
+	})
+}
+
+// writePermissionError traduce gli errori della gestione permessi in risposte HTTP
+func writePermissionError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid role ID":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid role ID",
+		})
+	case "role not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Role not found",
+		})
+	case "unknown permission":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown permission code, see GET /api/permissions",
+		})
+	case "cannot remove roles:manage from your own role":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You cannot remove roles:manage from your own role",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	service *services.PrivacyService
+}
+
+// NewPrivacyHandler crea una nuova istanza dell'handler
+func NewPrivacyHandler() *PrivacyHandler {
+	return &PrivacyHandler{
+		service: services.NewPrivacyService(),
+	}
+}
+
+// ExportUserData gestisce GET /api/privacy/users/:id/export?format=json|zip
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	adminID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid format, use json or zip",
+		})
+		return
+	}
+
+	export, err := h.service.ExportUserData(id, adminID)
+	if err != nil {
+		writePrivacyError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("merendels-user-%d-%s", id, export.GeneratedAt.Format("20060102-150405"))
+
+	if format == "zip" {
+		archive, err := h.service.BuildExportArchive(export)
+		if err != nil {
+			writePrivacyError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+		c.Data(http.StatusOK, "application/zip", archive)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// EraseUser gestisce POST /api/privacy/users/:id/erase
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	adminID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.EraseUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.EraseUser(id, adminID, &request)
+	if err != nil {
+		writePrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User personal data erased successfully",
+		"data":    result,
+	})
+}
+
+// GetPrivacyRequests gestisce GET /api/privacy/requests
+func (h *PrivacyHandler) GetPrivacyRequests(c *gin.Context) {
+	// Parametri di paginazione
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	requests, total, err := h.service.GetPrivacyRequests(limit, offset)
+	if err != nil {
+		writePrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Privacy requests fetched successfully",
+		"data":    requests,
+		"count":   len(requests),
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  total,
+		},
+	})
+}
+
+// writePrivacyError traduce gli errori di business di esportazione e cancellazione in risposte HTTP
+func writePrivacyError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case "confirmation email does not match":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Confirmation email does not match the user's email",
+		})
+	case "cannot erase your own account":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You cannot erase your own account",
+		})
+	case "user must be terminated before erasure", "user is already erased":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -118,8 +118,11 @@ func (h *RequestHandler) GetAllRequests(c *gin.Context) {
 		offset = 0
 	}
 
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	requests, err := h.requestService.GetAllRequests(limit, offset)
+	requests, err := h.requestService.GetAllRequests(viewer, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch requests",
@@ -155,8 +158,11 @@ func (h *RequestHandler) GetRequestByID(c *gin.Context) {
 		return
 	}
 
+	// Visibile al richiedente e a chi ha l'utente nello scope
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	request, err := h.requestService.GetRequestByID(id)
+	request, err := h.requestService.GetRequestByID(id, viewer)
 	if err != nil {
 		switch err.Error() {
 		case "ID richiesta non valido":
@@ -257,8 +263,11 @@ func (h *RequestHandler) GetRequestsByDateRange(c *gin.Context) {
 		return
 	}
 
+	// Le proprie richieste e quelle del team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	requests, err := h.requestService.GetRequestsByDateRange(startDate, endDate)
+	requests, err := h.requestService.GetRequestsByDateRange(viewer, startDate, endDate)
 	if err != nil {
 		switch err.Error() {
 		case "data inizio non può essere successiva alla data fine":
@@ -287,8 +296,11 @@ func (h *RequestHandler) GetRequestsByDateRange(c *gin.Context) {
 
 // GetPendingRequests gestisce GET /api/requests/pending
 func (h *RequestHandler) GetPendingRequests(c *gin.Context) {
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	requests, err := h.requestService.GetPendingRequests()
+	requests, err := h.requestService.GetPendingRequests(viewer)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch pending requests",
@@ -453,8 +465,11 @@ func (h *RequestHandler) GetRequestWithApprovals(c *gin.Context) {
 		return
 	}
 
+	// Visibile al richiedente e a chi ha l'utente nello scope
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	requestWithApprovals, err := h.requestService.GetRequestWithApprovals(id)
+	requestWithApprovals, err := h.requestService.GetRequestWithApprovals(id, viewer)
 	if err != nil {
 		switch err.Error() {
 		case "ID richiesta non valido":
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	service *services.SessionService
+}
+
+// NewSessionHandler crea una nuova istanza dell'handler
+func NewSessionHandler() *SessionHandler {
+	return &SessionHandler{
+		service: services.NewSessionService(),
+	}
+}
+
+// GetSessions gestisce GET /api/auth/sessions
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := h.service.GetSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions fetched successfully",
+		"data":    sessions,
+		"count":   len(sessions),
+	})
+}
+
+// RevokeSession gestisce DELETE /api/auth/sessions/:id
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	current, err := h.service.RevokeUserSession(claims.UserID, id, claims.SessionID)
+	if err != nil {
+		switch err.Error() {
+		case "session not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session signed out successfully",
+		"current": current, // true se l'utente ha chiuso la sessione che sta usando
+	})
+}
+
+// RevokeOtherSessions gestisce DELETE /api/auth/sessions
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	revoked, err := h.service.RevokeOtherSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		switch err.Error() {
+		case "current token has no session":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Current token has no session, please log in again",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions signed out successfully",
+		"count":   revoked,
+	})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TimbratureCorrectionHandler struct {
+	service *services.TimbratureCorrectionService
+}
+
+// NewTimbratureCorrectionHandler crea una nuova istanza dell'handler
+func NewTimbratureCorrectionHandler() *TimbratureCorrectionHandler {
+	return &TimbratureCorrectionHandler{
+		service: services.NewTimbratureCorrectionService(),
+	}
+}
+
+// CreateCorrection gestisce POST /api/timbrature/corrections
+func (h *TimbratureCorrectionHandler) CreateCorrection(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var request models.CreateTimbraturaCorrectionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	correction, err := h.service.CreateCorrection(userID, &request)
+	if err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Correction requested successfully",
+		"data":    correction,
+	})
+}
+
+// GetMyCorrections gestisce GET /api/timbrature/corrections/me
+func (h *TimbratureCorrectionHandler) GetMyCorrections(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	limit, offset := parseCorrectionPagination(c)
+
+	corrections, err := h.service.GetMyCorrections(userID, limit, offset)
+	if err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corrections fetched successfully",
+		"data":    corrections,
+		"count":   len(corrections),
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// GetCorrections gestisce GET /api/timbrature/corrections?status= (correzioni del team)
+func (h *TimbratureCorrectionHandler) GetCorrections(c *gin.Context) {
+	limit, offset := parseCorrectionPagination(c)
+
+	var status *models.CorrectionStatus
+	if statusParam := c.Query("status"); statusParam != "" {
+		value := models.CorrectionStatus(statusParam)
+		status = &value
+	}
+
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
+	corrections, err := h.service.GetCorrections(viewer, status, limit, offset)
+	if err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corrections fetched successfully",
+		"data":    corrections,
+		"count":   len(corrections),
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// GetCorrection gestisce GET /api/timbrature/corrections/:id
+func (h *TimbratureCorrectionHandler) GetCorrection(c *gin.Context) {
+	id, ok := parseCorrectionID(c)
+	if !ok {
+		return
+	}
+
+	correction, err := h.service.GetCorrection(id, middleware.GetViewerFromContext(c))
+	if err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Correction fetched successfully",
+		"data":    correction,
+	})
+}
+
+// CancelCorrection gestisce POST /api/timbrature/corrections/:id/cancel
+func (h *TimbratureCorrectionHandler) CancelCorrection(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, ok := parseCorrectionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelCorrection(id, userID); err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Correction cancelled successfully",
+	})
+}
+
+// ApproveCorrection gestisce POST /api/timbrature/corrections/:id/approve
+func (h *TimbratureCorrectionHandler) ApproveCorrection(c *gin.Context) {
+	h.reviewCorrection(c, true)
+}
+
+// RejectCorrection gestisce POST /api/timbrature/corrections/:id/reject
+func (h *TimbratureCorrectionHandler) RejectCorrection(c *gin.Context) {
+	h.reviewCorrection(c, false)
+}
+
+// reviewCorrection approva o rifiuta una correzione
+func (h *TimbratureCorrectionHandler) reviewCorrection(c *gin.Context, approve bool) {
+	if _, exists := middleware.GetUserIDFromContext(c); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, ok := parseCorrectionID(c)
+	if !ok {
+		return
+	}
+
+	// Binding del JSON della request (comment é opzionale)
+	var request models.ReviewTimbraturaCorrectionRequest
+	c.ShouldBindJSON(&request)
+
+	viewer := middleware.GetViewerFromContext(c)
+
+	var correction *models.TimbraturaCorrection
+	var err error
+	message := "Correction approved and applied successfully"
+	if approve {
+		correction, err = h.service.ApproveCorrection(id, viewer, request.Comment)
+	} else {
+		correction, err = h.service.RejectCorrection(id, viewer, request.Comment)
+		message = "Correction rejected successfully"
+	}
+	if err != nil {
+		writeCorrectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    correction,
+	})
+}
+
+func parseCorrectionID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func parseCorrectionPagination(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// writeCorrectionError traduce gli errori di business delle correzioni in risposte HTTP
+func writeCorrectionError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "correction not found", "timbratura not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case "reason is required", "invalid correction type", "invalid correction status",
+		"timbratura_id is required", "timbratura_id is not allowed when adding a timbratura",
+		"timestamp, action_type and location are required", "correction does not change the timbratura",
+		"timestamp cannot be in the future":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "invalid action type":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid action type. Use ENTRATA, USCITA, PAUSA_INIZIO or PAUSA_FINE",
+		})
+	case "invalid location":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid location. Use UFFICIO or SMART",
+		})
+	case "cannot review your own correction", "correction does not belong to your team":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+	case "correction is not pending", "timbratura already has a pending correction",
+		"correction breaks the timbrature sequence":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
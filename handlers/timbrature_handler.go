@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"merendels-backend/config"
 	"merendels-backend/middleware"
 	"merendels-backend/models"
 	"merendels-backend/services"
@@ -46,43 +45,9 @@ func (h *TimbratureHandler) CreateTimbrature (c *gin.Context) {
 	}
 
 	// Chiama il servizio (utilizzando lo userID del token e non della request)
-	response, err := h.service.CreateTimbrature(userID, &request)
+	response, warnings, err := h.service.CreateTimbrature(userID, &request)
 	if err != nil {
-		// Gestione errore specifici
-		switch err.Error() {
-		case "invalid action type":
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid action type. Use ENTRATA or USCITA",
-			})
-		case "invalid location":
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid location. Use UFFICIO or SMART",
-			})
-		case "cannot enter twice in a row - you must exit first":
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "You already entered today. You must exit first",
-			})
-		case "cannot exit twice in a row - you must enter first":
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "You already exited. You must enter first",
-			})
-		case "first timbratura must be ENTRATA":
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Your first timbratura must be ENTRATA",
-			})
-		default:
-			// Controllo per errori che contengono pattern specifici
-			if contains := err.Error(); len(contains) > 20 && contains[:20] == "you already have a " {
-				c.JSON(http.StatusConflict, gin.H{
-					"error": err.Error(),
-				})
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Internal server error",
-					"details": err.Error(),
-				})
-			}
-		}
+		writeCreateTimbraturaError(c, err)
 		return
 	}
 
@@ -91,10 +56,41 @@ func (h *TimbratureHandler) CreateTimbrature (c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Timbrature created successfully",
 		"data": response,
+		"warnings": warnings,
 		"created_by": email,
 	})
 }
 
+// CreateKioskTimbratura gestisce POST /api/timbrature/kiosk (API key con scope timbrature:write)
+func (h *TimbratureHandler) CreateKioskTimbratura(c *gin.Context) {
+	apiKey, _ := middleware.GetAPIKeyFromContext(c)
+
+	var request models.KioskTimbratureRequest
+
+	// Binding del JSON della request
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Qui lo user_id arriva dalla request: il kiosk timbra per conto del dipendente
+	response, warnings, err := h.service.CreateKioskTimbratura(&request, apiKey)
+	if err != nil {
+		writeCreateTimbraturaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Timbrature created successfully",
+		"data": response,
+		"warnings": warnings,
+		"created_by": apiKey.Name,
+	})
+}
+
 // GetMyTimbrature gestisce GET /api/timbrature/me
 func (h *TimbratureHandler) GetMyTimbrature(c *gin.Context) {
 	// Estrae user_id dal JWT Token
@@ -271,6 +267,69 @@ func (h *TimbratureHandler) GetMyLastTimbrature(c *gin.Context) {
 	})
 }
 
+// GetMyWorkSummary gestisce GET /api/timbrature/me/summary?from=&to= (default: mese corrente fino a oggi)
+func (h *TimbratureHandler) GetMyWorkSummary(c *gin.Context) {
+	// Estrae user_id dal JWT token
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := now
+
+	if fromParam := c.Query("from"); fromParam != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromParam, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid from date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+		from = parsed
+	}
+	if toParam := c.Query("to"); toParam != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toParam, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid to date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+		to = parsed
+	}
+
+	// Chiama il service
+	summary, err := h.service.GetWorkSummary(userID, from, to)
+	if err != nil {
+		switch err.Error() {
+		case "invalid date range: from is after to":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid date range: from is after to",
+			})
+		case "date range too long":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Date range too long, maximum 366 days",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to compute work summary",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Work summary computed successfully",
+		"data": summary,
+	})
+}
+
 // GetAllTimbrature gestisce GET /api/timbrature (solo per admin/manager)
 func (h *TimbratureHandler) GetAllTimbrature(c *gin.Context) {
 	// Parametri di paginazione
@@ -287,8 +346,11 @@ func (h *TimbratureHandler) GetAllTimbrature(c *gin.Context) {
 		offset = 0
 	}
 
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
 	// Chiama il service
-	responses, err := h.service.GetAllTimbrature(limit, offset)
+	responses, err := h.service.GetAllTimbrature(viewer, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch all timbrature",
@@ -313,52 +375,20 @@ func (h *TimbratureHandler) GetAllTimbrature(c *gin.Context) {
 }
 
 func (h *TimbratureHandler) GetEmployeesStatus(c *gin.Context) {
-	//TODO: da spostare query nella repository
-	query := `SELECT id, name, email, role_id, manager_id FROM users ORDER BY name`
-	rows, err := config.DB.Query(query)
+	// Solo utenti attivi del proprio team (o di tutta l'azienda con company:read_all)
+	viewer := middleware.GetViewerFromContext(c)
+
+	result, err := h.service.GetEmployeesStatus(viewer)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
-	defer rows.Close()
-
-	var result []gin.H
-	today := time.Now().Format("2006-01-02")
-
-	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.RoleID, &user.ManagerID)
-		if err != nil {
-			continue
-		}
-
-		// Prendo l'ultima timbratura dell'utente oggi
-		lastTimbrature, _ := h.service.GetLastTimbrature(user.ID)
-		
-		isWorking := false
-		workMode := "unknown"
-		
-		if lastTimbrature != nil {
-			// Controllo se è di oggi
-			lastDate := lastTimbrature.Timestamp.Format("2006-01-02")
-			if lastDate == today {
-				isWorking = lastTimbrature.ActionType == models.ActionEnter
-				workMode = string(lastTimbrature.Location) // "UFFICIO" o "SMART"
-			}
-		}
-
-		result = append(result, gin.H{
-			"id": user.ID,
-			"name": user.Name,
-			"is_working": isWorking,
-			"work_mode": workMode,
-		})
-	}
 
 	c.JSON(http.StatusOK, result)
 }
 
 // DeleteTimbratura gestisce DELETE /api/timbrature/:id (solo per admin)
+// La timbratura viene annullata, non cancellata: il motivo é obbligatorio e finisce nello storico
 func (h *TimbratureHandler) DeleteTimbratura(c *gin.Context) {
 	// Estrae ID dal parametro URL
 	idParam := c.Param("id")
@@ -370,26 +400,248 @@ func (h *TimbratureHandler) DeleteTimbratura(c *gin.Context) {
 		return
 	}
 
+	var request models.VoidTimbraturaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format, reason is required",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Log per audit
 	adminEmail, _ := middleware.GetUserEmailFromContext(c)
 	adminID, _ := middleware.GetUserIDFromContext(c)
 
 	// Chiama il service
-	err = h.service.DeleteTimbratura(id)
+	voided, err := h.service.DeleteTimbratura(id, middleware.GetViewerFromContext(c), request.Reason)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete timbratura",
-			"details": err.Error(),
-		})
+		switch err.Error() {
+		case "reason is required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Reason is required",
+			})
+		case "timbratura not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Timbratura not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to delete timbratura",
+				"details": err.Error(),
+			})
+		}
 		return
 	}
 
 	// Successo
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Timbratura deleted successfully",
+		"data": voided,
 		"deleted_by": gin.H{
 			"user_id": adminID,
 			"email": adminEmail,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// GetUserDayHistory gestisce GET /api/timbrature/users/:id/history?date=YYYY-MM-DD (default oggi)
+func (h *TimbratureHandler) GetUserDayHistory(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	date := time.Now()
+	if dateParam := c.Query("date"); dateParam != "" {
+		date, err = time.ParseInLocation("2006-01-02", dateParam, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
+	history, err := h.service.GetDayHistory(viewer, userID, date)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "User not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch timbrature history",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Timbrature history fetched successfully",
+		"data": history,
+	})
+}
+
+// GetGeofenceFlagged gestisce GET /api/timbrature/geofence-flagged - timbrature fuori sede da verificare
+func (h *TimbratureHandler) GetGeofenceFlagged(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// I responsabili vedono solo il proprio team, salvo permesso company:read_all
+	viewer := middleware.GetViewerFromContext(c)
+
+	responses, err := h.service.GetGeofenceFlagged(viewer, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch flagged timbrature",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Flagged timbrature fetched successfully",
+		"data": responses,
+		"count": len(responses),
+		"pagination": gin.H{
+			"limit": limit,
+			"offset": offset,
+		},
+	})
+}
+
+// ReviewGeofence gestisce POST /api/timbrature/:id/geofence-review - conferma una timbratura fuori sede
+func (h *TimbratureHandler) ReviewGeofence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	err = h.service.ReviewGeofence(id, middleware.GetViewerFromContext(c))
+	if err != nil {
+		switch err.Error() {
+		case "timbratura not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Timbratura not found",
+			})
+		case "cannot review your own timbratura":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "You cannot review your own timbratura",
+			})
+		case "timbratura is not flagged":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Timbratura is not flagged for review",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to review timbratura",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	reviewerEmail, _ := middleware.GetUserEmailFromContext(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Timbratura reviewed successfully",
+		"reviewed_by": reviewerEmail,
+	})
+}
+
+// writeCreateTimbraturaError traduce gli errori di creazione timbratura in risposte HTTP
+func writeCreateTimbraturaError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid action type":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid action type. Use ENTRATA, USCITA, PAUSA_INIZIO or PAUSA_FINE",
+		})
+	case "invalid location":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid location. Use UFFICIO or SMART",
+		})
+	case "cannot enter twice in a row - you must exit first":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You already entered today. You must exit first",
+		})
+	case "cannot exit twice in a row - you must enter first":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You already exited. You must enter first",
+		})
+	case "you are on break - end the break first":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You are on break. You must end the break first",
+		})
+	case "cannot start a break - you are not working":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You are not working. You must enter first",
+		})
+	case "cannot start a break - you are already on break":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You are already on break",
+		})
+	case "cannot end a break - you are not on break":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "You are not on break. You must start a break first",
+		})
+	case "break too short":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Break too short for your work contract",
+		})
+	case "latitude and longitude must be provided together", "invalid coordinates":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "position is required for office timbrature":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Position (latitude and longitude) is required for UFFICIO timbrature",
+		})
+	case "timbratura is outside the office geofence":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You are not at an office site. Use SMART or punch from the office",
+		})
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case "user is not active":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "User is not active",
+		})
+	case "first timbratura must be ENTRATA":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Your first timbratura must be ENTRATA",
+		})
+	default:
+		// Controllo per errori che contengono pattern specifici
+		if contains := err.Error(); len(contains) > 20 && contains[:20] == "you already have a " {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+				"details": err.Error(),
+			})
+		}
+	}
+}
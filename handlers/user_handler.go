@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"merendels-backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	service *services.UserService
+}
+
+// NewUserHandler crea una nuova istanza dell'handler
+func NewUserHandler() *UserHandler {
+	return &UserHandler{
+		service: services.NewUserService(),
+	}
+}
+
+// GetUsers gestisce GET /api/users?search=&role_id=&manager_id=&status=&limit=&offset=
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	filter := &models.UserFilter{
+		Search: c.Query("search"),
+		Status: models.UserStatus(c.Query("status")),
+	}
+
+	if roleID, err := strconv.Atoi(c.Query("role_id")); err == nil {
+		filter.RoleID = &roleID
+	}
+	if managerID, err := strconv.Atoi(c.Query("manager_id")); err == nil {
+		filter.ManagerID = &managerID
+	}
+
+	// Parametri di paginazione
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	filter.Limit = limit
+	filter.Offset = offset
+
+	users, total, err := h.service.GetUsers(filter)
+	if err != nil {
+		switch err.Error() {
+		case "invalid status filter":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid status filter",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Users fetched successfully",
+		"data":    users,
+		"count":   len(users),
+		"pagination": gin.H{
+			"limit":  filter.Limit,
+			"offset": filter.Offset,
+			"total":  total,
+		},
+	})
+}
+
+// GetUserByID gestisce GET /api/users/:id
+func (h *UserHandler) GetUserByID(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUserByID(id)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User fetched successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUser gestisce PUT /api/users/:id
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.service.UpdateUser(id, actor, &request)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUserRole gestisce PUT /api/users/:id/role
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.service.UpdateUserRole(id, actor, &request)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUserManager gestisce PUT /api/users/:id/manager
+func (h *UserHandler) UpdateUserManager(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserManagerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.service.UpdateUserManager(id, actor, &request)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User manager updated successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUserWorkContract gestisce PUT /api/users/:id/work-contract
+func (h *UserHandler) UpdateUserWorkContract(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserWorkContractRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.service.UpdateUserWorkContract(id, actor, &request)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User work contract updated successfully",
+		"data":    user,
+	})
+}
+
+// DeactivateUser gestisce POST /api/users/:id/deactivate
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.DeactivateUser(id, actor)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deactivated successfully",
+		"data":    user,
+	})
+}
+
+// ReactivateUser gestisce POST /api/users/:id/reactivate
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.ReactivateUser(id, actor)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User reactivated successfully",
+		"data":    user,
+	})
+}
+
+// TerminateUser gestisce POST /api/users/:id/terminate
+func (h *UserHandler) TerminateUser(c *gin.Context) {
+	actor, ok := actorClaims(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.TerminateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.TerminateUser(id, actor, &request)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User terminated successfully",
+		"data":    result,
+	})
+}
+
+// GetSettlement gestisce GET /api/users/:id/settlement
+func (h *UserHandler) GetSettlement(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	settlement, err := h.service.GetSettlement(id)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Settlement fetched successfully",
+		"data":    settlement,
+	})
+}
+
+// actorClaims legge i claims di chi agisce, servono per confrontarne ruolo e permessi con quelli dell'utente gestito
+func actorClaims(c *gin.Context) (*utils.JWTClaims, bool) {
+	claims, exists := middleware.GetUserClaimsFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return nil, false
+	}
+	return claims, true
+}
+
+// parseUserID legge l'ID utente dall'URL, rispondendo 400 se non valido
+func parseUserID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// writeUserError traduce gli errori di business della gestione utenti in risposte HTTP
+func writeUserError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case "invalid ID: must be greater than 0", "name and email are required",
+		"user cannot be their own manager", "manager is not active":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "invalid role_id":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid role ID",
+		})
+	case "invalid manager_id":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid manager ID",
+		})
+	case "invalid work_contract_id":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid work contract ID",
+		})
+	case "cannot change your own role", "cannot assign a role higher than your own",
+		"cannot assign a role that has permissions you do not have",
+		"cannot manage a user with a higher role", "cannot manage a user with permissions you do not have":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+	case "email already registered":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Email already registered",
+		})
+	case "manager assignment would create a cycle":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Manager assignment would create a cycle in the reporting chain",
+		})
+	case "cannot deactivate your own account":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You cannot deactivate your own account",
+		})
+	case "cannot terminate your own account":
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You cannot terminate your own account",
+		})
+	case "invalid termination date format, use YYYY-MM-DD", "termination date cannot be in the future":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "settlement not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Settlement not found",
+		})
+	case "user is not active", "user is not inactive", "user is already terminated":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
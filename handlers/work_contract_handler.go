@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"merendels-backend/models"
+	"merendels-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkContractHandler struct {
+	service *services.WorkContractService
+}
+
+// NewWorkContractHandler crea una nuova istanza dell'handler
+func NewWorkContractHandler() *WorkContractHandler {
+	return &WorkContractHandler{
+		service: services.NewWorkContractService(),
+	}
+}
+
+// GetWorkContracts gestisce GET /api/work-contracts
+func (h *WorkContractHandler) GetWorkContracts(c *gin.Context) {
+	contracts, err := h.service.GetWorkContracts()
+	if err != nil {
+		writeWorkContractError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Work contracts fetched successfully",
+		"data":    contracts,
+		"count":   len(contracts),
+	})
+}
+
+// CreateWorkContract gestisce POST /api/work-contracts
+func (h *WorkContractHandler) CreateWorkContract(c *gin.Context) {
+	var request models.SaveWorkContractRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	contract, err := h.service.CreateWorkContract(&request)
+	if err != nil {
+		writeWorkContractError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Work contract created successfully",
+		"data":    contract,
+	})
+}
+
+// UpdateWorkContract gestisce PUT /api/work-contracts/:id
+func (h *WorkContractHandler) UpdateWorkContract(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ID format",
+		})
+		return
+	}
+
+	var request models.SaveWorkContractRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	contract, err := h.service.UpdateWorkContract(id, &request)
+	if err != nil {
+		writeWorkContractError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Work contract updated successfully",
+		"data":    contract,
+	})
+}
+
+// writeWorkContractError traduce gli errori della gestione contratti in risposte HTTP
+func writeWorkContractError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "work contract not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Work contract not found",
+		})
+	case "name is required", "minutes cannot be negative":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case "invalid break policy":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid break policy. Use WARN or ENFORCE",
+		})
+	case "work contract name already exists", "cannot unset the default work contract":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+	}
+}
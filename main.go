@@ -4,6 +4,7 @@ import (
 	"log"
 	"merendels-backend/config"
 	"merendels-backend/routes"
+	"merendels-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,14 +14,25 @@ func main() {
 	config.ConnectDatabase()
 	defer config.DB.Close()
 
+	// Caricamento chiavi di firma JWT (dopo il caricamento del .env)
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatal("Error loading JWT signing keys:", err)
+	}
+
 	// Setup Gin router
 	router := gin.Default()
 
+	// IP del client per rate limiting e log: X-Forwarded-For solo dai proxy fidati
+	if err := router.SetTrustedProxies(config.TrustedProxies()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Middleware CORS per permette richieste dal frontend
 	router.Use(func(ctx *gin.Context) {
 		ctx.Header("Access-Control-Allow-Origin", "*")
 		ctx.Header("Access-Control-Allow-Methods","GET, POST, PUT, DELETE, OPTIONS")
-		ctx.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		ctx.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		ctx.Header("Access-Control-Expose-Headers", "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if ctx.Request.Method == "OPTIONS" {
 			ctx.AbortWithStatus(204)
@@ -39,12 +51,24 @@ func main() {
 		})
 	})
 
+	// Chiavi pubbliche per la verifica dei token da parte di altri servizi
+	routes.SetupWellKnownRoutes(&router.RouterGroup)
+
 	// Setup API Routes group
 	api := router.Group("/api")
 	{
 		routes.SetupAuthRoutes(api)        // Rotte autenticazione: /api/auth/*
 		routes.SetupUserRoleRoutes(api)    // Rotte user roles: /api/user-roles/*
+		routes.SetupPermissionRoutes(api)  // Rotte permessi: /api/permissions
+		routes.SetupAPIKeyRoutes(api)      // Rotte API key: /api/api-keys/*
+		routes.SetupInvitationRoutes(api)  // Rotte inviti: /api/invitations/*
+		routes.SetupUserRoutes(api)        // Rotte gestione utenti: /api/users/*
+		routes.SetupWorkContractRoutes(api) // Rotte contratti di lavoro: /api/work-contracts/*
+		routes.SetupOfficeSiteRoutes(api)  // Rotte sedi e geofence: /api/office-sites/*
+		routes.SetupImpersonationRoutes(api) // Rotte impersonificazione: /api/impersonations/*
+		routes.SetupPrivacyRoutes(api)     // Rotte GDPR: /api/privacy/*
 		routes.SetupTimbratureRoutes(api)  // Rotte timbrature: /api/timbrature/*
+		routes.SetupTimbratureCorrectionRoutes(api) // Rotte correzioni timbrature: /api/timbrature/corrections/*
 		routes.SetupRequestRoutes(api)     // Rotte richieste ferie/permessi: /api/requests/*
 		routes.SetupApprovalRoutes(api)    // Rotte approvazioni: /api/approvals/*
 	}
@@ -1,17 +1,33 @@
 package middleware
 
 import (
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/services"
 	"merendels-backend/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware verifica il JWT Token in ogni richiesta
+// AuthMiddleware verifica il JWT Token in ogni richiesta.
+// I client non umani possono autenticarsi con una API key nell'header X-API-Key: in quel caso
+// nel context c'é solo la chiave (niente user_id/claims) e le rotte la accettano tramite
+// RequirePermission (permessi concessi dagli scope) o RequireScope
 func AuthMiddleware() gin.HandlerFunc {
+	revocationService := services.NewTokenRevocationService()
+	securityVersionService := services.NewSecurityVersionService()
+	apiKeyService := services.NewAPIKeyService()
+	impersonationService := services.NewImpersonationService()
+	sessionService := services.NewSessionService()
+
 	return func(c *gin.Context) {
 		// Estrae header Authorization
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && c.GetHeader("X-API-Key") != "" {
+			authenticateAPIKey(c, apiKeyService)
+			return
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Authorization header required",
@@ -40,6 +56,60 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Verifica che il token non sia stato revocato (logout)
+		revoked, err := revocationService.IsRevoked(claims)
+		if err != nil {
+			log.Printf("Error checking token revocation: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Unable to verify token",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
+		// Sessione chiusa da un altro dispositivo (o dall'utente stesso): il token non vale piú
+		active, err := sessionService.IsActive(claims, models.ClientInfo{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()})
+		if err != nil {
+			log.Printf("Error checking session: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Unable to verify token",
+			})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been signed out",
+			})
+			c.Abort()
+			return
+		}
+
+		// Ruolo, manager, password o stato cambiati dopo l'emissione: i claims non sono piú affidabili
+		current, err := securityVersionService.IsCurrent(claims)
+		if err != nil {
+			log.Printf("Error checking security version: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Unable to verify token",
+			})
+			c.Abort()
+			return
+		}
+		if !current {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token is outdated, please refresh it",
+			})
+			c.Abort()
+			return
+		}
+
 		// Salvo i claims nel context di Gin cosí da poterci accedere con gli handler
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
@@ -47,12 +117,50 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("hierarchy_level", claims.HierarchyLevel)
 		c.Set("claims", claims)
 
+		// Token di impersonificazione: sola lettura e ogni chiamata finisce nell'audit con entrambe le identitá
+		if claims.IsImpersonated() {
+			if !impersonationService.IsAllowed(c.Request.Method, c.FullPath()) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "This action is not allowed while impersonating a user",
+				})
+				c.Abort()
+			} else {
+				c.Next()
+			}
+			impersonationService.RecordCall(claims, c.Request.Method, c.Request.URL.RequestURI(), c.Writer.Status(), c.ClientIP())
+			return
+		}
+
 		// Autorizzazione ok, quindi continua
 		c.Next()
 	}
 }
 
+// authenticateAPIKey valida l'header X-API-Key e salva la chiave nel context
+func authenticateAPIKey(c *gin.Context, apiKeyService *services.APIKeyService) {
+	key, err := apiKeyService.Authenticate(c.GetHeader("X-API-Key"))
+	if err != nil {
+		log.Printf("Error checking api key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to verify api key",
+		})
+		c.Abort()
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid, expired or revoked api key",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("api_key", key)
+	c.Next()
+}
+
 // RequireHierarchyLevel middleware per autorizzazioni basate su hierarchy_level
+// Deprecated: le rotte usano RequirePermission, i permessi si assegnano ai ruoli da /api/user-roles/:id/permissions
 func RequireHierarchyLevel(minLevel int) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Recupera hierarchy_level dal context (dopo AuthMiddleware)
@@ -113,6 +221,40 @@ func GetUserEmailFromContext(c *gin.Context) (string, bool) {
 	return emailStr, ok
 }
 
+// GetAPIKeyFromContext estrae la API key dal context (solo per richieste con X-API-Key)
+func GetAPIKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	key, exists := c.Get("api_key")
+	if !exists {
+		return nil, false
+	}
+
+	apiKey, ok := key.(*models.APIKey)
+	return apiKey, ok
+}
+
+// GetViewerFromContext restituisce chi sta consultando i dati (utente o API key), per il calcolo del team
+func GetViewerFromContext(c *gin.Context) services.Viewer {
+	if apiKey, ok := GetAPIKeyFromContext(c); ok {
+		return services.Viewer{APIKey: apiKey}
+	}
+
+	var viewer services.Viewer
+	if claims, ok := GetUserClaimsFromContext(c); ok {
+		viewer.UserID = claims.UserID
+		viewer.RoleID = claims.RoleID
+	}
+	return viewer
+}
+
+// GetActorFromContext restituisce l'operatore se la richiesta usa un token di impersonificazione
+func GetActorFromContext(c *gin.Context) (*utils.ActorClaim, bool) {
+	claims, ok := GetUserClaimsFromContext(c)
+	if !ok || !claims.IsImpersonated() {
+		return nil, false
+	}
+	return claims.Actor, true
+}
+
 // GetUserClaimsFromContext estrae tutti i claims dal context
 func GetUserClaimsFromContext(c *gin.Context) (*utils.JWTClaims, bool) {
 	claims, exists := c.Get("claims")
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"log"
+	"merendels-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission middleware per autorizzazioni basate sui permessi del ruolo (dopo AuthMiddleware)
+func RequirePermission(permission string) gin.HandlerFunc {
+	return RequireAnyPermission(permission)
+}
+
+// RequireAnyPermission lascia passare se il ruolo possiede almeno uno dei permessi indicati
+func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	permissionService := services.NewPermissionService()
+
+	return func(c *gin.Context) {
+		// API key: valgono i permessi concessi dai suoi scope
+		if apiKey, ok := GetAPIKeyFromContext(c); ok {
+			for _, permission := range permissions {
+				if apiKey.GrantsPermission(permission) {
+					c.Next()
+					return
+				}
+			}
+
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":    "API key scopes do not grant this permission",
+				"required": permissions,
+			})
+			c.Abort()
+			return
+		}
+
+		claims, exists := GetUserClaimsFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			allowed, err := permissionService.HasPermission(claims.RoleID, permission)
+			if err != nil {
+				log.Printf("Error checking permission %s for user ID %d: %v", permission, claims.UserID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Unable to verify permissions",
+				})
+				c.Abort()
+				return
+			}
+			if allowed {
+				// Permesso verificato
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":    "Insufficient permissions",
+			"required": permissions,
+		})
+		c.Abort()
+	}
+}
+
+// RequireScope rotte riservate ai client con API key che possiedono lo scope indicato
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, ok := GetAPIKeyFromContext(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This endpoint requires an API key",
+			})
+			c.Abort()
+			return
+		}
+
+		if !apiKey.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":    "Insufficient API key scope",
+				"required": scope,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
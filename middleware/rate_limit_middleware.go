@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"merendels-backend/config"
+	"merendels-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey ricava dalla richiesta la chiave su cui contare le richieste
+type RateLimitKey func(c *gin.Context) string
+
+// RateLimitPolicy limite di un gruppo di rotte (dichiarato nel package routes).
+// Requests e Period sono i default, sovrascrivibili con RATE_LIMIT_<NAME>
+type RateLimitPolicy struct {
+	Name     string
+	Requests int
+	Period   time.Duration
+	Key      RateLimitKey
+}
+
+// RateLimitByIP conta le richieste per indirizzo IP (rotte pubbliche)
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByUser conta le richieste per utente o API key (dopo AuthMiddleware), altrimenti per IP
+func RateLimitByUser(c *gin.Context) string {
+	if apiKey, ok := GetAPIKeyFromContext(c); ok {
+		return fmt.Sprintf("key:%d", apiKey.ID)
+	}
+	if userID, ok := GetUserIDFromContext(c); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimit middleware token bucket: ogni istanza ha i propri contatori, quindi ogni gruppo di
+// rotte che la usa ha un budget separato. Risponde 429 con gli header RateLimit-* e Retry-After
+func RateLimit(policy RateLimitPolicy) gin.HandlerFunc {
+	requests, period := config.RateLimitFor(policy.Name, policy.Requests, policy.Period)
+	if !config.RateLimitEnabled() || requests <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	limiter := utils.NewRateLimiter(requests, period)
+	keyFunc := policy.Key
+	if keyFunc == nil {
+		keyFunc = RateLimitByIP
+	}
+	policyHeader := fmt.Sprintf("%d;w=%d", requests, ceilSeconds(period))
+
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		result := limiter.Allow(key)
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			log.Printf("Rate limit %s exceeded by %s on %s %s", policy.Name, key, c.Request.Method, c.FullPath())
+
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests, please retry later",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds arrotonda per eccesso ai secondi interi usati dagli header
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
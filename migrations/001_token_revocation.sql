@@ -0,0 +1,15 @@
+-- Denylist dei token JWT revocati (logout della singola sessione)
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        VARCHAR(64) PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Revoca di tutti i token di un utente emessi prima di revoked_before (logout da tutte le sessioni)
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id        INTEGER PRIMARY KEY REFERENCES users(id),
+    revoked_before TIMESTAMPTZ NOT NULL
+);
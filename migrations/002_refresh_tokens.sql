@@ -0,0 +1,16 @@
+-- Refresh token opachi (salvati come hash SHA-256), ruotati ad ogni utilizzo.
+-- family_id raggruppa tutti i token nati dallo stesso login (un dispositivo)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users(id),
+    family_id   VARCHAR(64) NOT NULL,
+    token_hash  VARCHAR(64) NOT NULL UNIQUE,
+    device_name VARCHAR(255),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at     TIMESTAMPTZ,
+    revoked_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
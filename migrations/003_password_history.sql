@@ -0,0 +1,10 @@
+-- Storico delle password precedenti, per impedirne il riuso
+CREATE TABLE IF NOT EXISTS password_history (
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER NOT NULL REFERENCES users(id),
+    password_hash VARCHAR(255) NOT NULL,
+    salt          VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id, created_at DESC);
@@ -0,0 +1,22 @@
+-- Autenticazione a due fattori (TOTP)
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id        INTEGER PRIMARY KEY REFERENCES users(id),
+    secret         VARCHAR(64) NOT NULL,
+    enabled_at     TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Codici di recupero monouso (hash SHA-256)
+CREATE TABLE IF NOT EXISTS user_mfa_recovery_codes (
+    id        SERIAL PRIMARY KEY,
+    user_id   INTEGER NOT NULL REFERENCES users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_mfa_recovery_codes_user_id ON user_mfa_recovery_codes (user_id);
+
+-- 2FA obbligatoria per ruolo: di default per chi puó approvare ferie e cancellare timbrature
+ALTER TABLE user_roles ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE user_roles SET mfa_required = TRUE WHERE hierarchy_level <= 1;
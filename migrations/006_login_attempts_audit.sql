@@ -0,0 +1,14 @@
+-- Audit dei tentativi di login: client e tentativi su email non registrate
+ALTER TABLE auth_login_attempts ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE auth_login_attempts ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE auth_login_attempts ADD COLUMN IF NOT EXISTS ip_address VARCHAR(64);
+ALTER TABLE auth_login_attempts ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512);
+
+-- Valori di result: SUCCESS, FAILURE, LOCKED, MFA_PENDING
+ALTER TABLE auth_login_attempts ALTER COLUMN result TYPE VARCHAR(20) USING result::text;
+
+CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_user_id ON auth_login_attempts (user_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_email ON auth_login_attempts (email, timestamp DESC);
+
+-- Sblocco manuale: i fallimenti precedenti a questo istante non contano piú
+ALTER TABLE auth_credentials ADD COLUMN IF NOT EXISTS lockout_reset_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+-- Inviti alla registrazione (il token é salvato solo come hash SHA-256)
+CREATE TABLE IF NOT EXISTS user_invitations (
+    id               SERIAL PRIMARY KEY,
+    email            VARCHAR(255) NOT NULL,
+    name             VARCHAR(255) NOT NULL,
+    role_id          INTEGER REFERENCES user_roles(id),
+    manager_id       INTEGER REFERENCES users(id),
+    token_hash       VARCHAR(64) NOT NULL UNIQUE,
+    invited_by       INTEGER NOT NULL REFERENCES users(id),
+    expires_at       TIMESTAMPTZ NOT NULL,
+    accepted_at      TIMESTAMPTZ,
+    accepted_user_id INTEGER REFERENCES users(id),
+    revoked_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_invitations_email ON user_invitations (email);
@@ -0,0 +1,4 @@
+-- Stato dell'utente: gli utenti non vengono piú cancellati ma disattivati
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE';
+
+CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users (manager_id);
@@ -0,0 +1,13 @@
+-- Cessazione del rapporto di lavoro (status = 'TERMINATED')
+ALTER TABLE users ADD COLUMN IF NOT EXISTS termination_date DATE;
+
+-- Liquidazione finale di ferie e permessi residui
+CREATE TABLE IF NOT EXISTS leave_settlements (
+    id                SERIAL PRIMARY KEY,
+    user_id           INTEGER NOT NULL UNIQUE REFERENCES users(id),
+    termination_date  DATE NOT NULL,
+    residual_holidays REAL NOT NULL,
+    residual_permits  REAL NOT NULL,
+    created_by        INTEGER NOT NULL REFERENCES users(id),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,32 @@
+-- Permessi nominali assegnati ai ruoli (sostituiscono i controlli su hierarchy_level)
+CREATE TABLE IF NOT EXISTS permissions (
+    code        VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id         INTEGER NOT NULL REFERENCES user_roles(id) ON DELETE CASCADE,
+    permission_code VARCHAR(64) NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role_id, permission_code)
+);
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:manage', 'Gestione anagrafica, ruolo, manager e stato degli utenti'),
+    ('roles:manage', 'Gestione dei ruoli e della matrice dei permessi'),
+    ('invitations:manage', 'Invio e revoca degli inviti'),
+    ('security:manage', 'Consultazione tentativi di login e sblocco account'),
+    ('requests:read_all', 'Consultazione di tutte le richieste di ferie/permessi'),
+    ('approvals:read_all', 'Consultazione di tutte le approvazioni e statistiche'),
+    ('approvals:approve_ferie', 'Approvazione delle richieste di ferie'),
+    ('approvals:approve_permesso', 'Approvazione delle richieste di permesso'),
+    ('timbrature:read_all', 'Consultazione delle timbrature e dello stato dei dipendenti'),
+    ('timbrature:delete', 'Eliminazione delle timbrature')
+ON CONFLICT (code) DO NOTHING;
+
+-- Mantiene il comportamento precedente: i ruoli con hierarchy_level <= 1 hanno tutti i permessi
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, p.code
+FROM user_roles r CROSS JOIN permissions p
+WHERE r.hierarchy_level <= 1
+ON CONFLICT DO NOTHING;
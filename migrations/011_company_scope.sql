@@ -0,0 +1,15 @@
+-- I responsabili vedono solo i dati dei propri sottoposti (diretti e indiretti),
+-- la visibilitá su tutta l'azienda diventa un permesso separato (HR/direzione)
+INSERT INTO permissions (code, description) VALUES
+    ('company:read_all', 'Consultazione dei dati di tutti i dipendenti, non solo del proprio team')
+ON CONFLICT (code) DO NOTHING;
+
+-- Di default solo i ruoli al vertice della gerarchia
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, 'company:read_all'
+FROM user_roles r
+WHERE r.hierarchy_level = (SELECT MIN(hierarchy_level) FROM user_roles)
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_requests_user_id ON requests (user_id);
+CREATE INDEX IF NOT EXISTS idx_timbrature_user_id ON timbrature (user_id);
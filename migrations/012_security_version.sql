@@ -0,0 +1,3 @@
+-- Versione di sicurezza dell'utente, copiata nei JWT (claim "sv"): ogni modifica di ruolo,
+-- manager, password o stato la incrementa e i token emessi prima non sono piú accettati
+ALTER TABLE users ADD COLUMN IF NOT EXISTS security_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,24 @@
+-- API key per client non umani (export paghe, kiosk badge), salvate solo come hash SHA-256
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    key_prefix   VARCHAR(16) NOT NULL,
+    key_hash     VARCHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    created_by   INTEGER NOT NULL REFERENCES users(id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+INSERT INTO permissions (code, description) VALUES
+    ('api_keys:manage', 'Creazione e revoca delle API key')
+ON CONFLICT (code) DO NOTHING;
+
+-- Chi gestisce i ruoli gestisce anche le API key
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT role_id, 'api_keys:manage'
+FROM role_permissions
+WHERE permission_code = 'roles:manage'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,26 @@
+-- Login SSO OpenID Connect in corso: state (solo hash), nonce e code_verifier PKCE
+CREATE TABLE IF NOT EXISTS oidc_login_requests (
+    id            SERIAL PRIMARY KEY,
+    state_hash    VARCHAR(64) NOT NULL UNIQUE,
+    nonce         VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    consumed_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_requests_expires_at ON oidc_login_requests (expires_at);
+
+-- Collegamento tra utente e identitá presso l'identity provider (issuer + subject)
+CREATE TABLE IF NOT EXISTS user_identities (
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer        VARCHAR(255) NOT NULL,
+    subject       VARCHAR(255) NOT NULL,
+    email         VARCHAR(255),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
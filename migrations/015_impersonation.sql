@@ -0,0 +1,40 @@
+-- Impersonificazione di un utente da parte del supporto (token in sola lettura con claim act)
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id             SERIAL PRIMARY KEY,
+    actor_id       INTEGER NOT NULL REFERENCES users(id),
+    target_user_id INTEGER NOT NULL REFERENCES users(id),
+    reason         TEXT NOT NULL,
+    ip_address     VARCHAR(45),
+    started_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    ended_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_actor_id ON impersonation_sessions (actor_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_target_user_id ON impersonation_sessions (target_user_id);
+
+-- Ogni chiamata eseguita con un token di impersonificazione, con entrambe le identitá
+CREATE TABLE IF NOT EXISTS impersonation_audit_log (
+    id             SERIAL PRIMARY KEY,
+    session_id     INTEGER NOT NULL REFERENCES impersonation_sessions(id) ON DELETE CASCADE,
+    actor_id       INTEGER NOT NULL REFERENCES users(id),
+    target_user_id INTEGER NOT NULL REFERENCES users(id),
+    method         VARCHAR(10) NOT NULL,
+    path           VARCHAR(512) NOT NULL,
+    status_code    INTEGER NOT NULL,
+    ip_address     VARCHAR(45),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_log_session_id ON impersonation_audit_log (session_id, created_at);
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:impersonate', 'Accesso in sola lettura come un altro utente (supporto)')
+ON CONFLICT (code) DO NOTHING;
+
+-- Chi consulta la sicurezza degli account puó anche impersonificare
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT role_id, 'users:impersonate'
+FROM role_permissions
+WHERE permission_code = 'security:manage'
+ON CONFLICT DO NOTHING;
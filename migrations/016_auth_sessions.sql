@@ -0,0 +1,22 @@
+-- Sessioni di login (una per famiglia di refresh token): dispositivo, IP, user agent e ultimo accesso.
+-- session_id coincide con family_id dei refresh token e con il claim sid dei token di accesso
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id   VARCHAR(64) NOT NULL UNIQUE,
+    device_name  VARCHAR(255),
+    ip_address   VARCHAR(45),
+    user_agent   VARCHAR(512),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user_id ON auth_sessions (user_id) WHERE revoked_at IS NULL;
+
+-- I login giá attivi diventano sessioni, altrimenti i loro token verrebbero rifiutati
+INSERT INTO auth_sessions (user_id, session_id, device_name, created_at, last_seen_at)
+SELECT user_id, family_id, device_name, created_at, created_at
+FROM refresh_tokens
+WHERE used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+ON CONFLICT (session_id) DO NOTHING;
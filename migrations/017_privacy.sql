@@ -0,0 +1,26 @@
+-- Diritti dell'interessato (GDPR): esportazione dei dati personali e cancellazione per pseudonimizzazione.
+-- L'utente cancellato resta in tabella (timbrature, richieste e saldi sono obblighi di legge), senza dati identificativi
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
+
+-- Registro delle richieste evase (accountability del titolare)
+CREATE TABLE IF NOT EXISTS privacy_requests (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id),
+    request_type VARCHAR(20) NOT NULL, -- EXPORT, ERASURE
+    reason       TEXT,
+    performed_by INTEGER NOT NULL REFERENCES users(id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_privacy_requests_user_id ON privacy_requests (user_id);
+
+INSERT INTO permissions (code, description) VALUES
+    ('privacy:manage', 'Esportazione e cancellazione dei dati personali (GDPR)')
+ON CONFLICT (code) DO NOTHING;
+
+-- Chi gestisce gli utenti gestisce anche le richieste dell'interessato
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT role_id, 'privacy:manage'
+FROM role_permissions
+WHERE permission_code = 'users:manage'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,43 @@
+-- Richieste di correzione delle timbrature: il dipendente propone di aggiungere, modificare o togliere
+-- una timbratura, il responsabile approva. La timbratura originale non viene mai cancellata ma annullata
+CREATE TABLE IF NOT EXISTS timbrature_corrections (
+    id                    SERIAL PRIMARY KEY,
+    user_id               INTEGER NOT NULL REFERENCES users(id),
+    correction_type       VARCHAR(10) NOT NULL, -- ADD, MODIFY, REMOVE
+    timbratura_id         INTEGER REFERENCES timbrature(id) ON DELETE SET NULL, -- Timbratura da modificare/togliere
+    timestamp             TIMESTAMPTZ, -- Valori proposti (ADD, MODIFY)
+    action_type           VARCHAR(10),
+    location              VARCHAR(10),
+    reason                TEXT NOT NULL,
+    status                VARCHAR(10) NOT NULL DEFAULT 'PENDING', -- PENDING, APPROVED, REJECTED, CANCELLED
+    reviewer_id           INTEGER REFERENCES users(id),
+    review_comment        TEXT,
+    reviewed_at           TIMESTAMPTZ,
+    applied_timbratura_id INTEGER REFERENCES timbrature(id) ON DELETE SET NULL, -- Timbratura creata all'approvazione
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_timbrature_corrections_user_id ON timbrature_corrections (user_id);
+CREATE INDEX IF NOT EXISTS idx_timbrature_corrections_status ON timbrature_corrections (status);
+
+-- Una sola correzione in attesa per timbratura
+CREATE UNIQUE INDEX IF NOT EXISTS idx_timbrature_corrections_pending_target
+    ON timbrature_corrections (timbratura_id) WHERE status = 'PENDING';
+
+-- Timbrature annullate da una correzione approvata: restano per lo storico, escluse da letture e calcoli
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS voided_at TIMESTAMPTZ;
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS voided_by_correction_id INTEGER REFERENCES timbrature_corrections(id);
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS correction_id INTEGER REFERENCES timbrature_corrections(id); -- Correzione che l'ha creata
+
+CREATE INDEX IF NOT EXISTS idx_timbrature_user_active ON timbrature (user_id, timestamp) WHERE voided_at IS NULL;
+
+INSERT INTO permissions (code, description) VALUES
+    ('timbrature:approve_corrections', 'Approvazione delle correzioni delle timbrature del proprio team')
+ON CONFLICT (code) DO NOTHING;
+
+-- Chi approva i permessi approva anche le correzioni delle timbrature
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT role_id, 'timbrature:approve_corrections'
+FROM role_permissions
+WHERE permission_code = 'approvals:approve_permesso'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,51 @@
+-- Le timbrature non si cancellano piú: l'eliminazione amministrativa le annulla (voided_at, come le
+-- correzioni) registrando chi e perché. Ogni creazione e annullamento finisce in uno storico in sola aggiunta
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS voided_by INTEGER REFERENCES users(id);
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS void_reason TEXT;
+
+CREATE TABLE IF NOT EXISTS timbrature_revisions (
+    id            SERIAL PRIMARY KEY,
+    timbratura_id INTEGER NOT NULL REFERENCES timbrature(id),
+    user_id       INTEGER NOT NULL REFERENCES users(id), -- Titolare della timbratura
+    revision_type VARCHAR(10) NOT NULL, -- CREATED, VOIDED
+    source        VARCHAR(12) NOT NULL, -- USER, KIOSK, CORRECTION, ADMIN, LEGACY
+    timestamp     TIMESTAMPTZ NOT NULL, -- Valori della timbratura al momento della revisione
+    action_type   VARCHAR(10) NOT NULL,
+    location      VARCHAR(10) NOT NULL,
+    changed_by    INTEGER REFERENCES users(id), -- null per kiosk e storico pregresso
+    api_key_id    INTEGER REFERENCES api_keys(id),
+    correction_id INTEGER REFERENCES timbrature_corrections(id),
+    reason        TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_timbrature_revisions_timbratura_id ON timbrature_revisions (timbratura_id);
+CREATE INDEX IF NOT EXISTS idx_timbrature_revisions_user_id ON timbrature_revisions (user_id, created_at);
+
+-- Sola aggiunta: lo storico non si modifica né si cancella
+CREATE OR REPLACE FUNCTION timbrature_revisions_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'timbrature_revisions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_timbrature_revisions_append_only ON timbrature_revisions;
+CREATE TRIGGER trg_timbrature_revisions_append_only
+    BEFORE UPDATE OR DELETE ON timbrature_revisions
+    FOR EACH ROW EXECUTE FUNCTION timbrature_revisions_append_only();
+
+-- Storico delle timbrature esistenti: creazione (autore sconosciuto salvo correzioni) e annullamenti da correzione
+INSERT INTO timbrature_revisions (timbratura_id, user_id, revision_type, source, timestamp, action_type, location, changed_by, correction_id, created_at)
+SELECT t.id, t.user_id, 'CREATED',
+       CASE WHEN t.correction_id IS NOT NULL THEN 'CORRECTION' ELSE 'LEGACY' END,
+       t.timestamp, t.action_type, t.location, c.reviewer_id, t.correction_id, COALESCE(c.reviewed_at, t.timestamp)
+FROM timbrature t
+LEFT JOIN timbrature_corrections c ON c.id = t.correction_id
+WHERE NOT EXISTS (SELECT 1 FROM timbrature_revisions r WHERE r.timbratura_id = t.id);
+
+INSERT INTO timbrature_revisions (timbratura_id, user_id, revision_type, source, timestamp, action_type, location, changed_by, correction_id, created_at)
+SELECT t.id, t.user_id, 'VOIDED', 'CORRECTION', t.timestamp, t.action_type, t.location, c.reviewer_id, c.id, t.voided_at
+FROM timbrature t
+JOIN timbrature_corrections c ON c.id = t.voided_by_correction_id
+WHERE t.voided_at IS NOT NULL
+AND NOT EXISTS (SELECT 1 FROM timbrature_revisions r WHERE r.timbratura_id = t.id AND r.revision_type = 'VOIDED');
@@ -0,0 +1,43 @@
+-- Pause: nuove azioni PAUSA_INIZIO e PAUSA_FINE, piú lunghe dei 10 caratteri previsti finora
+DO $$
+DECLARE
+    constraint_name TEXT;
+BEGIN
+    -- Eventuali CHECK sui valori ammessi vengono sostituiti da quello con le nuove azioni
+    FOR constraint_name IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = 'timbrature'::regclass AND contype = 'c'
+        AND pg_get_constraintdef(oid) ILIKE '%action_type%'
+    LOOP
+        EXECUTE format('ALTER TABLE timbrature DROP CONSTRAINT %I', constraint_name);
+    END LOOP;
+END $$;
+
+ALTER TABLE timbrature ALTER COLUMN action_type TYPE VARCHAR(20) USING action_type::TEXT;
+ALTER TABLE timbrature ADD CONSTRAINT timbrature_action_type_check
+    CHECK (action_type IN ('ENTRATA', 'USCITA', 'PAUSA_INIZIO', 'PAUSA_FINE'));
+
+ALTER TABLE timbrature_corrections ALTER COLUMN action_type TYPE VARCHAR(20);
+ALTER TABLE timbrature_revisions ALTER COLUMN action_type TYPE VARCHAR(20);
+
+-- Contratti di lavoro: pausa minima oltre una certa durata della giornata (D.Lgs. 66/2003: 10 minuti oltre 6 ore).
+-- WARN segnala le pause mancanti o troppo brevi, ENFORCE rifiuta anche la PAUSA_FINE prima del minimo
+CREATE TABLE IF NOT EXISTS work_contracts (
+    id                  SERIAL PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL UNIQUE,
+    break_after_minutes INTEGER NOT NULL DEFAULT 360,
+    min_break_minutes   INTEGER NOT NULL DEFAULT 10,
+    break_policy        VARCHAR(10) NOT NULL DEFAULT 'WARN', -- WARN, ENFORCE
+    is_default          BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Un solo contratto predefinito, usato per chi non ne ha uno assegnato
+CREATE UNIQUE INDEX IF NOT EXISTS idx_work_contracts_default ON work_contracts (is_default) WHERE is_default;
+
+INSERT INTO work_contracts (name, break_after_minutes, min_break_minutes, break_policy, is_default)
+VALUES ('Standard', 360, 10, 'WARN', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_contract_id INTEGER REFERENCES work_contracts(id);
@@ -0,0 +1,36 @@
+-- Sedi aziendali con geofence: le timbrature UFFICIO devono avvenire entro radius_meters dalla sede.
+-- policy indica cosa fare di quelle fuori: REJECT le rifiuta, FLAG le registra da verificare, ALLOW le registra e basta
+CREATE TABLE IF NOT EXISTS office_sites (
+    id            SERIAL PRIMARY KEY,
+    name          VARCHAR(100) NOT NULL UNIQUE,
+    latitude      DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude     DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    radius_meters INTEGER NOT NULL CHECK (radius_meters > 0),
+    policy        VARCHAR(10) NOT NULL DEFAULT 'FLAG', -- REJECT, FLAG, ALLOW
+    is_active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Posizione strutturata della timbratura ed esito del controllo (NULL se non controllata: SMART, kiosk, nessuna sede)
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS accuracy_meters DOUBLE PRECISION;
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS office_site_id INTEGER REFERENCES office_sites(id);
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS geofence_status VARCHAR(20); -- INSIDE, OUTSIDE, NO_POSITION
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS geofence_flagged BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS geofence_reviewed_by INTEGER REFERENCES users(id);
+ALTER TABLE timbrature ADD COLUMN IF NOT EXISTS geofence_reviewed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_timbrature_geofence_flagged ON timbrature (timestamp) WHERE geofence_flagged AND voided_at IS NULL;
+
+INSERT INTO permissions (code, description) VALUES
+    ('office_sites:manage', 'Gestione delle sedi aziendali e dei geofence')
+ON CONFLICT (code) DO NOTHING;
+
+-- Chi gestisce gli utenti gestisce anche le sedi
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT role_id, 'office_sites:manage'
+FROM role_permissions
+WHERE permission_code = 'users:manage'
+ON CONFLICT DO NOTHING;
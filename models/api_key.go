@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Scope assegnabili alle API key
+const (
+	ScopeTimbratureWrite = "timbrature:write" // Timbrature per conto dei dipendenti (kiosk)
+	ScopeReportsRead     = "reports:read"     // Lettura di richieste, approvazioni e timbrature di tutta l'azienda
+)
+
+// APIKeyScopePermissions permessi concessi da ciascuno scope sulle rotte protette da RequirePermission
+var APIKeyScopePermissions = map[string][]string{
+	ScopeTimbratureWrite: {},
+	ScopeReportsRead:     {PermRequestsReadAll, PermApprovalsReadAll, PermTimbratureReadAll, PermCompanyReadAll},
+}
+
+// API key di un client non umano, la chiave in chiaro non viene mai salvata
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"` // Primi caratteri della chiave, per riconoscerla
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// HasScope verifica se la chiave possiede lo scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantsPermission verifica se uno degli scope della chiave concede il permesso
+func (k *APIKey) GrantsPermission(permission string) bool {
+	for _, scope := range k.Scopes {
+		for _, p := range APIKeyScopePermissions[scope] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Request front-end -> back-end
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days"` // Opzionale, senza scadenza se assente
+}
+
+// Risposta alla creazione: la chiave in chiaro é mostrata una sola volta
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Timbratura registrata da un kiosk per conto di un dipendente
+type KioskTimbratureRequest struct {
+	UserID      int          `json:"user_id" binding:"required"`
+	ActionType  ActionType   `json:"action_type" binding:"required"`
+	Location    LocationType `json:"location" binding:"required"`
+	Geolocation *string      `json:"geolocation"`
+}
@@ -7,6 +7,8 @@ type LoginResult string
 const (
 	LoginSuccess LoginResult = "SUCCESS"
 	LoginFailure LoginResult = "FAILURE"
+	LoginLocked LoginResult = "LOCKED" // Tentativo rifiutato perché l'account é bloccato (non allunga il blocco)
+	LoginMFAPending LoginResult = "MFA_PENDING" // Password corretta, in attesa del secondo fattore
 )
 
 // DATI SENSIBILI - Solo per uso interno al back-end
@@ -26,23 +28,89 @@ type CreateAuthCredentialRequest struct {
 
 }
 
-// Log del tentativo di login
+// Log del tentativo di login (UserID nil per email non registrate)
 type AuthLoginAttempt struct {
 	ID           int    `json:"id"`
-	UserID       int    `json:"user_id"`
+	UserID       *int    `json:"user_id"`
+	Email     *string     `json:"email"`
+	IPAddress *string     `json:"ip_address"`
+	UserAgent *string     `json:"user_agent"`
 	Timestamp time.Time `json:"timestamp"`
 	Result LoginResult `json:"result"`
 }
 
+// Dati del client che effettua la richiesta, salvati nei log di sicurezza
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// Stato del blocco account mostrato agli amministratori
+type LockoutStatus struct {
+	Locked         bool       `json:"locked"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	FailedAttempts int        `json:"failed_attempts"`
+}
+
+type AuthEventType string
+
+const (
+	EventPasswordResetRequested AuthEventType = "PASSWORD_RESET_REQUESTED"
+	EventPasswordResetCompleted AuthEventType = "PASSWORD_RESET_COMPLETED"
+	EventMFAEnabled             AuthEventType = "MFA_ENABLED"
+	EventMFADisabled            AuthEventType = "MFA_DISABLED"
+	EventMFARecoveryCodeUsed    AuthEventType = "MFA_RECOVERY_CODE_USED"
+	EventAccountUnlocked        AuthEventType = "ACCOUNT_UNLOCKED"
+)
+
+// Evento di sicurezza (reset password, ecc.) registrato accanto ai tentativi di login
+type AuthEvent struct {
+	ID        int           `json:"id"`
+	UserID    *int          `json:"user_id"`
+	EventType AuthEventType `json:"event_type"`
+	IPAddress *string       `json:"ip_address"`
+	Details   *string       `json:"details"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Token monouso per il reset della password, salvato solo come hash
+type PasswordResetToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Richiesta di reset password dal front-end
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// Conferma del reset password con il token ricevuto via email
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
 // Input login dal front-end
 type LoginRequest struct {
 	Email string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	DeviceName *string `json:"device_name"` // Opzionale, es. "iPhone", "Kiosk ufficio"
 }
 
 // Response dal back-end, NO PASSWORD & SALT
+// Se l'utente ha la 2FA attiva il login restituisce solo MFAToken, i token veri arrivano da /mfa/verify
 type LoginResponse struct {
-	Token string `json:"token"` //* Token JWT
+	Token string `json:"token,omitempty"` //* Token JWT di accesso (breve durata)
+	ExpiresIn int `json:"expires_in,omitempty"` // Secondi alla scadenza del token di accesso
+	RefreshToken string `json:"refresh_token,omitempty"` // Token opaco per ottenere un nuovo token di accesso
+	MFARequired bool `json:"mfa_required,omitempty"` // Serve il codice TOTP per completare il login
+	MFAToken string `json:"mfa_token,omitempty"` // Challenge a breve scadenza da inviare con il codice
+	MFAEnrollment *MFAEnrollment `json:"mfa_enrollment,omitempty"` // Presente se il ruolo impone la 2FA e l'utente non l'ha ancora configurata
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // Mostrati una sola volta, alla prima attivazione
 	User struct {
 		ID int `json:"id"`
 		Name string `json:"name"`
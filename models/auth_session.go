@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Sessione di login su un dispositivo (corrisponde a una famiglia di refresh token)
+type AuthSession struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	SessionID  string     `json:"-"` // Claim sid dei token di accesso
+	DeviceName *string    `json:"device_name"`
+	IPAddress  *string    `json:"ip_address"`
+	UserAgent  *string    `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"` // Sessione del token usato per la richiesta
+}
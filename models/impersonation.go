@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Sessione di impersonificazione: un operatore del supporto vede l'applicazione come l'utente
+type ImpersonationSession struct {
+	ID           int        `json:"id"`
+	ActorID      int        `json:"actor_id"`
+	ActorEmail   string     `json:"actor_email,omitempty"`
+	TargetUserID int        `json:"target_user_id"`
+	TargetEmail  string     `json:"target_email,omitempty"`
+	Reason       string     `json:"reason"`
+	IPAddress    *string    `json:"ip_address"`
+	StartedAt    time.Time  `json:"started_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	EndedAt      *time.Time `json:"ended_at"`
+}
+
+// Chiamata eseguita durante una sessione di impersonificazione
+type ImpersonationAuditEntry struct {
+	ID           int       `json:"id"`
+	SessionID    int       `json:"session_id"`
+	ActorID      int       `json:"actor_id"`
+	TargetUserID int       `json:"target_user_id"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	StatusCode   int       `json:"status_code"`
+	IPAddress    *string   `json:"ip_address"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Request front-end -> back-end per avviare l'impersonificazione
+type StartImpersonationRequest struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"` // Es. numero del ticket di supporto
+}
+
+// Risposta con il token di impersonificazione (nessun refresh token: alla scadenza si riparte)
+type ImpersonationResponse struct {
+	Token     string                `json:"token"`
+	ExpiresIn int                   `json:"expires_in"`
+	Session   *ImpersonationSession `json:"session"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "PENDING"
+	InvitationAccepted InvitationStatus = "ACCEPTED"
+	InvitationRevoked  InvitationStatus = "REVOKED"
+	InvitationExpired  InvitationStatus = "EXPIRED"
+)
+
+// Invito a registrarsi: ruolo e manager sono decisi dall'amministratore, non dall'invitato
+type UserInvitation struct {
+	ID             int              `json:"id"`
+	Email          string           `json:"email"`
+	Name           string           `json:"name"`
+	RoleID         *int             `json:"role_id"`
+	ManagerID      *int             `json:"manager_id"`
+	TokenHash      string           `json:"-"`
+	InvitedBy      int              `json:"invited_by"`
+	ExpiresAt      time.Time        `json:"expires_at"`
+	AcceptedAt     *time.Time       `json:"accepted_at"`
+	AcceptedUserID *int             `json:"accepted_user_id"`
+	RevokedAt      *time.Time       `json:"revoked_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Status         InvitationStatus `json:"status"` // Calcolato, non salvato
+}
+
+// Request front-end -> back-end per creare un invito
+type CreateInvitationRequest struct {
+	Email          string `json:"email" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	RoleID         *int   `json:"role_id"`
+	ManagerID      *int   `json:"manager_id"`
+	ExpiresInHours *int   `json:"expires_in_hours"` // Opzionale, default INVITATION_TTL
+}
+
+// Accettazione dell'invito con il token ricevuto via email
+type AcceptInvitationRequest struct {
+	Token      string  `json:"token" binding:"required"`
+	Password   string  `json:"password" binding:"required"`
+	Name       *string `json:"name"` // Opzionale, corregge il nome indicato nell'invito
+	DeviceName *string `json:"device_name"`
+}
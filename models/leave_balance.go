@@ -25,4 +25,15 @@ type LeaveBalanceResponse struct {
 	AccumulatedHolidays float32 `json:"accumulated_holidays"`
 	AccumulatedPermits  float32 `json:"accumulated_permits"`
 	ModifiedAt          time.Time  `json:"modified_at"`
-}
\ No newline at end of file
+}
+
+// Liquidazione finale del saldo ferie/permessi alla cessazione del rapporto
+type LeaveSettlement struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"user_id"`
+	TerminationDate  time.Time `json:"termination_date"`
+	ResidualHolidays float32   `json:"residual_holidays"`
+	ResidualPermits  float32   `json:"residual_permits"`
+	CreatedBy        int       `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
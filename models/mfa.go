@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Configurazione TOTP dell'utente. EnabledAt nil = enrollment avviato ma non ancora confermato
+type UserMFA struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `json:"-"` // Ultimo passo TOTP accettato, impedisce il riuso dello stesso codice
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Codice di recupero monouso, salvato solo come hash
+type MFARecoveryCode struct {
+	ID       int        `json:"id"`
+	UserID   int        `json:"user_id"`
+	CodeHash string     `json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// Dati per configurare l'app di autenticazione (l'URI va mostrato come QR code)
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Secondo passo del login: challenge + codice TOTP oppure codice di recupero
+type MFAVerifyRequest struct {
+	MFAToken     string  `json:"mfa_token" binding:"required"`
+	Code         string  `json:"code"`
+	RecoveryCode string  `json:"recovery_code"`
+	DeviceName   *string `json:"device_name"`
+}
+
+// Codice TOTP per confermare l'enrollment o rigenerare i codici di recupero
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Disattivazione della 2FA, richiede password e codice corrente
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// Stato della 2FA per l'utente autenticato
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RemainingRecoveryCodes int        `json:"remaining_recovery_codes"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type GeofencePolicy string
+type GeofenceStatus string
+
+const (
+	GeofencePolicyReject GeofencePolicy = "REJECT" // Timbratura fuori sede rifiutata
+	GeofencePolicyFlag   GeofencePolicy = "FLAG"   // Registrata e segnalata al responsabile
+	GeofencePolicyAllow  GeofencePolicy = "ALLOW"  // Registrata con l'esito, senza segnalazione
+)
+
+const (
+	GeofenceInside     GeofenceStatus = "INSIDE"      // Entro il raggio di una sede
+	GeofenceOutside    GeofenceStatus = "OUTSIDE"     // Fuori da tutte le sedi
+	GeofenceNoPosition GeofenceStatus = "NO_POSITION" // Posizione assente o troppo imprecisa
+)
+
+// Sede aziendale: le timbrature UFFICIO devono avvenire entro RadiusMeters dalle coordinate
+type OfficeSite struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name"`
+	Latitude     float64        `json:"latitude"`
+	Longitude    float64        `json:"longitude"`
+	RadiusMeters int            `json:"radius_meters"`
+	Policy       GeofencePolicy `json:"policy"`
+	IsActive     bool           `json:"is_active"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// Request front-end -> back-end, usata sia per la creazione che per la modifica
+type SaveOfficeSiteRequest struct {
+	Name         string         `json:"name" binding:"required"`
+	Latitude     *float64       `json:"latitude" binding:"required"`
+	Longitude    *float64       `json:"longitude" binding:"required"`
+	RadiusMeters int            `json:"radius_meters" binding:"required"`
+	Policy       GeofencePolicy `json:"policy" binding:"required"`
+	IsActive     *bool          `json:"is_active"` // Default true
+}
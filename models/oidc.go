@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Login SSO avviato e non ancora concluso (lo state in chiaro resta solo nel browser)
+type OIDCLoginRequest struct {
+	ID           int        `json:"id"`
+	StateHash    string     `json:"-"`
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	ConsumedAt   *time.Time `json:"consumed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Identitá dell'identity provider collegata a un utente
+type UserIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       *string    `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// Risposta all'avvio del login SSO: il frontend reindirizza il browser su AuthorizationURL
+type OIDCLoginStartResponse struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// Request per concludere il login SSO con i parametri ricevuti sul redirect
+type OIDCCallbackRequest struct {
+	Code       string  `json:"code" binding:"required"`
+	State      string  `json:"state" binding:"required"`
+	DeviceName *string `json:"device_name"`
+}
@@ -0,0 +1,70 @@
+package models
+
+// Codici dei permessi, devono corrispondere alla tabella permissions
+const (
+	PermUsersManage       = "users:manage"
+	PermRolesManage       = "roles:manage"
+	PermInvitationsManage = "invitations:manage"
+	PermSecurityManage    = "security:manage"
+	PermRequestsReadAll   = "requests:read_all"
+	PermApprovalsReadAll  = "approvals:read_all"
+	PermApproveHolidays   = "approvals:approve_ferie"
+	PermApprovePermits    = "approvals:approve_permesso"
+	PermTimbratureReadAll = "timbrature:read_all"
+	PermTimbratureDelete  = "timbrature:delete"
+	PermCompanyReadAll    = "company:read_all"
+	PermAPIKeysManage     = "api_keys:manage"
+	PermUsersImpersonate  = "users:impersonate"
+	PermPrivacyManage     = "privacy:manage"
+	PermTimbratureCorrect = "timbrature:approve_corrections"
+	PermOfficeSitesManage = "office_sites:manage"
+)
+
+// TeamScope utenti i cui dati sono visibili a chi fa la richiesta: tutta l'azienda
+// (permesso company:read_all) oppure i sottoposti diretti e indiretti
+type TeamScope struct {
+	CompanyWide bool
+	UserIDs     []int
+}
+
+// Includes verifica se i dati dell'utente sono visibili
+func (s *TeamScope) Includes(userID int) bool {
+	if s.CompanyWide {
+		return true
+	}
+	for _, id := range s.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Permesso assegnabile ai ruoli
+type Permission struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Permessi di un ruolo, restituiti dall'API di amministrazione
+type RolePermissions struct {
+	RoleID      int      `json:"role_id"`
+	Permissions []string `json:"permissions"`
+}
+
+// Request front-end -> back-end, sostituisce l'intero insieme di permessi del ruolo
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// ApprovePermissionFor restituisce il permesso necessario per approvare un tipo di richiesta
+func ApprovePermissionFor(requestType RequestType) string {
+	switch requestType {
+	case RequestHolidays:
+		return PermApproveHolidays
+	case RequestPermits:
+		return PermApprovePermits
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type PrivacyRequestType string
+
+const (
+	PrivacyExport  PrivacyRequestType = "EXPORT"
+	PrivacyErasure PrivacyRequestType = "ERASURE"
+)
+
+// Richiesta dell'interessato evasa (registro GDPR)
+type PrivacyRequest struct {
+	ID          int                `json:"id"`
+	UserID      int                `json:"user_id"`
+	RequestType PrivacyRequestType `json:"request_type"`
+	Reason      *string            `json:"reason"`
+	PerformedBy int                `json:"performed_by"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// Tutti i dati personali di un utente (diritto di accesso, art. 15 GDPR)
+type PersonalDataExport struct {
+	GeneratedAt   time.Time              `json:"generated_at"`
+	Profile       *UserDetails           `json:"profile"`
+	Timbrature    []Timbrature           `json:"timbrature"` // Con la geolocalizzazione, anche quelle annullate da una correzione
+	Corrections   []TimbraturaCorrection `json:"timbrature_corrections"`
+	Requests      []RequestWithStatus    `json:"requests"`
+	Approvals     []Approval             `json:"approvals"` // Sulle richieste dell'utente e quelle date da lui come responsabile
+	LeaveBalance  *LeaveBalance          `json:"leave_balance"`
+	Settlement    *LeaveSettlement       `json:"settlement"`
+	LoginAttempts []AuthLoginAttempt     `json:"login_attempts"`
+	AuthEvents    []AuthEvent            `json:"auth_events"`
+	Sessions      []AuthSession          `json:"sessions"`
+}
+
+// Request front-end -> back-end per la cancellazione.
+// L'email va ripetuta come conferma, l'operazione non é reversibile
+type EraseUserRequest struct {
+	ConfirmEmail string  `json:"confirm_email" binding:"required"`
+	Reason       *string `json:"reason"` // Es. riferimento alla richiesta dell'interessato
+}
+
+// Esito della cancellazione
+type ErasureResult struct {
+	UserID               int       `json:"user_id"`
+	PseudonymName        string    `json:"pseudonym_name"`
+	ErasedAt             time.Time `json:"erased_at"`
+	RetainedTimbrature   int       `json:"retained_timbrature"` // Mantenute senza geolocalizzazione
+	RetainedRequests     int       `json:"retained_requests"`   // Mantenute senza note
+	DeletedLoginAttempts int64     `json:"deleted_login_attempts"`
+}
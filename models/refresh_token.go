@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Refresh token opaco, salvato solo come hash. Tutti i token ottenuti per
+// rotazione dallo stesso login condividono la stessa FamilyID
+type RefreshToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	FamilyID   string     `json:"family_id"`
+	TokenHash  string     `json:"-"`
+	DeviceName *string    `json:"device_name"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UsedAt     *time.Time `json:"used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Input refresh dal front-end
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
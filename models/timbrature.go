@@ -7,6 +7,8 @@ type LocationType string
 const (
 	ActionEnter ActionType = "ENTRATA"
 	ActionExit ActionType = "USCITA"
+	ActionBreakStart ActionType = "PAUSA_INIZIO"
+	ActionBreakEnd ActionType = "PAUSA_FINE"
 )
 const (
 	LocationOffice LocationType = "UFFICIO"
@@ -20,6 +22,12 @@ type Timbrature struct {
 	ActionType ActionType `json:"action_type"`
 	Location LocationType `json:"location"`
 	Geolocation *string `json:"geolocation"`
+	Latitude *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Accuracy *float64 `json:"accuracy"` // Metri
+	OfficeSiteID *int `json:"office_site_id"` // Sede in cui é stata fatta o, se fuori, la piú vicina
+	GeofenceStatus *GeofenceStatus `json:"geofence_status"` // null se non controllata (SMART, kiosk, nessuna sede)
+	GeofenceFlagged bool `json:"geofence_flagged"` // Fuori sede, da verificare dal responsabile
 }
 
 // Request front-end -> back-end
@@ -28,6 +36,10 @@ type CreateTimbratureRequest struct {
 	ActionType ActionType `json:"action_type" binding:"required"`
 	Location LocationType `json:"location" binding:"required"`
 	Geolocation *string `json:"geolocation"`
+	// Posizione del dispositivo, controllata sui geofence delle sedi per le timbrature UFFICIO
+	Latitude *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Accuracy *float64 `json:"accuracy"` // Metri
 }
 
 // TimbratureResponse per le risposte API
@@ -38,4 +50,10 @@ type TimbratureResponse struct {
 	ActionType  ActionType `json:"action_type"`
 	Location    LocationType `json:"location"`
 	Geolocation *string	`json:"geolocation"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Accuracy    *float64 `json:"accuracy"`
+	OfficeSiteID *int `json:"office_site_id"`
+	GeofenceStatus *GeofenceStatus `json:"geofence_status"`
+	GeofenceFlagged bool `json:"geofence_flagged"`
 }
\ No newline at end of file
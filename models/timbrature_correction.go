@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type CorrectionType string
+type CorrectionStatus string
+
+const (
+	CorrectionAdd    CorrectionType = "ADD"    // Timbratura dimenticata
+	CorrectionModify CorrectionType = "MODIFY" // Orario, azione o sede sbagliati
+	CorrectionRemove CorrectionType = "REMOVE" // Timbratura fatta per errore
+)
+
+const (
+	CorrectionPending   CorrectionStatus = "PENDING"
+	CorrectionApproved  CorrectionStatus = "APPROVED"
+	CorrectionRejected  CorrectionStatus = "REJECTED"
+	CorrectionCancelled CorrectionStatus = "CANCELLED" // Ritirata dal dipendente
+)
+
+// Richiesta di correzione di una timbratura. All'approvazione la timbratura originale viene
+// annullata (non cancellata) e, per ADD e MODIFY, ne viene creata una nuova (AppliedTimbraturaID)
+type TimbraturaCorrection struct {
+	ID                  int              `json:"id"`
+	UserID              int              `json:"user_id"`
+	CorrectionType      CorrectionType   `json:"correction_type"`
+	TimbraturaID        *int             `json:"timbratura_id"` // null per ADD
+	Timestamp           *time.Time       `json:"timestamp"`     // Valori proposti, null per REMOVE
+	ActionType          *ActionType      `json:"action_type"`
+	Location            *LocationType    `json:"location"`
+	Reason              string           `json:"reason"`
+	Status              CorrectionStatus `json:"status"`
+	ReviewerID          *int             `json:"reviewer_id"`
+	ReviewComment       *string          `json:"review_comment"`
+	ReviewedAt          *time.Time       `json:"reviewed_at"`
+	AppliedTimbraturaID *int             `json:"applied_timbratura_id"`
+	CreatedAt           time.Time        `json:"created_at"`
+}
+
+// Timbrature vicine a quelle toccate da una correzione, per il controllo della sequenza (nil = nessuna)
+type CorrectionNeighbours struct {
+	RemovedPrevious *Timbrature // Prima e dopo la timbratura annullata (MODIFY, REMOVE)
+	RemovedNext     *Timbrature
+	AddedPrevious   *Timbrature // Prima e dopo la nuova timbratura (ADD, MODIFY)
+	AddedNext       *Timbrature
+}
+
+// Request front-end -> back-end.
+// ADD: timestamp, action_type e location obbligatori. MODIFY: timbratura_id e almeno un valore da
+// cambiare (gli altri restano quelli originali). REMOVE: solo timbratura_id
+type CreateTimbraturaCorrectionRequest struct {
+	CorrectionType CorrectionType `json:"correction_type" binding:"required"`
+	TimbraturaID   *int           `json:"timbratura_id"`
+	Timestamp      *time.Time     `json:"timestamp"`
+	ActionType     *ActionType    `json:"action_type"`
+	Location       *LocationType  `json:"location"`
+	Reason         string         `json:"reason" binding:"required"`
+}
+
+// Request front-end -> back-end per approvazione e rifiuto
+type ReviewTimbraturaCorrectionRequest struct {
+	Comment *string `json:"comment"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type RevisionType string
+type RevisionSource string
+
+const (
+	RevisionCreated RevisionType = "CREATED"
+	RevisionVoided  RevisionType = "VOIDED"
+)
+
+const (
+	RevisionSourceUser       RevisionSource = "USER"       // Timbratura del dipendente
+	RevisionSourceKiosk      RevisionSource = "KIOSK"      // Kiosk con API key
+	RevisionSourceCorrection RevisionSource = "CORRECTION" // Correzione approvata dal responsabile
+	RevisionSourceAdmin      RevisionSource = "ADMIN"      // Eliminazione amministrativa o chiusura del turno alla cessazione
+	RevisionSourceLegacy     RevisionSource = "LEGACY"     // Timbratura precedente allo storico
+)
+
+// Revisione di una timbratura: chi l'ha creata o annullata, quando e perché.
+// Timestamp, ActionType e Location sono i valori della timbratura al momento della revisione
+type TimbraturaRevision struct {
+	ID           int            `json:"id"`
+	TimbraturaID int            `json:"timbratura_id"`
+	UserID       int            `json:"user_id"`
+	RevisionType RevisionType   `json:"revision_type"`
+	Source       RevisionSource `json:"source"`
+	Timestamp    time.Time      `json:"timestamp"`
+	ActionType   ActionType     `json:"action_type"`
+	Location     LocationType   `json:"location"`
+	ChangedBy    *int           `json:"changed_by"`
+	APIKeyID     *int           `json:"api_key_id"`
+	CorrectionID *int           `json:"correction_id"`
+	Reason       *string        `json:"reason"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// Timbratura con lo stato di annullamento, per lo storico
+type TimbraturaHistoryEntry struct {
+	TimbratureResponse
+	VoidedAt             *time.Time `json:"voided_at"`
+	VoidedBy             *int       `json:"voided_by"`
+	VoidReason           *string    `json:"void_reason"`
+	VoidedByCorrectionID *int       `json:"voided_by_correction_id"`
+	CorrectionID         *int       `json:"correction_id"` // Correzione che l'ha creata
+}
+
+// Storico completo di una giornata: tutte le timbrature, anche annullate, e le loro revisioni
+type TimbratureDayHistory struct {
+	UserID     int                      `json:"user_id"`
+	Date       string                   `json:"date"` // YYYY-MM-DD
+	Timbrature []TimbraturaHistoryEntry `json:"timbrature"`
+	Revisions  []TimbraturaRevision     `json:"revisions"`
+}
+
+// Request front-end -> back-end per l'eliminazione (annullamento) di una timbratura
+type VoidTimbraturaRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -1,11 +1,23 @@
 package models
 
+import "time"
+
+type UserStatus string
+
+const (
+	UserActive   UserStatus = "ACTIVE"
+	UserInactive UserStatus = "INACTIVE" // Disattivato, non puó accedere
+	UserTerminated UserStatus = "TERMINATED" // Rapporto di lavoro concluso (offboarding completato)
+)
+
 type User struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	Email     string `json:"email"`
 	RoleID    *int   `json:"role_id"`
 	ManagerID *int   `json:"manager_id"`
+	Status    UserStatus `json:"status"`
+	TerminationDate *time.Time `json:"termination_date"` // Solo per utenti TERMINATED
 }
 
 // Request front-end -> back-end
@@ -15,4 +27,59 @@ type CreateUserRequest struct {
 	Email     string `json:"email" binding:"required"`
 	RoleID    *int   `json:"role_id"`
 	ManagerID *int   `json:"manager_id"`
-}
\ No newline at end of file
+}
+
+// Utente con i dati di ruolo e manager per le liste amministrative
+type UserDetails struct {
+	User
+	RoleName         *string `json:"role_name"`
+	HierarchyLevel   *int    `json:"hierarchy_level"`
+	ManagerName      *string `json:"manager_name"`
+	WorkContractID   *int    `json:"work_contract_id"` // null = contratto predefinito
+	WorkContractName *string `json:"work_contract_name"`
+}
+
+// Filtri per la ricerca utenti
+type UserFilter struct {
+	Search    string // Nome o email, case-insensitive
+	RoleID    *int
+	ManagerID *int
+	Status    UserStatus
+	Limit     int
+	Offset    int
+}
+
+// Modifica dei dati anagrafici
+type UpdateUserRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"required"`
+}
+
+// Assegnazione del ruolo (null = nessun ruolo)
+type UpdateUserRoleRequest struct {
+	RoleID *int `json:"role_id"`
+}
+
+// Assegnazione del contratto di lavoro (null = contratto predefinito)
+type UpdateUserWorkContractRequest struct {
+	WorkContractID *int `json:"work_contract_id"`
+}
+
+// Assegnazione del manager (null = nessun manager)
+type UpdateUserManagerRequest struct {
+	ManagerID *int `json:"manager_id"`
+}
+
+// Cessazione del rapporto di lavoro
+type TerminateUserRequest struct {
+	TerminationDate string  `json:"termination_date" binding:"required"` // YYYY-MM-DD, non nel futuro
+	Reason          *string `json:"reason"`
+}
+
+// Esito dell'offboarding
+type OffboardingResult struct {
+	User                *UserDetails         `json:"user"`
+	ClosedTimbrature    []TimbratureResponse `json:"closed_timbrature"`     // PAUSA_FINE (se in pausa) e USCITA generate se l'utente risultava ancora in servizio
+	CancelledRequestIDs []int                `json:"cancelled_request_ids"` // Richieste in attesa o approvate dopo la cessazione, annullate
+	Settlement          *LeaveSettlement     `json:"settlement"`
+}
@@ -4,6 +4,7 @@ type UserRole struct {
 	ID             int    `json:"id"`
 	Name           string `json:"name"`
 	HierarchyLevel int    `json:"hierarchy_level"`
+	MFARequired    bool   `json:"mfa_required"` // Se true gli utenti del ruolo devono usare la 2FA
 }
 
 // Request front-end -> back-end
@@ -11,4 +12,5 @@ type UserRole struct {
 type CreateUserRoleRequest struct {
 	Name           string `json:"name" binding:"required"`
 	HierarchyLevel int    `json:"hierarchy_level" binding:"required"`
+	MFARequired    bool   `json:"mfa_required"`
 }
\ No newline at end of file
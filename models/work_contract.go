@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type BreakPolicy string
+
+const (
+	BreakPolicyWarn    BreakPolicy = "WARN"    // Pause mancanti o troppo brevi solo segnalate
+	BreakPolicyEnforce BreakPolicy = "ENFORCE" // La PAUSA_FINE prima della pausa minima viene rifiutata
+)
+
+// Contratto di lavoro con le regole sulle pause: chi lavora piú di BreakAfterMinutes
+// nella giornata deve fare almeno MinBreakMinutes di pausa
+type WorkContract struct {
+	ID                int         `json:"id"`
+	Name              string      `json:"name"`
+	BreakAfterMinutes int         `json:"break_after_minutes"`
+	MinBreakMinutes   int         `json:"min_break_minutes"`
+	BreakPolicy       BreakPolicy `json:"break_policy"`
+	IsDefault         bool        `json:"is_default"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// Request front-end -> back-end, usata sia per la creazione che per la modifica
+type SaveWorkContractRequest struct {
+	Name              string      `json:"name" binding:"required"`
+	BreakAfterMinutes int         `json:"break_after_minutes"`
+	MinBreakMinutes   int         `json:"min_break_minutes"`
+	BreakPolicy       BreakPolicy `json:"break_policy" binding:"required"`
+	IsDefault         bool        `json:"is_default"`
+}
+
+type WorkWarningCode string
+
+const (
+	WarningBreakTooShort WorkWarningCode = "BREAK_TOO_SHORT" // Pausa terminata prima del minimo
+	WarningBreakMissing  WorkWarningCode = "BREAK_MISSING"   // Giornata oltre la soglia senza la pausa minima
+)
+
+// Avviso sul rispetto delle pause, restituito con la timbratura e nel riepilogo ore
+type WorkWarning struct {
+	Code    WorkWarningCode `json:"code"`
+	Message string          `json:"message"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+type UnpairedReason string
+
+const (
+	UnpairedMissingExit       UnpairedReason = "MISSING_USCITA"       // ENTRATA senza USCITA successiva
+	UnpairedMissingEnter      UnpairedReason = "MISSING_ENTRATA"      // USCITA senza ENTRATA precedente
+	UnpairedExceedsDuration   UnpairedReason = "EXCEEDS_MAX_DURATION" // Coppia troppo lunga, probabile USCITA dimenticata
+	UnpairedMissingBreakEnd   UnpairedReason = "MISSING_PAUSA_FINE"   // PAUSA_INIZIO senza PAUSA_FINE successiva
+	UnpairedMissingBreakStart UnpairedReason = "MISSING_PAUSA_INIZIO" // PAUSA_FINE senza PAUSA_INIZIO precedente
+)
+
+// Intervallo di lavoro tra un'ENTRATA (o PAUSA_FINE) e la sua USCITA (o PAUSA_INIZIO).
+// Gli intervalli che attraversano la mezzanotte sono divisi tra i due giorni
+type WorkInterval struct {
+	EntrataID  int          `json:"entrata_id"` // Timbratura di inizio (ENTRATA o PAUSA_FINE)
+	UscitaID   *int         `json:"uscita_id"`  // Timbratura di fine (USCITA o PAUSA_INIZIO), null se in corso
+	Start      time.Time    `json:"start"`
+	End        time.Time    `json:"end"`
+	Location   LocationType `json:"location"`
+	Minutes    int          `json:"minutes"`
+	InProgress bool         `json:"in_progress"`
+}
+
+// Timbratura che non é stato possibile accoppiare, da correggere
+type UnpairedTimbratura struct {
+	Timbratura TimbratureResponse `json:"timbratura"`
+	Reason     UnpairedReason     `json:"reason"`
+}
+
+// Ore lavorate in minuti, divise tra ufficio e smart working. Le pause non sono conteggiate nel totale
+type WorkTotals struct {
+	OfficeMinutes int `json:"office_minutes"`
+	SmartMinutes  int `json:"smart_minutes"`
+	TotalMinutes  int `json:"total_minutes"`
+	BreakMinutes  int `json:"break_minutes"`
+}
+
+// Riepilogo di una giornata
+type DailyWorkSummary struct {
+	Date        string         `json:"date"` // YYYY-MM-DD
+	Intervals   []WorkInterval `json:"intervals"`
+	HasUnpaired bool           `json:"has_unpaired"`
+	Warnings    []WorkWarning  `json:"warnings"`
+	WorkTotals
+}
+
+// Riepilogo delle ore lavorate in un periodo
+type WorkSummary struct {
+	UserID     int                  `json:"user_id"`
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Days       []DailyWorkSummary   `json:"days"`
+	Totals     WorkTotals           `json:"totals"`
+	WorkedDays int                  `json:"worked_days"`
+	Unpaired   []UnpairedTimbratura `json:"unpaired"`
+}
+
+type WorkState string
+
+const (
+	WorkStateNotWorking WorkState = "NOT_WORKING" // Nessuna timbratura o ultima USCITA
+	WorkStateWorking    WorkState = "WORKING"     // Ultima ENTRATA o PAUSA_FINE
+	WorkStateOnBreak    WorkState = "ON_BREAK"    // Ultima PAUSA_INIZIO
+)
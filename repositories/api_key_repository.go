@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct{}
+
+// NewAPIKeyRepository crea una nuova istanza del repository
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+const apiKeyColumns = `
+	id, name, key_prefix, key_hash, scopes, created_by,
+	created_at, expires_at, last_used_at, revoked_at`
+
+// Create salva una nuova API key
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, key_prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(
+		query,
+		key.Name,
+		key.KeyPrefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.CreatedBy,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetByID recupera una API key per ID
+func (r *APIKeyRepository) GetByID(id int) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	return scanAPIKey(config.DB.QueryRow(query, id))
+}
+
+// GetActiveByHash recupera una API key valida (non revocata né scaduta) dall'hash
+func (r *APIKeyRepository) GetActiveByHash(keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+	return scanAPIKey(config.DB.QueryRow(query, keyHash))
+}
+
+// GetAll recupera tutte le API key (piú recenti prima)
+func (r *APIKeyRepository) GetAll() ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke revoca la chiave, false se era giá revocata o non esiste
+func (r *APIKeyRepository) Revoke(id int) (bool, error) {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := config.DB.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// TouchLastUsed aggiorna last_used_at al massimo una volta al minuto, per non scrivere ad ogni richiesta
+func (r *APIKeyRepository) TouchLastUsed(id int) error {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	_, err := config.DB.Exec(query, id)
+	return err
+}
+
+// scanAPIKey legge una riga di api_keys, nil se non trovata
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.KeyPrefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
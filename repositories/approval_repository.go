@@ -30,15 +30,18 @@ func (r *ApprovalRepository) Create(approval *models.Approval) (*models.Approval
 	return approval, nil
 }
 
-// GetAll recupera tutte le approvazioni con paginazione
-func (r *ApprovalRepository) GetAll(limit, offset int) ([]models.Approval, error) {
-	query := `
-		SELECT id, request_id, approver_id, status, comments, approved_at 
-		FROM approvals 
-		ORDER BY approved_at DESC 
-		LIMIT $1 OFFSET $2`
-
-		rows, err := config.DB.Query(query, limit, offset)
+// GetAll recupera le approvazioni delle richieste degli utenti nello scope con paginazione
+func (r *ApprovalRepository) GetAll(limit, offset int, scope *models.TeamScope) ([]models.Approval, error) {
+	condition, args := scopeCondition("r.user_id", scope, []interface{}{limit, offset})
+	query := fmt.Sprintf(`
+		SELECT a.id, a.request_id, a.approver_id, a.status, a.comments, a.approved_at 
+		FROM approvals a
+		JOIN requests r ON r.id = a.request_id
+		WHERE %s
+		ORDER BY a.approved_at DESC 
+		LIMIT $1 OFFSET $2`, condition)
+
+		rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -175,16 +178,18 @@ func (r *ApprovalRepository) GetByApproverID(approverID, limit, offset int) ([]m
 	return approvals, nil
 }
 
-// GetByStatus recupera approvazioni per stato specifico
-func (r *ApprovalRepository) GetByStatus(status models.ApprovalStatus, limit, offset int) ([]models.Approval, error) {
-	query := `
-		SELECT id, request_id, approver_id, status, comments, approved_at 
-		FROM approvals 
-		WHERE status = $1 
-		ORDER BY approved_at DESC 
-		LIMIT $2 OFFSET $3`
-
-	rows, err := config.DB.Query(query, status, limit, offset)
+// GetByStatus recupera approvazioni per stato specifico delle richieste degli utenti nello scope
+func (r *ApprovalRepository) GetByStatus(status models.ApprovalStatus, limit, offset int, scope *models.TeamScope) ([]models.Approval, error) {
+	condition, args := scopeCondition("r.user_id", scope, []interface{}{status, limit, offset})
+	query := fmt.Sprintf(`
+		SELECT a.id, a.request_id, a.approver_id, a.status, a.comments, a.approved_at 
+		FROM approvals a
+		JOIN requests r ON r.id = a.request_id
+		WHERE a.status = $1 AND %s
+		ORDER BY a.approved_at DESC 
+		LIMIT $2 OFFSET $3`, condition)
+
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -367,12 +372,13 @@ func (r *ApprovalRepository) CountByApproverID(approverID int) (int, error) {
 	return count, nil
 }
 
-// CountByStatus conta le approvazioni per status
-func (r *ApprovalRepository) CountByStatus(status models.ApprovalStatus) (int, error) {
-	query := `SELECT COUNT(*) FROM approvals WHERE status = $1`
+// CountByStatus conta le approvazioni per status sulle richieste degli utenti nello scope
+func (r *ApprovalRepository) CountByStatus(status models.ApprovalStatus, scope *models.TeamScope) (int, error) {
+	condition, args := scopeCondition("r.user_id", scope, []interface{}{status})
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM approvals a JOIN requests r ON r.id = a.request_id WHERE a.status = $1 AND %s`, condition)
 
 	var count int
-	err := config.DB.QueryRow(query, status).Scan(&count)
+	err := config.DB.QueryRow(query, args...).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"merendels-backend/config"
 	"merendels-backend/models"
+	"time"
 )
 
 type AuthRepository struct{}
@@ -21,6 +22,10 @@ type LoginData struct {
 	RoleID         *int   `json:"role_id"`
 	ManagerID      *int   `json:"manager_id"`
 	HierarchyLevel *int   `json:"hierarchy_level"`
+	Status         models.UserStatus `json:"status"`
+	SecurityVersion int   `json:"security_version"`
+	MFARequired    bool   `json:"mfa_required"` // Il ruolo impone la 2FA
+	MFAEnabled     bool   `json:"mfa_enabled"`  // L'utente ha la 2FA attiva
 	PasswordHash   string `json:"-"`
 	Salt           string `json:"-"`
 }
@@ -35,10 +40,15 @@ func (r *AuthRepository) GetUserForLogin(email string) (*LoginData, error) {
 			u.role_id, 
 			u.manager_id,
 			ur.hierarchy_level,
+			u.status,
+			u.security_version,
+			COALESCE(ur.mfa_required, FALSE),
+			(m.enabled_at IS NOT NULL),
 			ac.password_hash,
 			ac.salt
 		FROM users u 
 		LEFT JOIN user_roles ur ON u.role_id = ur.id
+		LEFT JOIN user_mfa m ON u.id = m.user_id
 		JOIN auth_credentials ac ON u.id = ac.user_id  
 		WHERE u.email = $1`
 
@@ -51,6 +61,10 @@ func (r *AuthRepository) GetUserForLogin(email string) (*LoginData, error) {
 		&loginData.RoleID,
 		&loginData.ManagerID,
 		&loginData.HierarchyLevel,
+		&loginData.Status,
+		&loginData.SecurityVersion,
+		&loginData.MFARequired,
+		&loginData.MFAEnabled,
 		&loginData.PasswordHash,
 		&loginData.Salt,
 	)
@@ -64,10 +78,53 @@ func (r *AuthRepository) GetUserForLogin(email string) (*LoginData, error) {
 	return &loginData, nil
 }
 
+// GetUserForToken recupera i dati da inserire nel token per ID utente (usato nel refresh)
+func (r *AuthRepository) GetUserForToken(userID int) (*LoginData, error) {
+	query := `
+		SELECT 
+			u.id, 
+			u.name, 
+			u.email, 
+			u.role_id, 
+			u.manager_id,
+			ur.hierarchy_level,
+			u.status,
+			u.security_version,
+			COALESCE(ur.mfa_required, FALSE),
+			(m.enabled_at IS NOT NULL)
+		FROM users u 
+		LEFT JOIN user_roles ur ON u.role_id = ur.id
+		LEFT JOIN user_mfa m ON u.id = m.user_id
+		WHERE u.id = $1`
+
+	var loginData LoginData
+
+	err := config.DB.QueryRow(query, userID).Scan(
+		&loginData.UserID,
+		&loginData.Name,
+		&loginData.Email,
+		&loginData.RoleID,
+		&loginData.ManagerID,
+		&loginData.HierarchyLevel,
+		&loginData.Status,
+		&loginData.SecurityVersion,
+		&loginData.MFARequired,
+		&loginData.MFAEnabled,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Utente non trovato
+		}
+		return nil, err
+	}
+
+	return &loginData, nil
+}
+
 // GetUserProfile recupera i dati del profilo utente per ID
 func (r *AuthRepository) GetUserProfile(userID int) (*models.User, error) {
 	query := `
-		SELECT u.id, u.name, u.email, u.role_id, u.manager_id
+		SELECT u.id, u.name, u.email, u.role_id, u.manager_id, u.status, u.termination_date
 		FROM users u 
 		WHERE u.id = $1`
 
@@ -78,6 +135,8 @@ func (r *AuthRepository) GetUserProfile(userID int) (*models.User, error) {
 		&user.Email,
 		&user.RoleID,
 		&user.ManagerID,
+		&user.Status,
+		&user.TerminationDate,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -120,11 +179,27 @@ func (r *AuthRepository) CreateUserWithCredentials(user *models.User, passwordHa
 	return tx.Commit()
 }
 
-// UpdatePassword aggirona la password di un utente
+// UpdatePassword aggirona la password di un utente, archiviando quella precedente nello storico
 func (r *AuthRepository) UpdatePassword(userID int, newPasswordHash string, newSalt string) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 1. Copio la password attuale nello storico
+	historyQuery := `
+		INSERT INTO password_history (user_id, password_hash, salt)
+		SELECT user_id, password_hash, salt FROM auth_credentials WHERE user_id = $1`
+	_, err = tx.Exec(historyQuery, userID)
+	if err != nil {
+		return false, err
+	}
+
+	// 2. Aggiorno le credenziali
 	query := `UPDATE auth_credentials SET password_hash = $1, salt = $2, modified_at = CURRENT_TIMESTAMP WHERE user_id = $3`
 
-	result, err := config.DB.Exec(query, newPasswordHash, newSalt, userID)
+	result, err := tx.Exec(query, newPasswordHash, newSalt, userID)
 	if err != nil {
 		return false, err
 	}
@@ -138,32 +213,204 @@ func (r *AuthRepository) UpdatePassword(userID int, newPasswordHash string, newS
 		return false, sql.ErrNoRows
 	}
 
-	return true, nil
+	// 3. Nuova versione di sicurezza: i token emessi con la vecchia password non valgono piú
+	_, err = tx.Exec(`UPDATE users SET security_version = security_version + 1 WHERE id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
 }
 
-// RecordLoginAttempt registra un tentativo di login
-func (r *AuthRepository) RecordLoginAttempt(userID int, result models.LoginResult) error {
-	query := `INSERT INTO auth_login_attempts (user_id, result) VALUES ($1, $2)`
+// ReplacePasswordHash sostituisce l'hash della password attuale con uno equivalente in un formato
+// piú recente. Non tocca lo storico né la versione di sicurezza, la password non cambia.
+// Ritorna false se nel frattempo la password é stata cambiata
+func (r *AuthRepository) ReplacePasswordHash(userID int, currentPasswordHash, newPasswordHash, newSalt string) (bool, error) {
+	query := `UPDATE auth_credentials SET password_hash = $1, salt = $2 WHERE user_id = $3 AND password_hash = $4`
 
-	_, err := config.DB.Exec(query, userID, result)
+	result, err := config.DB.Exec(query, newPasswordHash, newSalt, userID, currentPasswordHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// GetCredentialsByUserID recupera le credenziali attuali di un utente
+func (r *AuthRepository) GetCredentialsByUserID(userID int) (*models.AuthCredential, error) {
+	query := `
+		SELECT id, user_id, password_hash, salt, created_at, modified_at
+		FROM auth_credentials
+		WHERE user_id = $1`
+
+	var credential models.AuthCredential
+	err := config.DB.QueryRow(query, userID).Scan(
+		&credential.ID,
+		&credential.UserID,
+		&credential.PasswordHash,
+		&credential.Salt,
+		&credential.CreatedAt,
+		&credential.ModifiedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Utente senza credenziali
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// GetPasswordHistory recupera le ultime password archiviate di un utente (dalla piú recente)
+func (r *AuthRepository) GetPasswordHistory(userID int, limit int) ([]models.AuthCredential, error) {
+	query := `
+		SELECT id, user_id, password_hash, salt, created_at
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := config.DB.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.AuthCredential
+
+	for rows.Next() {
+		var credential models.AuthCredential
+		err := rows.Scan(
+			&credential.ID,
+			&credential.UserID,
+			&credential.PasswordHash,
+			&credential.Salt,
+			&credential.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, credential)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RecordLoginAttempt registra un tentativo di login con i dati del client
+func (r *AuthRepository) RecordLoginAttempt(attempt *models.AuthLoginAttempt) error {
+	query := `
+		INSERT INTO auth_login_attempts (user_id, email, ip_address, user_agent, result)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := config.DB.Exec(query, attempt.UserID, attempt.Email, attempt.IPAddress, attempt.UserAgent, attempt.Result)
 	return err
 }
 
-// GetRecentFailedAttempts conta i tentativi falliti recenti
-func (r *AuthRepository) GetRecentFailedAttempts(userID int, minutes int) (int, error) {
-	query := `SELECT COUNT(*)
-	FROM auth_login_attempts
-	WHERE user_id = $1
-	AND result = 'FAILURE'
-	AND timestamp > NOW() - $2 * INTERVAL '1 minute'`
+// RecordAuthEvent registra un evento di sicurezza (reset password, ecc.)
+func (r *AuthRepository) RecordAuthEvent(event *models.AuthEvent) error {
+	query := `
+		INSERT INTO auth_events (user_id, event_type, ip_address, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(query, event.UserID, event.EventType, event.IPAddress, event.Details).Scan(&event.ID, &event.CreatedAt)
+}
+
+// GetConsecutiveFailures conta i tentativi falliti successivi a since, all'ultimo login riuscito
+// e all'ultimo sblocco manuale. Restituisce anche l'istante dell'ultimo fallimento
+func (r *AuthRepository) GetConsecutiveFailures(userID int, since time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MAX(a.timestamp)
+		FROM auth_login_attempts a
+		WHERE a.user_id = $1
+		AND a.result = 'FAILURE'
+		AND a.timestamp > GREATEST(
+			$2,
+			COALESCE((SELECT MAX(s.timestamp) FROM auth_login_attempts s WHERE s.user_id = $1 AND s.result = 'SUCCESS'), $2),
+			COALESCE((SELECT ac.lockout_reset_at FROM auth_credentials ac WHERE ac.user_id = $1), $2)
+		)`
 
 	var count int
-	err := config.DB.QueryRow(query, userID, minutes).Scan(&count)
+	var lastFailure *time.Time
+	err := config.DB.QueryRow(query, userID, since).Scan(&count, &lastFailure)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, lastFailure, nil
+}
+
+// ResetLockout azzera il conteggio dei tentativi falliti (sblocco manuale)
+func (r *AuthRepository) ResetLockout(userID int) (bool, error) {
+	query := `UPDATE auth_credentials SET lockout_reset_at = NOW() WHERE user_id = $1`
+
+	result, err := config.DB.Exec(query, userID)
 	if err != nil {
-		return 0, nil
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetLoginAttempts recupera i tentativi di login di un utente (piú recenti prima) e il totale
+func (r *AuthRepository) GetLoginAttempts(userID int, limit, offset int) ([]models.AuthLoginAttempt, int, error) {
+	var total int
+	err := config.DB.QueryRow(`SELECT COUNT(*) FROM auth_login_attempts WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, email, ip_address, user_agent, timestamp, result
+		FROM auth_login_attempts
+		WHERE user_id = $1
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := config.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var attempts []models.AuthLoginAttempt
+	for rows.Next() {
+		var attempt models.AuthLoginAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IPAddress,
+			&attempt.UserAgent,
+			&attempt.Timestamp,
+			&attempt.Result,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
 	}
 
-	return count, nil
+	return attempts, total, nil
 }
 
 // CheckEmailExists verifica se un email é giá registrato
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"time"
+)
+
+type AuthSessionRepository struct{}
+
+// NewAuthSessionRepository crea una nuova istanza del repository
+func NewAuthSessionRepository() *AuthSessionRepository {
+	return &AuthSessionRepository{}
+}
+
+const authSessionColumns = `
+	id, user_id, session_id, device_name, ip_address, user_agent,
+	created_at, last_seen_at, revoked_at`
+
+// Create salva una nuova sessione di login
+func (r *AuthSessionRepository) Create(session *models.AuthSession) error {
+	query := `
+		INSERT INTO auth_sessions (user_id, session_id, device_name, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_seen_at`
+
+	return config.DB.QueryRow(
+		query,
+		session.UserID,
+		session.SessionID,
+		session.DeviceName,
+		session.IPAddress,
+		session.UserAgent,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+// GetBySessionID recupera una sessione dal claim sid
+func (r *AuthSessionRepository) GetBySessionID(sessionID string) (*models.AuthSession, error) {
+	query := `SELECT ` + authSessionColumns + ` FROM auth_sessions WHERE session_id = $1`
+	return scanAuthSession(config.DB.QueryRow(query, sessionID))
+}
+
+// GetByID recupera una sessione per ID
+func (r *AuthSessionRepository) GetByID(id int) (*models.AuthSession, error) {
+	query := `SELECT ` + authSessionColumns + ` FROM auth_sessions WHERE id = $1`
+	return scanAuthSession(config.DB.QueryRow(query, id))
+}
+
+// GetActiveByUserID recupera le sessioni non revocate dell'utente usate dopo activeSince (piú recenti prima)
+func (r *AuthSessionRepository) GetActiveByUserID(userID int, activeSince time.Time) ([]models.AuthSession, error) {
+	query := `SELECT ` + authSessionColumns + ` FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND last_seen_at > $2
+		ORDER BY last_seen_at DESC, id DESC`
+
+	rows, err := config.DB.Query(query, userID, activeSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.AuthSession{}
+	for rows.Next() {
+		session, err := scanAuthSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TouchLastSeen aggiorna ultimo accesso ed eventualmente IP e user agent (al massimo una volta al minuto)
+func (r *AuthSessionRepository) TouchLastSeen(sessionID string, ipAddress, userAgent *string) error {
+	query := `
+		UPDATE auth_sessions
+		SET last_seen_at = NOW(), ip_address = COALESCE($2, ip_address), user_agent = COALESCE($3, user_agent)
+		WHERE session_id = $1 AND revoked_at IS NULL AND last_seen_at < NOW() - INTERVAL '1 minute'`
+
+	_, err := config.DB.Exec(query, sessionID, ipAddress, userAgent)
+	return err
+}
+
+// Revoke chiude una sessione
+func (r *AuthSessionRepository) Revoke(sessionID string) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE session_id = $1 AND revoked_at IS NULL`
+
+	_, err := config.DB.Exec(query, sessionID)
+	return err
+}
+
+// RevokeAllForUser chiude tutte le sessioni dell'utente tranne exceptSessionID (vuoto = tutte)
+// e restituisce i session_id chiusi
+func (r *AuthSessionRepository) RevokeAllForUser(userID int, exceptSessionID string) ([]string, error) {
+	query := `
+		UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND session_id <> $2
+		RETURNING session_id`
+
+	rows, err := config.DB.Query(query, userID, exceptSessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var sessionID string
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	return sessionIDs, rows.Err()
+}
+
+// scanAuthSession legge una riga di auth_sessions, nil se non trovata
+func scanAuthSession(row rowScanner) (*models.AuthSession, error) {
+	var session models.AuthSession
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.SessionID,
+		&session.DeviceName,
+		&session.IPAddress,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type ImpersonationRepository struct{}
+
+// NewImpersonationRepository crea una nuova istanza del repository
+func NewImpersonationRepository() *ImpersonationRepository {
+	return &ImpersonationRepository{}
+}
+
+const impersonationSessionQuery = `
+	SELECT s.id, s.actor_id, a.email, s.target_user_id, t.email, s.reason,
+		s.ip_address, s.started_at, s.expires_at, s.ended_at
+	FROM impersonation_sessions s
+	JOIN users a ON s.actor_id = a.id
+	JOIN users t ON s.target_user_id = t.id`
+
+// CreateSession registra l'avvio di una sessione di impersonificazione
+func (r *ImpersonationRepository) CreateSession(session *models.ImpersonationSession) error {
+	query := `
+		INSERT INTO impersonation_sessions (actor_id, target_user_id, reason, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, started_at`
+
+	return config.DB.QueryRow(
+		query,
+		session.ActorID,
+		session.TargetUserID,
+		session.Reason,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.StartedAt)
+}
+
+// GetSessionByID recupera una sessione di impersonificazione
+func (r *ImpersonationRepository) GetSessionByID(id int) (*models.ImpersonationSession, error) {
+	return scanImpersonationSession(config.DB.QueryRow(impersonationSessionQuery+` WHERE s.id = $1`, id))
+}
+
+// GetSessions recupera le sessioni piú recenti, con il totale per la paginazione
+func (r *ImpersonationRepository) GetSessions(limit, offset int) ([]models.ImpersonationSession, int, error) {
+	var total int
+	if err := config.DB.QueryRow(`SELECT COUNT(*) FROM impersonation_sessions`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := config.DB.Query(impersonationSessionQuery+` ORDER BY s.started_at DESC, s.id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	sessions := []models.ImpersonationSession{}
+	for rows.Next() {
+		session, err := scanImpersonationSession(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, total, rows.Err()
+}
+
+// EndSession chiude la sessione (logout dell'operatore dal token di impersonificazione)
+func (r *ImpersonationRepository) EndSession(id int) error {
+	query := `UPDATE impersonation_sessions SET ended_at = NOW() WHERE id = $1 AND ended_at IS NULL`
+
+	_, err := config.DB.Exec(query, id)
+	return err
+}
+
+// RecordCall salva una chiamata eseguita con un token di impersonificazione
+func (r *ImpersonationRepository) RecordCall(entry *models.ImpersonationAuditEntry) error {
+	query := `
+		INSERT INTO impersonation_audit_log (session_id, actor_id, target_user_id, method, path, status_code, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(
+		query,
+		entry.SessionID,
+		entry.ActorID,
+		entry.TargetUserID,
+		entry.Method,
+		entry.Path,
+		entry.StatusCode,
+		entry.IPAddress,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// GetAuditLog recupera le chiamate di una sessione in ordine cronologico
+func (r *ImpersonationRepository) GetAuditLog(sessionID int) ([]models.ImpersonationAuditEntry, error) {
+	query := `
+		SELECT id, session_id, actor_id, target_user_id, method, path, status_code, ip_address, created_at
+		FROM impersonation_audit_log
+		WHERE session_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := config.DB.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ImpersonationAuditEntry{}
+	for rows.Next() {
+		var entry models.ImpersonationAuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.SessionID,
+			&entry.ActorID,
+			&entry.TargetUserID,
+			&entry.Method,
+			&entry.Path,
+			&entry.StatusCode,
+			&entry.IPAddress,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// scanImpersonationSession legge una sessione con le email dei due utenti
+func scanImpersonationSession(row rowScanner) (*models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+	err := row.Scan(
+		&session.ID,
+		&session.ActorID,
+		&session.ActorEmail,
+		&session.TargetUserID,
+		&session.TargetEmail,
+		&session.Reason,
+		&session.IPAddress,
+		&session.StartedAt,
+		&session.ExpiresAt,
+		&session.EndedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type InvitationRepository struct{}
+
+// NewInvitationRepository crea una nuova istanza del repository
+func NewInvitationRepository() *InvitationRepository {
+	return &InvitationRepository{}
+}
+
+const invitationColumns = `
+	id, email, name, role_id, manager_id, token_hash, invited_by,
+	expires_at, accepted_at, accepted_user_id, revoked_at, created_at`
+
+// Create salva un nuovo invito
+func (r *InvitationRepository) Create(invitation *models.UserInvitation) error {
+	query := `
+		INSERT INTO user_invitations (email, name, role_id, manager_id, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(
+		query,
+		invitation.Email,
+		invitation.Name,
+		invitation.RoleID,
+		invitation.ManagerID,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+}
+
+// GetByID recupera un invito per ID
+func (r *InvitationRepository) GetByID(id int) (*models.UserInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM user_invitations WHERE id = $1`
+	return scanInvitation(config.DB.QueryRow(query, id))
+}
+
+// GetByTokenHash recupera un invito dall'hash del token
+func (r *InvitationRepository) GetByTokenHash(tokenHash string) (*models.UserInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM user_invitations WHERE token_hash = $1`
+	return scanInvitation(config.DB.QueryRow(query, tokenHash))
+}
+
+// GetAll recupera gli inviti, opzionalmente filtrati per stato (piú recenti prima)
+func (r *InvitationRepository) GetAll(status models.InvitationStatus) ([]models.UserInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM user_invitations`
+
+	switch status {
+	case models.InvitationPending:
+		query += ` WHERE accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`
+	case models.InvitationAccepted:
+		query += ` WHERE accepted_at IS NOT NULL`
+	case models.InvitationRevoked:
+		query += ` WHERE revoked_at IS NOT NULL`
+	case models.InvitationExpired:
+		query += ` WHERE accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= NOW()`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []models.UserInvitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// Revoke annulla un invito non ancora accettato
+func (r *InvitationRepository) Revoke(id int) (bool, error) {
+	query := `UPDATE user_invitations SET revoked_at = NOW() WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	result, err := config.DB.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RevokePendingForEmail annulla gli inviti ancora aperti per un'email (nuovo invito = unico valido)
+func (r *InvitationRepository) RevokePendingForEmail(email string) error {
+	query := `UPDATE user_invitations SET revoked_at = NOW() WHERE email = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	_, err := config.DB.Exec(query, email)
+	return err
+}
+
+// AcceptWithNewUser consuma l'invito e crea utente + credenziali in un'unica transazione.
+// Ritorna false se l'invito non é piú valido (giá accettato, revocato o scaduto)
+func (r *InvitationRepository) AcceptWithNewUser(invitationID int, user *models.User, passwordHash, salt string) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 1. Consumo l'invito (due accettazioni concorrenti = una sola valida)
+	result, err := tx.Exec(`
+		UPDATE user_invitations SET accepted_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`,
+		invitationID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	// 2. Creo l'utente con ruolo e manager dell'invito
+	err = tx.QueryRow(
+		`INSERT INTO users (name, email, role_id, manager_id) VALUES ($1, $2, $3, $4) RETURNING id`,
+		user.Name, user.Email, user.RoleID, user.ManagerID,
+	).Scan(&user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// 3. Credenziali
+	_, err = tx.Exec(
+		`INSERT INTO auth_credentials (user_id, password_hash, salt) VALUES ($1, $2, $3)`,
+		user.ID, passwordHash, salt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// 4. Collego l'invito all'utente creato
+	_, err = tx.Exec(`UPDATE user_invitations SET accepted_user_id = $2 WHERE id = $1`, invitationID, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// rowScanner accomuna *sql.Row e *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanInvitation legge un invito da una riga
+func scanInvitation(row rowScanner) (*models.UserInvitation, error) {
+	var invitation models.UserInvitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.Name,
+		&invitation.RoleID,
+		&invitation.ManagerID,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.AcceptedUserID,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Invito non trovato
+		}
+		return nil, err
+	}
+
+	return &invitation, nil
+}
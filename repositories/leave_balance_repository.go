@@ -313,4 +313,31 @@ func (r *LeaveBalanceRepository) InitializeUserBalance(userID int) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// GetSettlementByUserID recupera la liquidazione finale di un utente cessato
+func (r *LeaveBalanceRepository) GetSettlementByUserID(userID int) (*models.LeaveSettlement, error) {
+	query := `
+		SELECT id, user_id, termination_date, residual_holidays, residual_permits, created_by, created_at
+		FROM leave_settlements
+		WHERE user_id = $1`
+
+	var settlement models.LeaveSettlement
+	err := config.DB.QueryRow(query, userID).Scan(
+		&settlement.ID,
+		&settlement.UserID,
+		&settlement.TerminationDate,
+		&settlement.ResidualHolidays,
+		&settlement.ResidualPermits,
+		&settlement.CreatedBy,
+		&settlement.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Nessuna liquidazione
+		}
+		return nil, err
+	}
+
+	return &settlement, nil
+}
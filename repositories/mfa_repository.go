@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type MFARepository struct{}
+
+// NewMFARepository crea una nuova istanza del repository
+func NewMFARepository() *MFARepository {
+	return &MFARepository{}
+}
+
+// GetByUserID recupera la configurazione TOTP di un utente
+func (r *MFARepository) GetByUserID(userID int) (*models.UserMFA, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1`
+
+	var mfa models.UserMFA
+	err := config.DB.QueryRow(query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // 2FA mai configurata
+		}
+		return nil, err
+	}
+
+	return &mfa, nil
+}
+
+// SavePending salva un nuovo segreto in attesa di conferma.
+// Una configurazione giá attiva non viene mai sovrascritta
+func (r *MFARepository) SavePending(userID int, secret string) (bool, error) {
+	query := `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL`
+
+	result, err := config.DB.Exec(query, userID, secret)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Enable attiva la 2FA e sostituisce i codici di recupero in un'unica transazione
+func (r *MFARepository) Enable(userID int, step int64, recoveryCodeHashes []string) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND enabled_at IS NULL`,
+		userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil // Giá attivata da una richiesta concorrente
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// ReplaceRecoveryCodes invalida i codici di recupero esistenti e salva quelli nuovi
+func (r *MFARepository) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes sostituisce i codici di recupero all'interno di una transazione
+func replaceRecoveryCodes(tx *sql.Tx, userID int, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err := tx.Exec(
+			`INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, codeHash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkStepUsed registra il passo TOTP usato solo se successivo all'ultimo accettato.
+// Ritorna false se il codice era giá stato usato
+func (r *MFARepository) MarkStepUsed(userID int, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := config.DB.Exec(query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// UseRecoveryCode consuma un codice di recupero. Ritorna false se non valido o giá usato
+func (r *MFARepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := config.DB.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// CountRemainingRecoveryCodes conta i codici di recupero non ancora usati
+func (r *MFARepository) CountRemainingRecoveryCodes(userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM user_mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	err := config.DB.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// Delete rimuove la configurazione 2FA e i codici di recupero dell'utente
+func (r *MFARepository) Delete(userID int) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type OfficeSiteRepository struct{}
+
+// NewOfficeSiteRepository crea la nuova istanza della repo
+func NewOfficeSiteRepository() *OfficeSiteRepository {
+	return &OfficeSiteRepository{}
+}
+
+const officeSiteColumns = `id, name, latitude, longitude, radius_meters, policy, is_active, created_at, updated_at`
+
+// GetAll recupera le sedi ordinate per nome, solo quelle attive se activeOnly
+func (r *OfficeSiteRepository) GetAll(activeOnly bool) ([]models.OfficeSite, error) {
+	rows, err := config.DB.Query(`SELECT `+officeSiteColumns+` FROM office_sites WHERE is_active OR NOT $1 ORDER BY name`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := []models.OfficeSite{}
+	for rows.Next() {
+		site, err := scanOfficeSite(rows)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, *site)
+	}
+
+	return sites, rows.Err()
+}
+
+// GetByID recupera una sede, nil se non trovata
+func (r *OfficeSiteRepository) GetByID(id int) (*models.OfficeSite, error) {
+	return scanOfficeSite(config.DB.QueryRow(`SELECT `+officeSiteColumns+` FROM office_sites WHERE id = $1`, id))
+}
+
+// Create inserisce una nuova sede
+func (r *OfficeSiteRepository) Create(site *models.OfficeSite) error {
+	query := `
+		INSERT INTO office_sites (name, latitude, longitude, radius_meters, policy, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	return config.DB.QueryRow(query,
+		site.Name, site.Latitude, site.Longitude, site.RadiusMeters, site.Policy, site.IsActive,
+	).Scan(&site.ID, &site.CreatedAt, &site.UpdatedAt)
+}
+
+// Update modifica una sede esistente
+func (r *OfficeSiteRepository) Update(site *models.OfficeSite) error {
+	query := `
+		UPDATE office_sites
+		SET name = $2, latitude = $3, longitude = $4, radius_meters = $5, policy = $6, is_active = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	return config.DB.QueryRow(query,
+		site.ID, site.Name, site.Latitude, site.Longitude, site.RadiusMeters, site.Policy, site.IsActive,
+	).Scan(&site.CreatedAt, &site.UpdatedAt)
+}
+
+// ExistsByName verifica se esiste giá un'altra sede con lo stesso nome
+func (r *OfficeSiteRepository) ExistsByName(name string, excludeID int) (bool, error) {
+	var exists bool
+	err := config.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM office_sites WHERE LOWER(name) = LOWER($1) AND id <> $2)`, name, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// scanOfficeSite legge una riga di office_sites, nil se non trovata
+func scanOfficeSite(row rowScanner) (*models.OfficeSite, error) {
+	var site models.OfficeSite
+	err := row.Scan(
+		&site.ID,
+		&site.Name,
+		&site.Latitude,
+		&site.Longitude,
+		&site.RadiusMeters,
+		&site.Policy,
+		&site.IsActive,
+		&site.CreatedAt,
+		&site.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &site, nil
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type OIDCRepository struct{}
+
+// NewOIDCRepository crea una nuova istanza del repository
+func NewOIDCRepository() *OIDCRepository {
+	return &OIDCRepository{}
+}
+
+// CreateLoginRequest salva un login SSO avviato
+func (r *OIDCRepository) CreateLoginRequest(request *models.OIDCLoginRequest) error {
+	query := `
+		INSERT INTO oidc_login_requests (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(
+		query,
+		request.StateHash,
+		request.Nonce,
+		request.CodeVerifier,
+		request.ExpiresAt,
+	).Scan(&request.ID, &request.CreatedAt)
+}
+
+// ConsumeLoginRequest marca come usato il login SSO con lo state indicato e lo restituisce.
+// L'UPDATE condizionale garantisce che lo state sia utilizzabile una sola volta anche con
+// richieste concorrenti; ritorna nil se lo state é sconosciuto, scaduto o giá usato
+func (r *OIDCRepository) ConsumeLoginRequest(stateHash string) (*models.OIDCLoginRequest, error) {
+	query := `
+		UPDATE oidc_login_requests SET consumed_at = NOW()
+		WHERE state_hash = $1 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING id, state_hash, nonce, code_verifier, expires_at, consumed_at, created_at`
+
+	var request models.OIDCLoginRequest
+	err := config.DB.QueryRow(query, stateHash).Scan(
+		&request.ID,
+		&request.StateHash,
+		&request.Nonce,
+		&request.CodeVerifier,
+		&request.ExpiresAt,
+		&request.ConsumedAt,
+		&request.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// DeleteExpiredLoginRequests elimina i login SSO scaduti o giá conclusi
+func (r *OIDCRepository) DeleteExpiredLoginRequests() (int64, error) {
+	query := `DELETE FROM oidc_login_requests WHERE expires_at < NOW() OR consumed_at IS NOT NULL`
+
+	result, err := config.DB.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetIdentity recupera l'identitá collegata a issuer + subject
+func (r *OIDCRepository) GetIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE issuer = $1 AND subject = $2`
+
+	var identity models.UserIdentity
+	err := config.DB.QueryRow(query, issuer, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// GetUserIDByEmail recupera l'ID dell'utente con l'email indicata (nil se non esiste)
+func (r *OIDCRepository) GetUserIDByEmail(email string) (*int, error) {
+	query := `SELECT id FROM users WHERE LOWER(email) = LOWER($1)`
+
+	var userID int
+	err := config.DB.QueryRow(query, email).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &userID, nil
+}
+
+// CreateIdentity collega un'identitá a un utente esistente
+func (r *OIDCRepository) CreateIdentity(identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at, last_login_at`
+
+	return config.DB.QueryRow(
+		query,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+}
+
+// TouchIdentity aggiorna email e data dell'ultimo login dell'identitá
+func (r *OIDCRepository) TouchIdentity(id int, email *string) error {
+	query := `UPDATE user_identities SET email = COALESCE($2, email), last_login_at = NOW() WHERE id = $1`
+
+	_, err := config.DB.Exec(query, id, email)
+	return err
+}
+
+// CreateUserWithIdentity crea un nuovo utente (senza password) e la sua identitá in una transazione
+func (r *OIDCRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Utente: non ha credenziali locali, accede solo tramite SSO
+	userQuery := `
+		INSERT INTO users (name, email, role_id, manager_id) VALUES ($1, $2, $3, $4)
+		RETURNING id`
+	err = tx.QueryRow(userQuery, user.Name, user.Email, user.RoleID, user.ManagerID).Scan(&user.ID)
+	if err != nil {
+		return err
+	}
+
+	// 2. Identitá
+	identity.UserID = user.ID
+	identityQuery := `
+		INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at, last_login_at`
+	err = tx.QueryRow(identityQuery, identity.UserID, identity.Issuer, identity.Subject, identity.Email).
+		Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
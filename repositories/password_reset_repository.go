@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type PasswordResetRepository struct{}
+
+// NewPasswordResetRepository crea una nuova istanza del repository
+func NewPasswordResetRepository() *PasswordResetRepository {
+	return &PasswordResetRepository{}
+}
+
+// Create salva un nuovo token di reset (solo hash)
+func (r *PasswordResetRepository) Create(token *models.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetValidByHash recupera un token non ancora usato e non scaduto
+func (r *PasswordResetRepository) GetValidByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	var token models.PasswordResetToken
+	err := config.DB.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Token inesistente, usato o scaduto
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consuma il token, ritorna false se era giá stato usato
+func (r *PasswordResetRepository) MarkUsed(id int) (bool, error) {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	result, err := config.DB.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateForUser consuma tutti i token ancora aperti dell'utente
+func (r *PasswordResetRepository) InvalidateForUser(userID int) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+
+	_, err := config.DB.Exec(query, userID)
+	return err
+}
+
+// CountRecentForUser conta i token creati per l'utente negli ultimi x minuti
+func (r *PasswordResetRepository) CountRecentForUser(userID int, minutes int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM password_reset_tokens
+		WHERE user_id = $1
+		AND created_at > NOW() - $2 * INTERVAL '1 minute'`
+
+	var count int
+	err := config.DB.QueryRow(query, userID, minutes).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
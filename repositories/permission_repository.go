@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"fmt"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type PermissionRepository struct{}
+
+// NewPermissionRepository crea una nuova istanza del repository
+func NewPermissionRepository() *PermissionRepository {
+	return &PermissionRepository{}
+}
+
+// GetAll restituisce il catalogo dei permessi
+func (r *PermissionRepository) GetAll() ([]models.Permission, error) {
+	query := `SELECT code, description FROM permissions ORDER BY code`
+
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Code, &permission.Description); err != nil {
+			return nil, fmt.Errorf("error scanning permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// GetByRoleID restituisce i codici dei permessi assegnati al ruolo
+func (r *PermissionRepository) GetByRoleID(roleID int) ([]string, error) {
+	query := `
+	SELECT permission_code
+	FROM role_permissions
+	WHERE role_id = $1
+	ORDER BY permission_code`
+
+	rows, err := config.DB.Query(query, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("error scanning role permission: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// ReplaceForRole sostituisce in una transazione tutti i permessi del ruolo
+func (r *PermissionRepository) ReplaceForRole(roleID int, codes []string) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("error clearing role permissions: %w", err)
+	}
+
+	for _, code := range codes {
+		_, err := tx.Exec(`INSERT INTO role_permissions (role_id, permission_code) VALUES ($1, $2)`, roleID, code)
+		if err != nil {
+			return fmt.Errorf("error assigning permission %s: %w", code, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,389 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"time"
+)
+
+type PrivacyRepository struct{}
+
+// NewPrivacyRepository crea una nuova istanza del repository
+func NewPrivacyRepository() *PrivacyRepository {
+	return &PrivacyRepository{}
+}
+
+// GetTimbrature recupera tutte le timbrature dell'utente, geolocalizzazione compresa (piú vecchie prima)
+func (r *PrivacyRepository) GetTimbrature(userID int) ([]models.Timbrature, error) {
+	query := `
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged
+		FROM timbrature
+		WHERE user_id = $1
+		ORDER BY timestamp, id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timbrature := []models.Timbrature{}
+	for rows.Next() {
+		var t models.Timbrature
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged); err != nil {
+			return nil, err
+		}
+		timbrature = append(timbrature, t)
+	}
+
+	return timbrature, rows.Err()
+}
+
+// GetTimbratureCorrections recupera tutte le richieste di correzione delle timbrature dell'utente
+func (r *PrivacyRepository) GetTimbratureCorrections(userID int) ([]models.TimbraturaCorrection, error) {
+	query := `SELECT ` + timbraturaCorrectionColumns + ` FROM timbrature_corrections WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTimbraturaCorrections(rows)
+}
+
+// GetRequests recupera tutte le richieste dell'utente con lo stato dell'approvazione
+func (r *PrivacyRepository) GetRequests(userID int) ([]models.RequestWithStatus, error) {
+	query := `
+		SELECT
+			r.id, r.user_id, r.start_date, r.end_date, r.request_type, r.notes, r.created_at,
+			COALESCE(a.status, 'PENDING'), a.id, u.name
+		FROM requests r
+		LEFT JOIN approvals a ON r.id = a.request_id
+		LEFT JOIN users u ON a.approver_id = u.id
+		WHERE r.user_id = $1
+		ORDER BY r.created_at, r.id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.RequestWithStatus{}
+	for rows.Next() {
+		var req models.RequestWithStatus
+		err := rows.Scan(
+			&req.ID,
+			&req.UserID,
+			&req.StartDate,
+			&req.EndDate,
+			&req.RequestType,
+			&req.Notes,
+			&req.CreatedAt,
+			&req.Status,
+			&req.ApprovalID,
+			&req.ApproverName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, rows.Err()
+}
+
+// GetApprovals recupera le approvazioni sulle richieste dell'utente e quelle date dall'utente come responsabile
+func (r *PrivacyRepository) GetApprovals(userID int) ([]models.Approval, error) {
+	query := `
+		SELECT a.id, a.request_id, a.approver_id, a.status, a.comments, a.approved_at
+		FROM approvals a
+		JOIN requests r ON r.id = a.request_id
+		WHERE r.user_id = $1 OR a.approver_id = $1
+		ORDER BY a.approved_at, a.id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []models.Approval{}
+	for rows.Next() {
+		var approval models.Approval
+		err := rows.Scan(
+			&approval.ID,
+			&approval.RequestID,
+			&approval.ApproverID,
+			&approval.Status,
+			&approval.Comments,
+			&approval.ApprovedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+// GetLoginAttempts recupera i tentativi di login dell'utente, compresi quelli sulla sua email
+// registrati senza user_id (piú vecchi prima)
+func (r *PrivacyRepository) GetLoginAttempts(userID int, email string) ([]models.AuthLoginAttempt, error) {
+	query := `
+		SELECT id, user_id, email, ip_address, user_agent, timestamp, result
+		FROM auth_login_attempts
+		WHERE user_id = $1 OR LOWER(email) = LOWER($2)
+		ORDER BY timestamp, id`
+
+	rows, err := config.DB.Query(query, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.AuthLoginAttempt{}
+	for rows.Next() {
+		var attempt models.AuthLoginAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IPAddress,
+			&attempt.UserAgent,
+			&attempt.Timestamp,
+			&attempt.Result,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// GetAuthEvents recupera gli eventi di sicurezza dell'utente (piú vecchi prima)
+func (r *PrivacyRepository) GetAuthEvents(userID int) ([]models.AuthEvent, error) {
+	query := `
+		SELECT id, user_id, event_type, ip_address, details, created_at
+		FROM auth_events
+		WHERE user_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuthEvent{}
+	for rows.Next() {
+		var event models.AuthEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.EventType, &event.IPAddress, &event.Details, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetSessions recupera tutte le sessioni di login dell'utente, anche quelle chiuse
+func (r *PrivacyRepository) GetSessions(userID int) ([]models.AuthSession, error) {
+	query := `SELECT ` + authSessionColumns + ` FROM auth_sessions WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.AuthSession{}
+	for rows.Next() {
+		session, err := scanAuthSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RecordRequest registra una richiesta dell'interessato evasa
+func (r *PrivacyRepository) RecordRequest(request *models.PrivacyRequest) error {
+	query := `
+		INSERT INTO privacy_requests (user_id, request_type, reason, performed_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(query, request.UserID, request.RequestType, request.Reason, request.PerformedBy).
+		Scan(&request.ID, &request.CreatedAt)
+}
+
+// GetPrivacyRequests recupera il registro delle richieste evase (piú recenti prima) e il totale
+func (r *PrivacyRepository) GetPrivacyRequests(limit, offset int) ([]models.PrivacyRequest, int, error) {
+	var total int
+	if err := config.DB.QueryRow(`SELECT COUNT(*) FROM privacy_requests`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, request_type, reason, performed_by, created_at
+		FROM privacy_requests
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := config.DB.Query(query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var requests []models.PrivacyRequest
+	for rows.Next() {
+		var request models.PrivacyRequest
+		err := rows.Scan(
+			&request.ID,
+			&request.UserID,
+			&request.RequestType,
+			&request.Reason,
+			&request.PerformedBy,
+			&request.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		requests = append(requests, request)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return requests, total, nil
+}
+
+// ErasureData operazioni di cancellazione da eseguire in un'unica transazione
+type ErasureData struct {
+	UserID        int
+	Email         string // Email attuale, per i dati registrati senza user_id
+	PseudonymName string
+	PseudonymMail string
+	ErasedAt      time.Time
+	Request       *models.PrivacyRequest
+}
+
+// Erase pseudonimizza l'utente in un'unica transazione. Restano intatti timbrature (senza
+// geolocalizzazione), richieste e correzioni (senza note e motivazioni), approvazioni, saldi e liquidazione; credenziali,
+// 2FA, sessioni, identitá SSO e tentativi di login vengono eliminati.
+// Ritorna false se l'utente non é cessato o é giá stato cancellato
+func (r *PrivacyRepository) Erase(data *ErasureData) (bool, int64, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	// 1. Anagrafica: nome ed email sostituiti da uno pseudonimo, i token emessi non valgono piú
+	result, err := tx.Exec(`
+		UPDATE users
+		SET name = $2, email = $3, erased_at = $4, security_version = security_version + 1
+		WHERE id = $1 AND status = $5 AND erased_at IS NULL`,
+		data.UserID, data.PseudonymName, data.PseudonymMail, data.ErasedAt, models.UserTerminated,
+	)
+	if err != nil {
+		return false, 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, err
+	}
+	if rowsAffected != 1 {
+		return false, 0, nil
+	}
+
+	// 2. Dati di autenticazione: non servono piú a nessuno
+	for _, query := range []string{
+		`DELETE FROM auth_credentials WHERE user_id = $1`,
+		`DELETE FROM password_history WHERE user_id = $1`,
+		`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+		`DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM auth_sessions WHERE user_id = $1`,
+	} {
+		if _, err := tx.Exec(query, data.UserID); err != nil {
+			return false, 0, err
+		}
+	}
+
+	// 3. Tentativi di login (anche quelli sull'email senza user_id)
+	result, err = tx.Exec(`DELETE FROM auth_login_attempts WHERE user_id = $1 OR LOWER(email) = LOWER($2)`, data.UserID, data.Email)
+	if err != nil {
+		return false, 0, err
+	}
+	deletedAttempts, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, err
+	}
+
+	// 4. Eventi di sicurezza: restano per l'audit, senza IP e dettagli
+	if _, err := tx.Exec(`UPDATE auth_events SET ip_address = NULL, details = NULL WHERE user_id = $1`, data.UserID); err != nil {
+		return false, 0, err
+	}
+
+	// 5. Timbrature e richieste: obblighi di legge, si tolgono solo posizione precisa e note libere
+	if _, err := tx.Exec(`UPDATE timbrature SET geolocation = NULL, latitude = NULL, longitude = NULL, accuracy_meters = NULL WHERE user_id = $1`, data.UserID); err != nil {
+		return false, 0, err
+	}
+	if _, err := tx.Exec(`UPDATE requests SET notes = NULL WHERE user_id = $1`, data.UserID); err != nil {
+		return false, 0, err
+	}
+	if _, err := tx.Exec(`UPDATE timbrature_corrections SET reason = '', review_comment = NULL WHERE user_id = $1`, data.UserID); err != nil {
+		return false, 0, err
+	}
+
+	// 6. Inviti ricevuti: nome ed email dell'invitato
+	_, err = tx.Exec(`
+		UPDATE user_invitations SET name = $2, email = $3
+		WHERE accepted_user_id = $1 OR LOWER(email) = LOWER($4)`,
+		data.UserID, data.PseudonymName, data.PseudonymMail, data.Email,
+	)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// 7. Registro della richiesta
+	if request := data.Request; request != nil {
+		err = tx.QueryRow(`
+			INSERT INTO privacy_requests (user_id, request_type, reason, performed_by)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at`,
+			request.UserID, request.RequestType, request.Reason, request.PerformedBy,
+		).Scan(&request.ID, &request.CreatedAt)
+		if err != nil {
+			return false, 0, err
+		}
+	}
+
+	return true, deletedAttempts, tx.Commit()
+}
+
+// IsErased verifica se l'utente é giá stato cancellato
+func (r *PrivacyRepository) IsErased(userID int) (bool, error) {
+	var erasedAt sql.NullTime
+	err := config.DB.QueryRow(`SELECT erased_at FROM users WHERE id = $1`, userID).Scan(&erasedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return erasedAt.Valid, nil
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type RefreshTokenRepository struct{}
+
+// NewRefreshTokenRepository crea una nuova istanza del repository
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{}
+}
+
+// Create salva un nuovo refresh token (solo hash)
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, device_name, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(
+		query,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.DeviceName,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByHash recupera un refresh token dal suo hash
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, device_name, expires_at, created_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	var token models.RefreshToken
+	err := config.DB.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.DeviceName,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Token non trovato
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed segna il token come usato solo se ancora valido.
+// Ritorna false se il token era giá stato usato o revocato (possibile riuso)
+func (r *RefreshTokenRepository) MarkUsed(id int) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+
+	result, err := config.DB.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RevokeFamily revoca tutti i token di una famiglia (un login/dispositivo)
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+
+	_, err := config.DB.Exec(query, familyID)
+	return err
+}
+
+// RevokeAllForUser revoca tutti i refresh token di un utente
+func (r *RefreshTokenRepository) RevokeAllForUser(userID int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := config.DB.Exec(query, userID)
+	return err
+}
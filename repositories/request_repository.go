@@ -38,15 +38,17 @@ func (r *RequestRepository) Create(request *models.Request) (*models.Request, er
 	return request, nil
 }
 
-// GetAll recupera tutte le richieste con paginazione
-func (r *RequestRepository) GetAll(limit, offset int) ([]models.Request, error) {
-	query := `
+// GetAll recupera le richieste degli utenti nello scope con paginazione
+func (r *RequestRepository) GetAll(limit, offset int, scope *models.TeamScope) ([]models.Request, error) {
+	condition, args := scopeCondition("user_id", scope, []interface{}{limit, offset})
+	query := fmt.Sprintf(`
 		SELECT id, user_id, start_date, end_date, request_type, notes, created_at 
 		FROM requests 
+		WHERE %s
 		ORDER BY created_at DESC 
-		LIMIT $1 OFFSET $2`
+		LIMIT $1 OFFSET $2`, condition)
 
-	rows, err := config.DB.Query(query, limit, offset)
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -210,11 +212,12 @@ func (r *RequestRepository) GetByUserIDWithStatus(userID, limit, offset int) ([]
 }
 
 
-// GetByDateRange recupera richieste in un range di date specifico
-func (r *RequestRepository) GetByDateRange(startDate, endDate time.Time) ([]models.Request, error) {
-	query := `SELECT id, user_id, start_date, end_date, request_type, notes, created_at FROM requests WHERE (start_date <= $2 AND end_date >= $1) ORDER BY start_date ASC`
+// GetByDateRange recupera le richieste degli utenti nello scope in un range di date specifico
+func (r *RequestRepository) GetByDateRange(startDate, endDate time.Time, scope *models.TeamScope) ([]models.Request, error) {
+	condition, args := scopeCondition("user_id", scope, []interface{}{startDate, endDate})
+	query := fmt.Sprintf(`SELECT id, user_id, start_date, end_date, request_type, notes, created_at FROM requests WHERE (start_date <= $2 AND end_date >= $1) AND %s ORDER BY start_date ASC`, condition)
 
-	rows, err := config.DB.Query(query,startDate,endDate)
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -295,11 +298,12 @@ func (r *RequestRepository) CheckOverlapForUser(userID int, startDate, endDate t
 	return count > 0, nil;
 }
 
-// GetPendingRequests recupera richieste che non hanno ancora approvazioni
-func (r *RequestRepository) GetPendingRequests() ([]models.Request, error) {
-	query := `SELECT r.id, r.user_id, r.start_date, r.end_date, r.request_type, r.notes, r.created_at FROM requests r LEFT JOIN approvals a ON r.id = a.request_id WHERE a.id IS NULL ORDER BY r.created_at ASC`
+// GetPendingRequests recupera le richieste degli utenti nello scope che non hanno ancora approvazioni
+func (r *RequestRepository) GetPendingRequests(scope *models.TeamScope) ([]models.Request, error) {
+	condition, args := scopeCondition("r.user_id", scope, nil)
+	query := fmt.Sprintf(`SELECT r.id, r.user_id, r.start_date, r.end_date, r.request_type, r.notes, r.created_at FROM requests r LEFT JOIN approvals a ON r.id = a.request_id WHERE a.id IS NULL AND %s ORDER BY r.created_at ASC`, condition)
 
-	rows,err := config.DB.Query(query)
+	rows,err := config.DB.Query(query, args...)
 	if err != nil {
 		return nil,err
 	}
@@ -329,6 +333,85 @@ func (r *RequestRepository) GetPendingRequests() ([]models.Request, error) {
 	return requests, nil
 }
 
+// GetApprovedByUser recupera le richieste approvate di un utente (accettate e non rifiutate né revocate)
+func (r *RequestRepository) GetApprovedByUser(userID int) ([]models.Request, error) {
+	query := `
+		SELECT r.id, r.user_id, r.start_date, r.end_date, r.request_type, r.notes, r.created_at
+		FROM requests r
+		WHERE r.user_id = $1
+		  AND EXISTS (SELECT 1 FROM approvals a WHERE a.request_id = r.id AND a.status = $2)
+		  AND NOT EXISTS (SELECT 1 FROM approvals a WHERE a.request_id = r.id AND a.status IN ($3, $4))
+		ORDER BY r.start_date ASC`
+
+	rows, err := config.DB.Query(query, userID, models.ApprovalAccepted, models.ApprovalRejected, models.ApprovalRevoked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+
+	for rows.Next() {
+		var req models.Request
+		err := rows.Scan(
+			&req.ID,
+			&req.UserID,
+			&req.StartDate,
+			&req.EndDate,
+			&req.RequestType,
+			&req.Notes,
+			&req.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetPendingByUserEndingAfter recupera le richieste in attesa di un utente che terminano dopo la data indicata
+func (r *RequestRepository) GetPendingByUserEndingAfter(userID int, date time.Time) ([]models.Request, error) {
+	query := `
+		SELECT r.id, r.user_id, r.start_date, r.end_date, r.request_type, r.notes, r.created_at
+		FROM requests r
+		LEFT JOIN approvals a ON r.id = a.request_id
+		WHERE a.id IS NULL AND r.user_id = $1 AND r.end_date > $2
+		ORDER BY r.start_date ASC`
+
+	rows, err := config.DB.Query(query, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+
+	for rows.Next() {
+		var req models.Request
+		err := rows.Scan(
+			&req.ID,
+			&req.UserID,
+			&req.StartDate,
+			&req.EndDate,
+			&req.RequestType,
+			&req.Notes,
+			&req.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
 // Update aggiorna una richiesta esistente
 func (r *RequestRepository) Update(request *models.Request) (bool, error) {
 	query := `UPDATE requests SET start_date = $1, end_date = $2, request_type = $3, notes = $4 WHERE id = $5`
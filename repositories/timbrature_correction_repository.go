@@ -0,0 +1,361 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"time"
+)
+
+type TimbratureCorrectionRepository struct{}
+
+// NewTimbratureCorrectionRepository crea la nuova istanza della repo
+func NewTimbratureCorrectionRepository() *TimbratureCorrectionRepository {
+	return &TimbratureCorrectionRepository{}
+}
+
+const timbraturaCorrectionColumns = `id, user_id, correction_type, timbratura_id, timestamp, action_type, location,
+	reason, status, reviewer_id, review_comment, reviewed_at, applied_timbratura_id, created_at`
+
+// Create inserisce una nuova richiesta di correzione in attesa
+func (r *TimbratureCorrectionRepository) Create(correction *models.TimbraturaCorrection) error {
+	query := `
+		INSERT INTO timbrature_corrections (user_id, correction_type, timbratura_id, timestamp, action_type, location, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	return config.DB.QueryRow(query,
+		correction.UserID,
+		correction.CorrectionType,
+		correction.TimbraturaID,
+		correction.Timestamp,
+		correction.ActionType,
+		correction.Location,
+		correction.Reason,
+		correction.Status,
+	).Scan(&correction.ID, &correction.CreatedAt)
+}
+
+// GetByID recupera una richiesta di correzione, nil se non trovata
+func (r *TimbratureCorrectionRepository) GetByID(id int) (*models.TimbraturaCorrection, error) {
+	query := `SELECT ` + timbraturaCorrectionColumns + ` FROM timbrature_corrections WHERE id = $1`
+	return scanTimbraturaCorrection(config.DB.QueryRow(query, id))
+}
+
+// HasPendingForTimbratura verifica se la timbratura ha giá una correzione in attesa
+func (r *TimbratureCorrectionRepository) HasPendingForTimbratura(timbraturaID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM timbrature_corrections WHERE timbratura_id = $1 AND status = $2)`
+
+	var exists bool
+	err := config.DB.QueryRow(query, timbraturaID, models.CorrectionPending).Scan(&exists)
+	return exists, err
+}
+
+// GetByUserID recupera le richieste di correzione di un utente, piú recenti prima
+func (r *TimbratureCorrectionRepository) GetByUserID(userID, limit, offset int) ([]models.TimbraturaCorrection, error) {
+	query := `
+		SELECT ` + timbraturaCorrectionColumns + `
+		FROM timbrature_corrections
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := config.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTimbraturaCorrections(rows)
+}
+
+// GetAll recupera le richieste di correzione degli utenti nello scope, filtrate per status se indicato
+func (r *TimbratureCorrectionRepository) GetAll(status *models.CorrectionStatus, limit, offset int, scope *models.TeamScope) ([]models.TimbraturaCorrection, error) {
+	condition, args := scopeCondition("user_id", scope, []interface{}{limit, offset, status})
+	query := fmt.Sprintf(`
+		SELECT `+timbraturaCorrectionColumns+`
+		FROM timbrature_corrections
+		WHERE ($3::VARCHAR IS NULL OR status = $3) AND %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2`, condition)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTimbraturaCorrections(rows)
+}
+
+// Cancel ritira una correzione in attesa del dipendente. Ritorna false se non é piú in attesa
+func (r *TimbratureCorrectionRepository) Cancel(id, userID int) (bool, error) {
+	query := `
+		UPDATE timbrature_corrections
+		SET status = $3, reviewed_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = $4`
+
+	result, err := config.DB.Exec(query, id, userID, models.CorrectionCancelled, models.CorrectionPending)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// Reject rifiuta una correzione in attesa. Ritorna false se non é piú in attesa
+func (r *TimbratureCorrectionRepository) Reject(id, reviewerID int, comment *string) (bool, error) {
+	query := `
+		UPDATE timbrature_corrections
+		SET status = $4, reviewer_id = $2, review_comment = $3, reviewed_at = NOW()
+		WHERE id = $1 AND status = $5`
+
+	result, err := config.DB.Exec(query, id, reviewerID, comment, models.CorrectionRejected, models.CorrectionPending)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// Apply approva la correzione e la applica in un'unica transazione: la timbratura da modificare o
+// togliere viene annullata (resta in tabella con voided_at), per ADD e MODIFY viene inserita la nuova.
+// Entrambe le operazioni finiscono nello storico delle revisioni.
+// Ritorna false se la correzione non é piú in attesa o la timbratura é giá stata annullata
+func (r *TimbratureCorrectionRepository) Apply(correction *models.TimbraturaCorrection, reviewerID int, comment *string, appliedAt time.Time,
+	validate func(*models.TimbraturaCorrection, *models.CorrectionNeighbours) error) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 0. Blocca le correzioni concorrenti dello stesso dipendente e ricontrolla la sequenza
+	// sulle timbrature attuali, dentro la transazione
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, correction.UserID); err != nil {
+		return false, err
+	}
+	neighbours, err := correctionNeighbours(tx, correction)
+	if err != nil {
+		return false, err
+	}
+	if neighbours == nil {
+		return false, nil
+	}
+	if err := validate(correction, neighbours); err != nil {
+		return false, err
+	}
+
+	// 1. Chiude la richiesta (solo se ancora in attesa, evita doppie approvazioni concorrenti)
+	result, err := tx.Exec(`
+		UPDATE timbrature_corrections
+		SET status = $4, reviewer_id = $2, review_comment = $3, reviewed_at = $5
+		WHERE id = $1 AND status = $6`,
+		correction.ID, reviewerID, comment, models.CorrectionApproved, appliedAt, models.CorrectionPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	// 2. Annulla la timbratura originale
+	if correction.CorrectionType == models.CorrectionModify || correction.CorrectionType == models.CorrectionRemove {
+		voided := &models.TimbraturaRevision{
+			RevisionType: models.RevisionVoided,
+			Source:       models.RevisionSourceCorrection,
+			ChangedBy:    &reviewerID,
+			CorrectionID: &correction.ID,
+		}
+		err := tx.QueryRow(`
+			UPDATE timbrature
+			SET voided_at = $3, voided_by_correction_id = $2
+			WHERE id = $1 AND user_id = $4 AND voided_at IS NULL
+			RETURNING id, user_id, timestamp, action_type, location`,
+			correction.TimbraturaID, correction.ID, appliedAt, correction.UserID,
+		).Scan(&voided.TimbraturaID, &voided.UserID, &voided.Timestamp, &voided.ActionType, &voided.Location)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if err := insertTimbraturaRevision(tx, voided); err != nil {
+			return false, err
+		}
+	}
+
+	// 3. Inserisce la timbratura corretta, la geolocalizzazione dell'originale non vale per il nuovo orario
+	if correction.CorrectionType == models.CorrectionAdd || correction.CorrectionType == models.CorrectionModify {
+		var appliedID int
+		err := tx.QueryRow(`
+			INSERT INTO timbrature (user_id, timestamp, action_type, location, correction_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+			correction.UserID, correction.Timestamp, correction.ActionType, correction.Location, correction.ID,
+		).Scan(&appliedID)
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := tx.Exec(`UPDATE timbrature_corrections SET applied_timbratura_id = $2 WHERE id = $1`, correction.ID, appliedID); err != nil {
+			return false, err
+		}
+
+		created := &models.TimbraturaRevision{
+			TimbraturaID: appliedID,
+			UserID:       correction.UserID,
+			RevisionType: models.RevisionCreated,
+			Source:       models.RevisionSourceCorrection,
+			Timestamp:    *correction.Timestamp,
+			ActionType:   *correction.ActionType,
+			Location:     *correction.Location,
+			ChangedBy:    &reviewerID,
+			CorrectionID: &correction.ID,
+		}
+		if err := insertTimbraturaRevision(tx, created); err != nil {
+			return false, err
+		}
+		correction.AppliedTimbraturaID = &appliedID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	correction.Status = models.CorrectionApproved
+	correction.ReviewerID = &reviewerID
+	correction.ReviewComment = comment
+	correction.ReviewedAt = &appliedAt
+	return true, nil
+}
+
+// GetNeighbours recupera le timbrature vicine a quella annullata e a quella nuova della correzione.
+// nil se la timbratura da correggere non esiste piú
+func (r *TimbratureCorrectionRepository) GetNeighbours(correction *models.TimbraturaCorrection) (*models.CorrectionNeighbours, error) {
+	return correctionNeighbours(config.DB, correction)
+}
+
+// queryRower esegue query a riga singola, sul database o dentro una transazione
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// correctionNeighbours vedi GetNeighbours: la timbratura originale é esclusa da entrambe le ricerche
+func correctionNeighbours(q queryRower, correction *models.TimbraturaCorrection) (*models.CorrectionNeighbours, error) {
+	neighbours := &models.CorrectionNeighbours{}
+	excludeID := 0
+
+	if correction.TimbraturaID != nil {
+		excludeID = *correction.TimbraturaID
+
+		var original time.Time
+		err := q.QueryRow(`SELECT timestamp FROM timbrature WHERE id = $1 AND user_id = $2 AND voided_at IS NULL`,
+			excludeID, correction.UserID).Scan(&original)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if neighbours.RemovedPrevious, neighbours.RemovedNext, err = adjacentTimbrature(q, correction.UserID, original, excludeID); err != nil {
+			return nil, err
+		}
+	}
+
+	if correction.CorrectionType != models.CorrectionRemove {
+		var err error
+		if neighbours.AddedPrevious, neighbours.AddedNext, err = adjacentTimbrature(q, correction.UserID, *correction.Timestamp, excludeID); err != nil {
+			return nil, err
+		}
+	}
+
+	return neighbours, nil
+}
+
+// adjacentTimbrature recupera le timbrature non annullate immediatamente prima e dopo timestamp,
+// ignorando excludeID (0 se nessuna). nil se non esistono
+func adjacentTimbrature(q queryRower, userID int, timestamp time.Time, excludeID int) (*models.Timbrature, *models.Timbrature, error) {
+	queries := []string{
+		`SELECT id, user_id, timestamp, action_type, location
+		FROM timbrature
+		WHERE user_id = $1 AND voided_at IS NULL AND id <> $3 AND timestamp <= $2
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`,
+		`SELECT id, user_id, timestamp, action_type, location
+		FROM timbrature
+		WHERE user_id = $1 AND voided_at IS NULL AND id <> $3 AND timestamp > $2
+		ORDER BY timestamp ASC, id ASC
+		LIMIT 1`,
+	}
+
+	var adjacent [2]*models.Timbrature
+	for i, query := range queries {
+		var t models.Timbrature
+		err := q.QueryRow(query, userID, timestamp, excludeID).Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		adjacent[i] = &t
+	}
+
+	return adjacent[0], adjacent[1], nil
+}
+
+// scanTimbraturaCorrection legge una riga di timbrature_corrections, nil se non trovata
+func scanTimbraturaCorrection(row rowScanner) (*models.TimbraturaCorrection, error) {
+	var correction models.TimbraturaCorrection
+	err := row.Scan(
+		&correction.ID,
+		&correction.UserID,
+		&correction.CorrectionType,
+		&correction.TimbraturaID,
+		&correction.Timestamp,
+		&correction.ActionType,
+		&correction.Location,
+		&correction.Reason,
+		&correction.Status,
+		&correction.ReviewerID,
+		&correction.ReviewComment,
+		&correction.ReviewedAt,
+		&correction.AppliedTimbraturaID,
+		&correction.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &correction, nil
+}
+
+func scanTimbraturaCorrections(rows *sql.Rows) ([]models.TimbraturaCorrection, error) {
+	corrections := []models.TimbraturaCorrection{}
+	for rows.Next() {
+		correction, err := scanTimbraturaCorrection(rows)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, *correction)
+	}
+
+	return corrections, rows.Err()
+}
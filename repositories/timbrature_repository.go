@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"database/sql"
+	"fmt"
 	"merendels-backend/config"
 	"merendels-backend/models"
 	"time"
@@ -14,28 +15,55 @@ func NewTimbratureRepository() *TimbratureRepository {
 	return &TimbratureRepository{}
 }
 
-// Create inserisce una nuova timbratura nel database
-func (r *TimbratureRepository) Create(timbratura *models.Timbrature) error {
-	query := `INSERT INTO timbrature (user_id, timestamp, action_type, location, geolocation) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+// Create inserisce una nuova timbratura nel database e la revisione di creazione nello storico
+// (revision indica solo origine e autore, il resto viene dalla timbratura)
+func (r *TimbratureRepository) Create(timbratura *models.Timbrature, revision *models.TimbraturaRevision) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertTimbratura(tx, timbratura, revision); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertTimbratura inserisce la timbratura e la sua revisione CREATED nella transazione del chiamante
+func insertTimbratura(tx *sql.Tx, timbratura *models.Timbrature, revision *models.TimbraturaRevision) error {
+	query := `INSERT INTO timbrature (user_id, timestamp, action_type, location, geolocation,
+			latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
 	
-	err := config.DB.QueryRow(query, timbratura.UserID, timbratura.Timestamp, timbratura.ActionType, timbratura.Location, timbratura.Geolocation).Scan(&timbratura.ID)
+	err := tx.QueryRow(query, timbratura.UserID, timbratura.Timestamp, timbratura.ActionType, timbratura.Location, timbratura.Geolocation,
+		timbratura.Latitude, timbratura.Longitude, timbratura.Accuracy, timbratura.OfficeSiteID, timbratura.GeofenceStatus, timbratura.GeofenceFlagged).Scan(&timbratura.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	revision.TimbraturaID = timbratura.ID
+	revision.UserID = timbratura.UserID
+	revision.RevisionType = models.RevisionCreated
+	revision.Timestamp = timbratura.Timestamp
+	revision.ActionType = timbratura.ActionType
+	revision.Location = timbratura.Location
+	return insertTimbraturaRevision(tx, revision)
 }
 
-// GetAll recupera tutte le timbrature con eventuali filtri di limite e offset
-func (r *TimbratureRepository) GetAll(limit, offset int) ([]models.Timbrature, error) {
+// GetAll recupera le timbrature degli utenti nello scope con eventuali filtri di limite e offset
+func (r *TimbratureRepository) GetAll(limit, offset int, scope *models.TeamScope) ([]models.Timbrature, error) {
 	// Query con ordinamento per data decrescente e paginazione tramite LIMIT e OFFSET
-	query := `SELECT id, user_id, timestamp, action_type, location, geolocation 
+	condition, args := scopeCondition("user_id", scope, []interface{}{limit, offset})
+	query := fmt.Sprintf(`SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged 
 			  FROM timbrature 
+			  WHERE voided_at IS NULL AND %s
 			  ORDER BY timestamp DESC 
-			  LIMIT $1 OFFSET $2`
+			  LIMIT $1 OFFSET $2`, condition)
 
-	// Esegue la query, passando i parametri limit e offset
-	rows, err := config.DB.Query(query, limit, offset)
+	// Esegue la query, passando i parametri limit, offset ed eventualmente gli utenti visibili
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		// Se c’è un errore nell’esecuzione della query lo ritorna
 		return nil, err
@@ -51,7 +79,7 @@ func (r *TimbratureRepository) GetAll(limit, offset int) ([]models.Timbrature, e
 		var t models.Timbrature
 
 		// Popola la struct Timbrature con i valori delle colonne della riga corrente
-		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation)
+		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
 		if err != nil {
 			// Se c’è un errore nello scan ritorna l’errore
 			return nil, err
@@ -74,9 +102,9 @@ func (r *TimbratureRepository) GetAll(limit, offset int) ([]models.Timbrature, e
 func (r *TimbratureRepository) GetByUserID(userID, limit, offset int) ([]models.Timbrature, error) {
 	// Query con filtro per userID, ordinamento per data decrescente e paginazione tramite LIMIT e OFFSET
 	query := `
-		SELECT id, user_id, timestamp, action_type, location, geolocation 
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged 
 		FROM timbrature 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND voided_at IS NULL
 		ORDER BY timestamp DESC 
 		LIMIT $2 OFFSET $3`
 
@@ -96,7 +124,7 @@ func (r *TimbratureRepository) GetByUserID(userID, limit, offset int) ([]models.
 		var t models.Timbrature
 
 		// Popola la struct Timbrature con i valori delle colonne della riga corrente
-		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation)
+		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
 		if err != nil {
 			// Se c’è un errore nello scan ritorna l’errore
 			return nil, err
@@ -119,9 +147,9 @@ func (r *TimbratureRepository) GetByUserID(userID, limit, offset int) ([]models.
 func (r *TimbratureRepository) GetByUserIDAndDate(userID int, date time.Time) ([]models.Timbrature, error) {
 	// Query che filtra per user_id e per data (solo la parte "giorno" del timestamp)
 	query := `
-		SELECT id, user_id, timestamp, action_type, location, geolocation 
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged 
 		FROM timbrature 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND voided_at IS NULL
 		AND DATE(timestamp) = DATE($2)
 		ORDER BY timestamp ASC`
 	
@@ -141,7 +169,7 @@ func (r *TimbratureRepository) GetByUserIDAndDate(userID int, date time.Time) ([
 		var t models.Timbrature
 		
 		// Legge i valori della riga e li mappa nella struct
-		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation)
+		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
 		if err != nil {
 			return nil, err
 		}
@@ -159,13 +187,64 @@ func (r *TimbratureRepository) GetByUserIDAndDate(userID int, date time.Time) ([
 	return timbrature, nil
 }
 
+// GetByUserIDBetween recupera le timbrature di un utente con timestamp in [from, to), in ordine cronologico
+func (r *TimbratureRepository) GetByUserIDBetween(userID int, from, to time.Time) ([]models.Timbrature, error) {
+	query := `
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged
+		FROM timbrature
+		WHERE user_id = $1 AND voided_at IS NULL
+		AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp ASC, id ASC`
+
+	rows, err := config.DB.Query(query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timbrature []models.Timbrature
+	for rows.Next() {
+		var t models.Timbrature
+		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
+		if err != nil {
+			return nil, err
+		}
+		timbrature = append(timbrature, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return timbrature, nil
+}
+
+// GetByID recupera una timbratura non annullata
+func (r *TimbratureRepository) GetByID(id int) (*models.Timbrature, error) {
+	query := `
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged
+		FROM timbrature
+		WHERE id = $1 AND voided_at IS NULL`
+
+	var t models.Timbrature
+	err := config.DB.QueryRow(query, id).Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
 // GetLastTimbratureByUserID recupera l'ultima timbratura registrata da un utente
 func (r *TimbratureRepository) GetLastTimbratureByUserID(userID int) (*models.Timbrature, error) {
 	// Query che prende l'ultima timbratura per user_id ordinando in ordine decrescente e limitando a 1
 	query := `
-		SELECT id, user_id, timestamp, action_type, location, geolocation 
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged 
 		FROM timbrature 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND voided_at IS NULL
 		ORDER BY timestamp DESC 
 		LIMIT 1`
 	
@@ -174,7 +253,7 @@ func (r *TimbratureRepository) GetLastTimbratureByUserID(userID int) (*models.Ti
 	
 	// Usa QueryRow perché ci aspettiamo un solo record
 	err := config.DB.QueryRow(query, userID).Scan(
-		&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation)
+		&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
 	
 	if err != nil {
 		// Caso in cui non ci sono righe (utente senza timbrature)
@@ -189,33 +268,103 @@ func (r *TimbratureRepository) GetLastTimbratureByUserID(userID int) (*models.Ti
 	return &t, nil
 }
 
-// Delete elimina una timbratura dal database (usata solo per correzioni)
-func (r *TimbratureRepository) Delete(id int) error {
-	// Query SQL per cancellare una timbratura dato l'id
-	query := `DELETE FROM timbrature WHERE id = $1`
-	
-	// Esegue la query di DELETE
-	result, err := config.DB.Exec(query, id)
+// Void annulla una timbratura (eliminazione amministrativa): la riga resta con voided_at, autore e motivo
+// e l'annullamento viene registrato nello storico. Ritorna nil se non esiste o é giá annullata
+func (r *TimbratureRepository) Void(id, voidedBy int, reason string) (*models.Timbrature, error) {
+	tx, err := config.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
-	// Controlla quante righe sono state effettivamente eliminate
-	rowsAffected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	query := `
+		UPDATE timbrature
+		SET voided_at = NOW(), voided_by = $2, void_reason = $3
+		WHERE id = $1 AND voided_at IS NULL
+		RETURNING id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged`
+
+	var t models.Timbrature
+	err = tx.QueryRow(query, id, voidedBy, reason).Scan(
+		&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location, &t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
 	if err != nil {
-		return err
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
 	}
-	
-	// Se nessuna riga è stata cancellata, ritorna errore "nessuna riga trovata"
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+
+	revision := &models.TimbraturaRevision{
+		TimbraturaID: t.ID,
+		UserID:       t.UserID,
+		RevisionType: models.RevisionVoided,
+		Source:       models.RevisionSourceAdmin,
+		Timestamp:    t.Timestamp,
+		ActionType:   t.ActionType,
+		Location:     t.Location,
+		ChangedBy:    &voidedBy,
+		Reason:       &reason,
 	}
-	
-	// Nessun errore → operazione riuscita
-	return nil
+	if err := insertTimbraturaRevision(tx, revision); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetGeofenceFlagged recupera le timbrature fuori sede non ancora verificate degli utenti nello scope, piú vecchie prima
+func (r *TimbratureRepository) GetGeofenceFlagged(limit, offset int, scope *models.TeamScope) ([]models.Timbrature, error) {
+	condition, args := scopeCondition("user_id", scope, []interface{}{limit, offset})
+	query := fmt.Sprintf(`
+		SELECT id, user_id, timestamp, action_type, location, geolocation, latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged
+		FROM timbrature
+		WHERE geofence_flagged AND voided_at IS NULL AND %s
+		ORDER BY timestamp ASC, id ASC
+		LIMIT $1 OFFSET $2`, condition)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timbrature := []models.Timbrature{}
+	for rows.Next() {
+		var t models.Timbrature
+		err := rows.Scan(&t.ID, &t.UserID, &t.Timestamp, &t.ActionType, &t.Location,
+			&t.Geolocation, &t.Latitude, &t.Longitude, &t.Accuracy, &t.OfficeSiteID, &t.GeofenceStatus, &t.GeofenceFlagged)
+		if err != nil {
+			return nil, err
+		}
+		timbrature = append(timbrature, t)
+	}
+
+	return timbrature, rows.Err()
+}
+
+// MarkGeofenceReviewed toglie la segnalazione a una timbratura fuori sede verificata dal responsabile.
+// Ritorna false se non é (piú) segnalata
+func (r *TimbratureRepository) MarkGeofenceReviewed(id, reviewedBy int) (bool, error) {
+	query := `
+		UPDATE timbrature
+		SET geofence_flagged = FALSE, geofence_reviewed_by = $2, geofence_reviewed_at = NOW()
+		WHERE id = $1 AND geofence_flagged AND voided_at IS NULL`
+
+	result, err := config.DB.Exec(query, id, reviewedBy)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
 }
 
-// CountByUserID conta il numero totale di timbrature associate a un utente
+// CountByUserID conta il numero totale di timbrature associate a un utente,
+// incluse quelle annullate: anche queste restano conservate nel database
 func (r *TimbratureRepository) CountByUserID(userID int) (int, error) {
 	// Query SQL che conta tutte le righe per un determinato user_id
 	query := `SELECT COUNT(*) FROM timbrature WHERE user_id = $1`
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TimbratureRevisionRepository struct{}
+
+// NewTimbratureRevisionRepository crea la nuova istanza della repo
+func NewTimbratureRevisionRepository() *TimbratureRevisionRepository {
+	return &TimbratureRevisionRepository{}
+}
+
+// GetHistoryByUserIDBetween recupera tutte le timbrature dell'utente in [from, to), comprese
+// quelle annullate, in ordine cronologico
+func (r *TimbratureRevisionRepository) GetHistoryByUserIDBetween(userID int, from, to time.Time) ([]models.TimbraturaHistoryEntry, error) {
+	query := `
+		SELECT id, user_id, timestamp, action_type, location, geolocation,
+			latitude, longitude, accuracy_meters, office_site_id, geofence_status, geofence_flagged, voided_at, voided_by, void_reason, voided_by_correction_id, correction_id
+		FROM timbrature
+		WHERE user_id = $1
+		AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp ASC, id ASC`
+
+	rows, err := config.DB.Query(query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.TimbraturaHistoryEntry{}
+	for rows.Next() {
+		var entry models.TimbraturaHistoryEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Timestamp,
+			&entry.ActionType,
+			&entry.Location,
+			&entry.Geolocation,
+			&entry.Latitude,
+			&entry.Longitude,
+			&entry.Accuracy,
+			&entry.OfficeSiteID,
+			&entry.GeofenceStatus,
+			&entry.GeofenceFlagged,
+			&entry.VoidedAt,
+			&entry.VoidedBy,
+			&entry.VoidReason,
+			&entry.VoidedByCorrectionID,
+			&entry.CorrectionID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetByTimbraturaIDs recupera le revisioni delle timbrature indicate in ordine cronologico
+func (r *TimbratureRevisionRepository) GetByTimbraturaIDs(timbraturaIDs []int) ([]models.TimbraturaRevision, error) {
+	ids := make([]int64, len(timbraturaIDs))
+	for i, id := range timbraturaIDs {
+		ids[i] = int64(id)
+	}
+
+	query := `
+		SELECT id, timbratura_id, user_id, revision_type, source, timestamp, action_type, location,
+			changed_by, api_key_id, correction_id, reason, created_at
+		FROM timbrature_revisions
+		WHERE timbratura_id = ANY($1)
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := config.DB.Query(query, pq.Int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.TimbraturaRevision{}
+	for rows.Next() {
+		var revision models.TimbraturaRevision
+		err := rows.Scan(
+			&revision.ID,
+			&revision.TimbraturaID,
+			&revision.UserID,
+			&revision.RevisionType,
+			&revision.Source,
+			&revision.Timestamp,
+			&revision.ActionType,
+			&revision.Location,
+			&revision.ChangedBy,
+			&revision.APIKeyID,
+			&revision.CorrectionID,
+			&revision.Reason,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// insertTimbraturaRevision aggiunge una revisione allo storico, nella stessa transazione della modifica
+func insertTimbraturaRevision(tx *sql.Tx, revision *models.TimbraturaRevision) error {
+	query := `
+		INSERT INTO timbrature_revisions (timbratura_id, user_id, revision_type, source, timestamp, action_type, location,
+			changed_by, api_key_id, correction_id, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(query,
+		revision.TimbraturaID,
+		revision.UserID,
+		revision.RevisionType,
+		revision.Source,
+		revision.Timestamp,
+		revision.ActionType,
+		revision.Location,
+		revision.ChangedBy,
+		revision.APIKeyID,
+		revision.CorrectionID,
+		revision.Reason,
+	).Scan(&revision.ID, &revision.CreatedAt)
+}
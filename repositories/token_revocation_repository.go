@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"time"
+)
+
+type TokenRevocationRepository struct{}
+
+// NewTokenRevocationRepository crea una nuova istanza del repository
+func NewTokenRevocationRepository() *TokenRevocationRepository {
+	return &TokenRevocationRepository{}
+}
+
+// RevokeToken inserisce il jti nella denylist fino alla scadenza naturale del token
+func (r *TokenRevocationRepository) RevokeToken(jti string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`
+
+	_, err := config.DB.Exec(query, jti, userID, expiresAt)
+	return err
+}
+
+// RevokeAllUserTokens invalida tutti i token dell'utente emessi prima di revokedBefore e
+// incrementa la versione di sicurezza, che copre anche i token emessi nello stesso secondo
+func (r *TokenRevocationRepository) RevokeAllUserTokens(userID int, revokedBefore time.Time) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_token_revocations (user_id, revoked_before)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before`
+
+	if _, err := tx.Exec(query, userID, revokedBefore); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET security_version = security_version + 1 WHERE id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRevocationStatus indica se il jti é revocato e da quando sono revocati tutti i token dell'utente
+func (r *TokenRevocationRepository) GetRevocationStatus(jti string, userID int) (bool, *time.Time, error) {
+	query := `
+		SELECT
+			EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1),
+			(SELECT revoked_before FROM user_token_revocations WHERE user_id = $2)`
+
+	var revoked bool
+	var revokedBefore sql.NullTime
+
+	err := config.DB.QueryRow(query, jti, userID).Scan(&revoked, &revokedBefore)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if revokedBefore.Valid {
+		return revoked, &revokedBefore.Time, nil
+	}
+
+	return revoked, nil, nil
+}
+
+// DeleteExpired rimuove dalla denylist i token ormai scaduti
+func (r *TokenRevocationRepository) DeleteExpired() (int64, error) {
+	query := `DELETE FROM revoked_tokens WHERE expires_at < NOW()`
+
+	result, err := config.DB.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
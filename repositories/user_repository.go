@@ -0,0 +1,380 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type UserRepository struct{}
+
+// NewUserRepository crea una nuova istanza del repository
+func NewUserRepository() *UserRepository {
+	return &UserRepository{}
+}
+
+const userDetailsQuery = `
+	SELECT u.id, u.name, u.email, u.role_id, u.manager_id, u.status, u.termination_date,
+		ur.name, ur.hierarchy_level, m.name, u.work_contract_id, wc.name
+	FROM users u
+	LEFT JOIN user_roles ur ON u.role_id = ur.id
+	LEFT JOIN users m ON u.manager_id = m.id
+	LEFT JOIN work_contracts wc ON u.work_contract_id = wc.id`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// GetAll recupera gli utenti filtrati e paginati, con il totale dei risultati
+func (r *UserRepository) GetAll(filter *models.UserFilter) ([]models.UserDetails, int, error) {
+	var conditions []string
+	var args []any
+
+	if filter.Search != "" {
+		// I caratteri jolly scritti dall'utente vengono cercati letteralmente
+		search := likeEscaper.Replace(strings.ToLower(filter.Search))
+		args = append(args, "%"+search+"%")
+		conditions = append(conditions, fmt.Sprintf("(LOWER(u.name) LIKE $%d OR LOWER(u.email) LIKE $%d)", len(args), len(args)))
+	}
+	if filter.RoleID != nil {
+		args = append(args, *filter.RoleID)
+		conditions = append(conditions, fmt.Sprintf("u.role_id = $%d", len(args)))
+	}
+	if filter.ManagerID != nil {
+		args = append(args, *filter.ManagerID)
+		conditions = append(conditions, fmt.Sprintf("u.manager_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("u.status = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := config.DB.QueryRow(`SELECT COUNT(*) FROM users u`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := userDetailsQuery + where + fmt.Sprintf(" ORDER BY u.name, u.id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []models.UserDetails
+	for rows.Next() {
+		user, err := scanUserDetails(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetByID recupera un utente con ruolo e manager
+func (r *UserRepository) GetByID(id int) (*models.UserDetails, error) {
+	return scanUserDetails(config.DB.QueryRow(userDetailsQuery+` WHERE u.id = $1`, id))
+}
+
+// EmailExistsForOtherUser verifica se l'email é giá usata da un altro utente
+func (r *UserRepository) EmailExistsForOtherUser(email string, userID int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND id <> $2)`
+
+	var exists bool
+	err := config.DB.QueryRow(query, email, userID).Scan(&exists)
+	return exists, err
+}
+
+// Update aggiorna nome ed email
+func (r *UserRepository) Update(id int, name, email string) error {
+	query := `UPDATE users SET name = $1, email = $2 WHERE id = $3`
+
+	_, err := config.DB.Exec(query, name, email, id)
+	return err
+}
+
+// UpdateRole assegna il ruolo e incrementa la versione di sicurezza
+func (r *UserRepository) UpdateRole(id int, roleID *int) error {
+	query := `UPDATE users SET role_id = $1, security_version = security_version + 1 WHERE id = $2`
+
+	_, err := config.DB.Exec(query, roleID, id)
+	return err
+}
+
+// UpdateManager assegna il manager e incrementa la versione di sicurezza
+func (r *UserRepository) UpdateManager(id int, managerID *int) error {
+	query := `UPDATE users SET manager_id = $1, security_version = security_version + 1 WHERE id = $2`
+
+	_, err := config.DB.Exec(query, managerID, id)
+	return err
+}
+
+// UpdateWorkContract assegna il contratto di lavoro (nil = contratto predefinito)
+func (r *UserRepository) UpdateWorkContract(id int, workContractID *int) error {
+	query := `UPDATE users SET work_contract_id = $1 WHERE id = $2`
+
+	_, err := config.DB.Exec(query, workContractID, id)
+	return err
+}
+
+// UpdateStatus cambia lo stato dell'utente e incrementa la versione di sicurezza
+func (r *UserRepository) UpdateStatus(id int, status models.UserStatus) error {
+	query := `UPDATE users SET status = $1, security_version = security_version + 1 WHERE id = $2`
+
+	_, err := config.DB.Exec(query, status, id)
+	return err
+}
+
+// GetSecurityVersion restituisce la versione di sicurezza corrente (0 se l'utente non esiste)
+func (r *UserRepository) GetSecurityVersion(id int) (int, error) {
+	query := `SELECT security_version FROM users WHERE id = $1`
+
+	var version int
+	err := config.DB.QueryRow(query, id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// BumpSecurityVersionByRole incrementa la versione di sicurezza di tutti gli utenti del ruolo
+func (r *UserRepository) BumpSecurityVersionByRole(roleID int) (int64, error) {
+	query := `UPDATE users SET security_version = security_version + 1 WHERE role_id = $1`
+
+	result, err := config.DB.Exec(query, roleID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// IsInManagerChain verifica se userID compare risalendo la catena dei manager a partire da managerID
+// (compreso managerID stesso). Se sí, assegnare managerID come manager di userID creerebbe un ciclo
+func (r *UserRepository) IsInManagerChain(managerID, userID int) (bool, error) {
+	query := `
+		WITH RECURSIVE chain AS (
+			SELECT id, manager_id, 1 AS depth FROM users WHERE id = $1
+			UNION ALL
+			SELECT u.id, u.manager_id, c.depth + 1
+			FROM users u
+			JOIN chain c ON u.id = c.manager_id
+			WHERE c.depth < 100
+		)
+		SELECT EXISTS(SELECT 1 FROM chain WHERE id = $2)`
+
+	var exists bool
+	err := config.DB.QueryRow(query, managerID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetSubordinateIDs restituisce gli ID dei sottoposti diretti e indiretti del manager (escluso lui stesso)
+func (r *UserRepository) GetSubordinateIDs(managerID int) ([]int, error) {
+	query := `
+		WITH RECURSIVE team AS (
+			SELECT id, 1 AS depth FROM users WHERE manager_id = $1
+			UNION
+			SELECT u.id, t.depth + 1
+			FROM users u
+			JOIN team t ON u.manager_id = t.id
+			WHERE t.depth < 100
+		)
+		SELECT DISTINCT id FROM team WHERE id <> $1`
+
+	rows, err := config.DB.Query(query, managerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// scopeCondition restituisce la condizione SQL che limita column agli utenti dello scope.
+// Per la visibilitá aziendale la condizione é sempre vera, senza scope sempre falsa; in entrambi i casi non aggiunge argomenti
+func scopeCondition(column string, scope *models.TeamScope, args []interface{}) (string, []interface{}) {
+	if scope == nil {
+		return "FALSE", args
+	}
+	if scope.CompanyWide {
+		return "TRUE", args
+	}
+
+	ids := make([]int64, len(scope.UserIDs))
+	for i, id := range scope.UserIDs {
+		ids[i] = int64(id)
+	}
+	args = append(args, pq.Int64Array(ids))
+	return fmt.Sprintf("%s = ANY($%d)", column, len(args)), args
+}
+
+// GetActiveUsers recupera gli utenti attivi ordinati per nome
+func (r *UserRepository) GetActiveUsers() ([]models.User, error) {
+	query := `
+		SELECT id, name, email, role_id, manager_id, status
+		FROM users
+		WHERE status = $1
+		ORDER BY name`
+
+	rows, err := config.DB.Query(query, models.UserActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.RoleID, &user.ManagerID, &user.Status)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// TerminationData operazioni di offboarding da eseguire insieme alla cessazione
+type TerminationData struct {
+	UserID              int
+	TerminationDate     time.Time
+	PerformedBy         int
+	ClosingTimbrature   []models.Timbrature // PAUSA_FINE (se in pausa) e USCITA da inserire se l'utente risulta in servizio
+	CancelRequestIDs    []int               // Richieste in attesa o approvate dopo la cessazione da annullare
+	CancellationComment string
+	Settlement          *models.LeaveSettlement
+}
+
+// Terminate esegue la cessazione in un'unica transazione: stato utente, chiusura della timbratura
+// aperta, annullamento delle richieste successive alla cessazione e liquidazione del saldo.
+// Ritorna false se l'utente era giá cessato
+func (r *UserRepository) Terminate(data *TerminationData) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 1. Stato utente
+	result, err := tx.Exec(
+		`UPDATE users SET status = $2, termination_date = $3, security_version = security_version + 1 WHERE id = $1 AND status <> $2`,
+		data.UserID, models.UserTerminated, data.TerminationDate,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	// 2. Chiusura del turno rimasto aperto, registrata nello storico come operazione amministrativa
+	closingReason := "Turno chiuso per cessazione del rapporto di lavoro"
+	for i := range data.ClosingTimbrature {
+		revision := &models.TimbraturaRevision{
+			Source:    models.RevisionSourceAdmin,
+			ChangedBy: &data.PerformedBy,
+			Reason:    &closingReason,
+		}
+		if err := insertTimbratura(tx, &data.ClosingTimbrature[i], revision); err != nil {
+			return false, err
+		}
+	}
+
+	// 3. Richieste revocate d'ufficio: quelle in attesa ricevono un'approvazione REVOKED (solo se nel
+	// frattempo nessuno le ha gestite), quelle approvate vedono revocata l'approvazione
+	for _, requestID := range data.CancelRequestIDs {
+		_, err = tx.Exec(`
+			INSERT INTO approvals (request_id, approver_id, status, comments)
+			SELECT $1, $2, $3, $4
+			WHERE NOT EXISTS (SELECT 1 FROM approvals WHERE request_id = $1)`,
+			requestID, data.PerformedBy, models.ApprovalRevoked, data.CancellationComment,
+		)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = tx.Exec(
+			`UPDATE approvals SET status = $2, comments = $3, approved_at = CURRENT_TIMESTAMP WHERE request_id = $1 AND status = $4`,
+			requestID, models.ApprovalRevoked, data.CancellationComment, models.ApprovalAccepted,
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// 4. Liquidazione del saldo
+	if settlement := data.Settlement; settlement != nil {
+		err = tx.QueryRow(`
+			INSERT INTO leave_settlements (user_id, termination_date, residual_holidays, residual_permits, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at`,
+			settlement.UserID, settlement.TerminationDate, settlement.ResidualHolidays, settlement.ResidualPermits, settlement.CreatedBy,
+		).Scan(&settlement.ID, &settlement.CreatedAt)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// scanUserDetails legge un utente con ruolo e manager da una riga
+func scanUserDetails(row rowScanner) (*models.UserDetails, error) {
+	var user models.UserDetails
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.RoleID,
+		&user.ManagerID,
+		&user.Status,
+		&user.TerminationDate,
+		&user.RoleName,
+		&user.HierarchyLevel,
+		&user.ManagerName,
+		&user.WorkContractID,
+		&user.WorkContractName,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Utente non trovato
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -20,12 +20,12 @@ func NewUserRoleRepository() *UserRoleRepository {
 // Create fá l'INSERT nella tabella user_roles
 func (r *UserRoleRepository) Create(userRole *models.UserRole) (*models.UserRole, error) {
 	query := `
-	INSERT INTO user_roles (name, hierarchy_level)
-	VALUES ($1, $2)
+	INSERT INTO user_roles (name, hierarchy_level, mfa_required)
+	VALUES ($1, $2, $3)
 	RETURNING id`
 
 	// err Esegue la row e fa lo scan di quello che é stato creato dalla query
-	err := config.DB.QueryRow(query, userRole.Name, userRole.HierarchyLevel).Scan(&userRole.ID)
+	err := config.DB.QueryRow(query, userRole.Name, userRole.HierarchyLevel, userRole.MFARequired).Scan(&userRole.ID)
 	// Controllo errori
 	if (err != nil) {
 		return nil, fmt.Errorf("errore nella creazione di user_role: %w", err)
@@ -37,7 +37,7 @@ func (r *UserRoleRepository) Create(userRole *models.UserRole) (*models.UserRole
 
 // GetAll recupera tutti i record da user_roles
 func (r *UserRoleRepository) GetAll() ([]models.UserRole, error) {
-	query := `SELECT id, name, hierarchy_level FROM user_roles ORDER BY hierarchy_level`
+	query := `SELECT id, name, hierarchy_level, mfa_required FROM user_roles ORDER BY hierarchy_level`
 
 	// Esegue la query per prendere tutti i record
 	rows, err := config.DB.Query(query)
@@ -55,7 +55,7 @@ func (r *UserRoleRepository) GetAll() ([]models.UserRole, error) {
 		var userRole models.UserRole
 
 		// Scan di ogni riga nei campi dello struct
-		err := rows.Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel)
+		err := rows.Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel, &userRole.MFARequired)
 		if err != nil {
 			return nil, err
 		}
@@ -75,12 +75,12 @@ func (r *UserRoleRepository) GetAll() ([]models.UserRole, error) {
 // GetById recupera un user_role per ID
 func (r *UserRoleRepository) GetByID(id int) (*models.UserRole, error) {
 	// Query per prendere la row da l'id
-	query := `SELECT id, name, hierarchy_level FROM user_roles WHERE id = $1`
+	query := `SELECT id, name, hierarchy_level, mfa_required FROM user_roles WHERE id = $1`
 
 	var userRole models.UserRole
 
 	// Inizializzazione di err -> esegue la query e fa lo scan del risultato puntando a userRole
-	err := config.DB.QueryRow(query, id).Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel)
+	err := config.DB.QueryRow(query, id).Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel, &userRole.MFARequired)
 	// Controllo errori
 	if err != nil {
 		// Caso nessun record trovato, torna nil
@@ -96,12 +96,12 @@ func (r *UserRoleRepository) GetByID(id int) (*models.UserRole, error) {
 
 func (r *UserRoleRepository) GetByHierarchyLevel(level int) (*models.UserRole, error) {
 	// Query per prendere la row dal livello di gerarchia
-	query := `SELECT id, name, hierarchy_level FROM user_roles WHERE hierarchy_level = $1`
+	query := `SELECT id, name, hierarchy_level, mfa_required FROM user_roles WHERE hierarchy_level = $1`
 
 	var userRole models.UserRole
 
 	// Inizializzazione di err -> esegue la query e fa lo scan del risultato puntando a userRole
-	err := config.DB.QueryRow(query, level).Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel)
+	err := config.DB.QueryRow(query, level).Scan(&userRole.ID, &userRole.Name, &userRole.HierarchyLevel, &userRole.MFARequired)
 	// Controllo errori
 	if err != nil {
 		// Caso nessun record trovato, torna nil
@@ -118,10 +118,10 @@ func (r *UserRoleRepository) GetByHierarchyLevel(level int) (*models.UserRole, e
 // Update user_roles esistente
 func (r *UserRoleRepository) Update(userRole *models.UserRole) (bool, error) {
 	// Query per fare l'update
-	query := `UPDATE user_roles SET name = $1, hierarchy_level = $2 WHERE id = $3`
+	query := `UPDATE user_roles SET name = $1, hierarchy_level = $2, mfa_required = $3 WHERE id = $4`
 
 	// Execution della query
-	res, err := config.DB.Exec(query, userRole.Name, userRole.HierarchyLevel, userRole.ID)
+	res, err := config.DB.Exec(query, userRole.Name, userRole.HierarchyLevel, userRole.MFARequired, userRole.ID)
 	// Controllo errori
 	if err != nil {
 		return false, err
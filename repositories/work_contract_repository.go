@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"database/sql"
+	"merendels-backend/config"
+	"merendels-backend/models"
+)
+
+type WorkContractRepository struct{}
+
+// NewWorkContractRepository crea la nuova istanza della repo
+func NewWorkContractRepository() *WorkContractRepository {
+	return &WorkContractRepository{}
+}
+
+const workContractColumns = `id, name, break_after_minutes, min_break_minutes, break_policy, is_default, created_at, updated_at`
+
+// GetAll recupera tutti i contratti ordinati per nome
+func (r *WorkContractRepository) GetAll() ([]models.WorkContract, error) {
+	rows, err := config.DB.Query(`SELECT ` + workContractColumns + ` FROM work_contracts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contracts := []models.WorkContract{}
+	for rows.Next() {
+		contract, err := scanWorkContract(rows)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, *contract)
+	}
+
+	return contracts, rows.Err()
+}
+
+// GetByID recupera un contratto, nil se non trovato
+func (r *WorkContractRepository) GetByID(id int) (*models.WorkContract, error) {
+	return scanWorkContract(config.DB.QueryRow(`SELECT `+workContractColumns+` FROM work_contracts WHERE id = $1`, id))
+}
+
+// GetForUser recupera il contratto dell'utente, altrimenti quello predefinito (nil se non esiste)
+func (r *WorkContractRepository) GetForUser(userID int) (*models.WorkContract, error) {
+	query := `
+		SELECT ` + workContractColumns + `
+		FROM work_contracts
+		WHERE id = COALESCE(
+			(SELECT work_contract_id FROM users WHERE id = $1),
+			(SELECT id FROM work_contracts WHERE is_default)
+		)`
+
+	return scanWorkContract(config.DB.QueryRow(query, userID))
+}
+
+// Save crea (ID 0) o aggiorna un contratto. Se é il nuovo predefinito, il precedente smette di esserlo
+func (r *WorkContractRepository) Save(contract *models.WorkContract) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if contract.IsDefault {
+		if _, err := tx.Exec(`UPDATE work_contracts SET is_default = FALSE, updated_at = NOW() WHERE is_default AND id <> $1`, contract.ID); err != nil {
+			return err
+		}
+	}
+
+	if contract.ID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO work_contracts (name, break_after_minutes, min_break_minutes, break_policy, is_default)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, updated_at`,
+			contract.Name, contract.BreakAfterMinutes, contract.MinBreakMinutes, contract.BreakPolicy, contract.IsDefault,
+		).Scan(&contract.ID, &contract.CreatedAt, &contract.UpdatedAt)
+	} else {
+		err = tx.QueryRow(`
+			UPDATE work_contracts
+			SET name = $2, break_after_minutes = $3, min_break_minutes = $4, break_policy = $5, is_default = $6, updated_at = NOW()
+			WHERE id = $1
+			RETURNING created_at, updated_at`,
+			contract.ID, contract.Name, contract.BreakAfterMinutes, contract.MinBreakMinutes, contract.BreakPolicy, contract.IsDefault,
+		).Scan(&contract.CreatedAt, &contract.UpdatedAt)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ExistsByName verifica se esiste giá un altro contratto con lo stesso nome
+func (r *WorkContractRepository) ExistsByName(name string, excludeID int) (bool, error) {
+	var exists bool
+	err := config.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM work_contracts WHERE LOWER(name) = LOWER($1) AND id <> $2)`, name, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// scanWorkContract legge una riga di work_contracts, nil se non trovata
+func scanWorkContract(row rowScanner) (*models.WorkContract, error) {
+	var contract models.WorkContract
+	err := row.Scan(
+		&contract.ID,
+		&contract.Name,
+		&contract.BreakAfterMinutes,
+		&contract.MinBreakMinutes,
+		&contract.BreakPolicy,
+		&contract.IsDefault,
+		&contract.CreatedAt,
+		&contract.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &contract, nil
+}
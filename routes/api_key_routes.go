@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAPIKeyRoutes configura le rotte per la gestione delle API key
+func SetupAPIKeyRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewAPIKeyHandler()
+
+	// Gestione API key - Solo con permesso api_keys:manage
+	apiKeys := router.Group("/api-keys")
+	apiKeys.Use(middleware.RateLimit(apiIPRateLimit))
+	apiKeys.Use(middleware.AuthMiddleware())
+	apiKeys.Use(middleware.RateLimit(apiRateLimit))
+	apiKeys.Use(middleware.RequirePermission(models.PermAPIKeysManage))
+	{
+		apiKeys.POST("", handler.CreateAPIKey)       // POST /api/api-keys - Crea una chiave (mostrata una sola volta)
+		apiKeys.GET("", handler.GetAPIKeys)          // GET /api/api-keys - Lista chiavi con ultimo utilizzo
+		apiKeys.DELETE("/:id", handler.RevokeAPIKey) // DELETE /api/api-keys/:id - Revoca una chiave
+	}
+}
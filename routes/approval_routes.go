@@ -3,6 +3,7 @@ package routes
 import (
 	"merendels-backend/handlers"
 	"merendels-backend/middleware"
+	"merendels-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,7 +14,9 @@ func SetupApprovalRoutes(router *gin.RouterGroup) {
 
 	// Rotte per approvals - TUTTE PROTETTE DA JWT
 	approvals := router.Group("/approvals")
+	approvals.Use(middleware.RateLimit(apiIPRateLimit))
 	approvals.Use(middleware.AuthMiddleware()) // Tutti gli endpoint richiedono autenticazione
+	approvals.Use(middleware.RateLimit(apiRateLimit))
 	{
 		// OPERAZIONI DI LETTURA - Accessibili a tutti gli utenti autenticati
 		// Gli utenti possono vedere lo stato delle approvazioni delle proprie richieste
@@ -23,40 +26,40 @@ func SetupApprovalRoutes(router *gin.RouterGroup) {
 
 		approvals.GET("/request/:request_id", handler.GetApprovalsByRequestID) // GET /api/approvals/request/:request_id
 		
-		// OPERAZIONI AMMINISTRATIVE AVANZATE - Solo hierarchy_level <= 1
+		// OPERAZIONI AMMINISTRATIVE AVANZATE - Solo con permesso approvals:read_all
 		approvals.GET("/statistics", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermApprovalsReadAll), 
 			handler.GetApprovalStatistics)                          // GET /api/approvals/statistics - Statistiche approvazioni
 			
 		approvals.GET("/status/:status", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermApprovalsReadAll), 
 			handler.GetApprovalsByStatus)                           // GET /api/approvals/status/:status - Approvazioni per status
 		
 		approvals.GET("", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermApprovalsReadAll), 
 			handler.GetAllApprovals)                                // GET /api/approvals - Tutte le approvazioni (admin/manager)
 		
 		// ROTTE CON PARAMETRI DINAMICI - Alla fine per evitare conflitti
 		approvals.GET("/:id", handler.GetApprovalByID)              // GET /api/approvals/:id - Singola approvazione per ID
 		
 		
-		// OPERAZIONI DI APPROVAZIONE - Solo hierarchy_level <= 1 (Responsabile/Capo)
-		// Solo manager e responsabili possono creare, modificare ed eliminare approvazioni
+		// OPERAZIONI DI APPROVAZIONE - Serve il permesso di approvazione per almeno un tipo di richiesta
+		// Il service verifica poi il permesso specifico per il tipo (ferie/permesso)
 		
 		approvals.POST("", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequireAnyPermission(models.PermApproveHolidays, models.PermApprovePermits), 
 			handler.CreateApproval)                                 // POST /api/approvals - Crea nuova approvazione
 			
 		approvals.PUT("/:id/status", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequireAnyPermission(models.PermApproveHolidays, models.PermApprovePermits), 
 			handler.UpdateApprovalStatus)                           // PUT /api/approvals/:id/status - Aggiorna status approvazione
 			
 		approvals.POST("/:id/revoke", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequireAnyPermission(models.PermApproveHolidays, models.PermApprovePermits), 
 			handler.RevokeApproval)                                 // POST /api/approvals/:id/revoke - Revoca approvazione
 			
 		approvals.DELETE("/:id", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequireAnyPermission(models.PermApproveHolidays, models.PermApprovePermits), 
 			handler.DeleteApproval)                                 // DELETE /api/approvals/:id - Elimina approvazione
 	}
 }
\ No newline at end of file
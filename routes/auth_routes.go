@@ -3,28 +3,71 @@ package routes
 import (
 	"merendels-backend/handlers"
 	"merendels-backend/middleware"
+	"merendels-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupAuthRoutes(router *gin.RouterGroup) {
 	handler := handlers.NewAuthHandler()
+	mfaHandler := handlers.NewMFAHandler()
+	invitationHandler := handlers.NewInvitationHandler()
+	oidcHandler := handlers.NewOIDCHandler()
+	sessionHandler := handlers.NewSessionHandler()
 
 	// Rotte per autenticazione
 	auth := router.Group("/auth") 
 	{
-		// Rotte pubbliche (no middleware)
-		auth.POST("/login", handler.Login) // POST /api/auth/login
-		auth.POST("/register", handler.Register) // Post /api/auth/register
+		// Rotte pubbliche (no middleware JWT), con limite di richieste per IP
+		public := auth.Group("")
+		public.Use(middleware.RateLimit(authRateLimit))
+		public.POST("/login", middleware.RateLimit(loginRateLimit), handler.Login) // POST /api/auth/login
+		public.POST("/register", handler.Register) // Post /api/auth/register - Solo se OPEN_REGISTRATION=true
+		public.POST("/invitations/accept", invitationHandler.AcceptInvitation) // POST /api/auth/invitations/accept - Attiva l'account da invito
+		public.POST("/refresh", handler.RefreshToken) // POST /api/auth/refresh - Rinnova il token di accesso
+		public.POST("/password-reset/request", handler.RequestPasswordReset) // POST /api/auth/password-reset/request - Invia link di reset
+		public.POST("/password-reset/confirm", handler.ConfirmPasswordReset) // POST /api/auth/password-reset/confirm - Imposta nuova password
+		public.POST("/mfa/verify", middleware.RateLimit(loginRateLimit), mfaHandler.Verify) // POST /api/auth/mfa/verify - Secondo passo del login (codice TOTP o di recupero)
+		public.GET("/oidc/login", oidcHandler.BeginLogin) // GET /api/auth/oidc/login - Avvia il login SSO (URL dell'identity provider)
+		public.POST("/oidc/callback", oidcHandler.Callback) // POST /api/auth/oidc/callback - Conclude il login SSO con code e state
 
 		// Rotte protette (middleware JWT)
 		protected := auth.Group("")
+		protected.Use(middleware.RateLimit(apiIPRateLimit))
 		protected.Use(middleware.AuthMiddleware()) // Applica middleware a tutte le rotte sotto
+		protected.Use(middleware.RateLimit(apiRateLimit))
 		{
 			protected.PUT("/change-password", handler.ChangePassword)	// PUT /api/auth/change-passowrd
 			protected.GET("/profile", handler.GetProfile)	// GET /api/auth/profile
-			protected.POST("/logout", handler.Logout)	// POST /api/auth/logout
+			protected.POST("/logout", handler.Logout)	// POST /api/auth/logout - Logout della sessione corrente
+			protected.POST("/logout-all", handler.LogoutAll)	// POST /api/auth/logout-all - Logout da tutte le sessioni
 			protected.POST("/validate", handler.ValidateToken)	// POST /api/auth/validate
+			protected.GET("/sessions", sessionHandler.GetSessions)	// GET /api/auth/sessions - Dispositivi su cui l'utente ha fatto login
+			protected.DELETE("/sessions", sessionHandler.RevokeOtherSessions)	// DELETE /api/auth/sessions - Logout da tutte le altre sessioni
+			protected.DELETE("/sessions/:id", sessionHandler.RevokeSession)	// DELETE /api/auth/sessions/:id - Logout remoto di una sessione
+			protected.GET("/mfa", mfaHandler.GetStatus)	// GET /api/auth/mfa - Stato della 2FA
+			protected.POST("/mfa/setup", mfaHandler.Setup)	// POST /api/auth/mfa/setup - Nuovo segreto TOTP + URI per il QR code
+			protected.POST("/mfa/activate", mfaHandler.Activate)	// POST /api/auth/mfa/activate - Conferma enrollment e restituisce codici di recupero
+			protected.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)	// POST /api/auth/mfa/recovery-codes - Rigenera i codici di recupero
+			protected.POST("/mfa/disable", mfaHandler.Disable)	// POST /api/auth/mfa/disable - Disattiva la 2FA (se non obbligatoria per il ruolo)
+
+			// Sicurezza account - Solo con permesso security:manage
+			protected.GET("/users/:id/login-attempts",
+				middleware.RequirePermission(models.PermSecurityManage),
+				handler.GetLoginAttempts)	// GET /api/auth/users/:id/login-attempts - Tentativi di login (paginati)
+			protected.POST("/users/:id/unlock",
+				middleware.RequirePermission(models.PermSecurityManage),
+				handler.UnlockUser)	// POST /api/auth/users/:id/unlock - Sblocca un account bloccato
 		}
 	}
+}
+
+// SetupWellKnownRoutes configura le rotte pubbliche /.well-known/*
+func SetupWellKnownRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewAuthHandler()
+
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", handler.GetJWKS) // GET /.well-known/jwks.json - Chiavi pubbliche JWT
+	}
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupImpersonationRoutes configura le rotte per l'impersonificazione da parte del supporto
+func SetupImpersonationRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewImpersonationHandler()
+
+	impersonations := router.Group("/impersonations")
+	impersonations.Use(middleware.RateLimit(apiIPRateLimit))
+	impersonations.Use(middleware.AuthMiddleware())
+	impersonations.Use(middleware.RateLimit(apiRateLimit))
+	{
+		// Avvio - Solo con permesso users:impersonate
+		impersonations.POST("",
+			middleware.RequirePermission(models.PermUsersImpersonate),
+			handler.StartImpersonation) // POST /api/impersonations - Token in sola lettura per conto di un utente
+
+		// Audit - Solo con permesso security:manage
+		impersonations.GET("",
+			middleware.RequirePermission(models.PermSecurityManage),
+			handler.GetSessions) // GET /api/impersonations - Sessioni di impersonificazione (paginate)
+		impersonations.GET("/:id",
+			middleware.RequirePermission(models.PermSecurityManage),
+			handler.GetSessionAuditLog) // GET /api/impersonations/:id - Sessione con tutte le chiamate eseguite
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupInvitationRoutes configura le rotte per la gestione degli inviti
+func SetupInvitationRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewInvitationHandler()
+
+	// Gestione inviti - Solo con permesso invitations:manage
+	invitations := router.Group("/invitations")
+	invitations.Use(middleware.RateLimit(apiIPRateLimit))
+	invitations.Use(middleware.AuthMiddleware())
+	invitations.Use(middleware.RateLimit(apiRateLimit))
+	invitations.Use(middleware.RequirePermission(models.PermInvitationsManage))
+	{
+		invitations.POST("", handler.CreateInvitation)       // POST /api/invitations - Crea e invia un invito
+		invitations.GET("", handler.GetInvitations)          // GET /api/invitations?status=pending - Lista inviti
+		invitations.DELETE("/:id", handler.RevokeInvitation) // DELETE /api/invitations/:id - Revoca un invito
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupOfficeSiteRoutes configura le rotte per le sedi aziendali e i loro geofence
+func SetupOfficeSiteRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewOfficeSiteHandler()
+
+	// Sedi aziendali - Solo con permesso office_sites:manage
+	sites := router.Group("/office-sites")
+	sites.Use(middleware.RateLimit(apiIPRateLimit))
+	sites.Use(middleware.AuthMiddleware())
+	sites.Use(middleware.RateLimit(apiRateLimit))
+	sites.Use(middleware.RequirePermission(models.PermOfficeSitesManage))
+	{
+		sites.GET("", handler.GetOfficeSites)       // GET /api/office-sites - Elenco sedi, anche disattivate
+		sites.POST("", handler.CreateOfficeSite)    // POST /api/office-sites - Crea sede
+		sites.PUT("/:id", handler.UpdateOfficeSite) // PUT /api/office-sites/:id - Modifica coordinate, raggio, policy o stato
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPermissionRoutes configura le rotte per il catalogo dei permessi
+func SetupPermissionRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewPermissionHandler()
+
+	// Catalogo permessi - Solo con permesso roles:manage
+	permissions := router.Group("/permissions")
+	permissions.Use(middleware.RateLimit(apiIPRateLimit))
+	permissions.Use(middleware.AuthMiddleware())
+	permissions.Use(middleware.RateLimit(apiRateLimit))
+	permissions.Use(middleware.RequirePermission(models.PermRolesManage))
+	{
+		permissions.GET("", handler.GetAllPermissions) // GET /api/permissions - Permessi assegnabili ai ruoli
+	}
+}
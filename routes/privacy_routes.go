@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPrivacyRoutes configura le rotte per i diritti dell'interessato (GDPR)
+func SetupPrivacyRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewPrivacyHandler()
+
+	// Esportazione e cancellazione - Solo con permesso privacy:manage
+	privacy := router.Group("/privacy")
+	privacy.Use(middleware.RateLimit(apiIPRateLimit))
+	privacy.Use(middleware.AuthMiddleware())
+	privacy.Use(middleware.RateLimit(apiRateLimit))
+	privacy.Use(middleware.RequirePermission(models.PermPrivacyManage))
+	{
+		privacy.GET("/users/:id/export", handler.ExportUserData) // GET /api/privacy/users/:id/export?format=json|zip - Tutti i dati personali dell'utente
+		privacy.POST("/users/:id/erase", handler.EraseUser)      // POST /api/privacy/users/:id/erase - Cancellazione per pseudonimizzazione (solo utenti cessati)
+		privacy.GET("/requests", handler.GetPrivacyRequests)     // GET /api/privacy/requests - Registro delle richieste evase
+	}
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"merendels-backend/middleware"
+	"time"
+)
+
+// Limiti di richieste per gruppo di rotte, sovrascrivibili con RATE_LIMIT_<NOME>=richieste/periodo
+var (
+	// Rotte pubbliche di autenticazione, per IP
+	authRateLimit = middleware.RateLimitPolicy{Name: "auth", Requests: 30, Period: time.Minute, Key: middleware.RateLimitByIP}
+
+	// Login e secondo fattore, per IP (si aggiunge al blocco dell'account dopo i tentativi falliti)
+	loginRateLimit = middleware.RateLimitPolicy{Name: "login", Requests: 10, Period: time.Minute, Key: middleware.RateLimitByIP}
+
+	// Rotte autenticate prima dell'autenticazione, per IP: le richieste con token o API key non validi
+	// arrivano comunque al database (revoca, sessioni, API key) e vanno limitate
+	apiIPRateLimit = middleware.RateLimitPolicy{Name: "api_ip", Requests: 600, Period: time.Minute, Key: middleware.RateLimitByIP}
+
+	// Rotte autenticate, per utente o API key (ogni gruppo ha il proprio budget)
+	apiRateLimit = middleware.RateLimitPolicy{Name: "api", Requests: 300, Period: time.Minute, Key: middleware.RateLimitByUser}
+
+	// Nuova timbratura, per utente
+	timbratureWriteRateLimit = middleware.RateLimitPolicy{Name: "timbrature_write", Requests: 10, Period: time.Minute, Key: middleware.RateLimitByUser}
+
+	// Kiosk badge: una sola API key timbra per tutti i dipendenti della sede
+	kioskRateLimit = middleware.RateLimitPolicy{Name: "kiosk", Requests: 120, Period: time.Minute, Key: middleware.RateLimitByUser}
+)
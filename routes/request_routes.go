@@ -3,6 +3,7 @@ package routes
 import (
 	"merendels-backend/handlers"
 	"merendels-backend/middleware"
+	"merendels-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,7 +14,9 @@ func SetupRequestRoutes(router *gin.RouterGroup) {
 
 	// Rotte per requests - TUTTE PROTETTE DA JWT
 	requests := router.Group("/requests")
+	requests.Use(middleware.RateLimit(apiIPRateLimit))
 	requests.Use(middleware.AuthMiddleware()) // Tutti gli endpoint richiedono autenticazione
+	requests.Use(middleware.RateLimit(apiRateLimit))
 	{
 		// IMPORTANTE: Rotte specifiche PRIMA di quelle con parametri dinamici per evitare conflitti routing
 		
@@ -24,14 +27,14 @@ func SetupRequestRoutes(router *gin.RouterGroup) {
 		// OPERAZIONI DI CONSULTA - Accessibili a tutti gli utenti autenticati
 		requests.GET("/date-range", handler.GetRequestsByDateRange) // GET /api/requests/date-range?start_date=...&end_date=... - Richieste per range date
 		
-		// OPERAZIONI AMMINISTRATIVE - Solo hierarchy_level <= 1 (Responsabile/Capo)
+		// OPERAZIONI AMMINISTRATIVE - Solo chi puó approvare almeno un tipo di richiesta
 		requests.GET("/pending", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequireAnyPermission(models.PermApproveHolidays, models.PermApprovePermits), 
 			handler.GetPendingRequests)                             // GET /api/requests/pending - Richieste in attesa di approvazione
 		
 		// Gestione globale richieste (solo per manager/admin) - DEVE essere prima di /:id per evitare conflitti
 		requests.GET("", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermRequestsReadAll), 
 			handler.GetAllRequests)                                 // GET /api/requests - Tutte le richieste (admin/manager)
 		
 		// ROTTE CON PARAMETRI DINAMICI - Devono essere ALLA FINE per evitare conflitti
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTimbratureCorrectionRoutes configura le rotte per le richieste di correzione delle timbrature
+func SetupTimbratureCorrectionRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewTimbratureCorrectionHandler()
+
+	corrections := router.Group("/timbrature/corrections")
+	corrections.Use(middleware.RateLimit(apiIPRateLimit))
+	corrections.Use(middleware.AuthMiddleware())
+	corrections.Use(middleware.RateLimit(apiRateLimit))
+	{
+		// OPERAZIONI PERSONALI - Il dipendente propone e ritira le proprie correzioni
+		corrections.POST("", handler.CreateCorrection)            // POST /api/timbrature/corrections - Richiedi aggiunta, modifica o rimozione di una timbratura
+		corrections.GET("/me", handler.GetMyCorrections)          // GET /api/timbrature/corrections/me - Le mie correzioni
+		corrections.POST("/:id/cancel", handler.CancelCorrection) // POST /api/timbrature/corrections/:id/cancel - Ritira una correzione in attesa
+
+		// REVISIONE - Responsabili con permesso timbrature:approve_corrections, solo sul proprio team
+		corrections.GET("",
+			middleware.RequirePermission(models.PermTimbratureCorrect),
+			handler.GetCorrections) // GET /api/timbrature/corrections?status=PENDING - Correzioni del team
+		corrections.POST("/:id/approve",
+			middleware.RequirePermission(models.PermTimbratureCorrect),
+			handler.ApproveCorrection) // POST /api/timbrature/corrections/:id/approve - Approva e applica
+		corrections.POST("/:id/reject",
+			middleware.RequirePermission(models.PermTimbratureCorrect),
+			handler.RejectCorrection) // POST /api/timbrature/corrections/:id/reject - Rifiuta
+
+		corrections.GET("/:id", handler.GetCorrection) // GET /api/timbrature/corrections/:id - Dettaglio (propria o del team)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"merendels-backend/handlers"
 	"merendels-backend/middleware"
+	"merendels-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,27 +14,45 @@ func SetupTimbratureRoutes(router *gin.RouterGroup) {
 	
 	// Rotte per timbrature - TUTTE PROTETTE DA JWT
 	timbrature := router.Group("/timbrature")
+	timbrature.Use(middleware.RateLimit(apiIPRateLimit))
 	timbrature.Use(middleware.AuthMiddleware()) // Tutti gli endpoint richiedono autenticazione
+	timbrature.Use(middleware.RateLimit(apiRateLimit))
 	{
 		// OPERAZIONI PERSONALI - Tutti gli utenti autenticati
 		// Endpoint per gestire le proprie timbrature
-		timbrature.POST("", handler.CreateTimbrature) // POST /api/timbrature - Crea timbratura
+		timbrature.POST("", middleware.RateLimit(timbratureWriteRateLimit), handler.CreateTimbrature) // POST /api/timbrature - Crea timbratura
 		timbrature.GET("/me", handler.GetMyTimbrature) // GET /api/timbrature/me - Le mie timbrature
 		timbrature.GET("/me/today", handler.GetMyTodayTimbrature) // GET /api/timbrature/me/today - Timbrature di oggi
 		timbrature.GET("/me/date/:date", handler.GetMyTimbratureByDate) // GET /api/timbrature/me/date/2025-01-15
 		timbrature.GET("/me/status", handler.GetMyWorkingStatus) // GET /api/timbrature/me/status - Stato lavorativo
 		timbrature.GET("/me/last", handler.GetMyLastTimbrature) // GET /api/timbrature/me/last - Ultima timbratura
+		timbrature.GET("/me/summary", handler.GetMyWorkSummary) // GET /api/timbrature/me/summary?from=&to= - Ore lavorate per giorno e totali del periodo
 		
-		// OPERAZIONI AMMINISTRATIVE - Solo hierarchy_level <= 1 (Responsabile/Capo)
+		// KIOSK - Solo API key con scope timbrature:write, timbra per conto del dipendente indicato
+		timbrature.POST("/kiosk", 
+			middleware.RequireScope(models.ScopeTimbratureWrite), 
+			middleware.RateLimit(kioskRateLimit), 
+			handler.CreateKioskTimbratura) // POST /api/timbrature/kiosk
+		
+		// OPERAZIONI AMMINISTRATIVE - In base ai permessi del ruolo
 		timbrature.GET("", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermTimbratureReadAll), 
 			handler.GetAllTimbrature)  // GET /api/timbrature - Tutte le timbrature
 		timbrature.GET("/employees-status", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermTimbratureReadAll), 
 			handler.GetEmployeesStatus) // GET /api/timbrature/employees-status
+		timbrature.GET("/geofence-flagged", 
+			middleware.RequirePermission(models.PermTimbratureCorrect), 
+			handler.GetGeofenceFlagged) // GET /api/timbrature/geofence-flagged - Timbrature fuori sede da verificare (proprio team)
+		timbrature.POST("/:id/geofence-review", 
+			middleware.RequirePermission(models.PermTimbratureCorrect), 
+			handler.ReviewGeofence) // POST /api/timbrature/:id/geofence-review - Conferma timbratura fuori sede
+		timbrature.GET("/users/:id/history", 
+			middleware.RequirePermission(models.PermTimbratureReadAll), 
+			handler.GetUserDayHistory) // GET /api/timbrature/users/:id/history?date=2025-01-15 - Storico completo del giorno, timbrature annullate comprese
 			
 		timbrature.DELETE("/:id", 
-			middleware.RequireHierarchyLevel(1), 
-			handler.DeleteTimbratura)  // DELETE /api/timbrature/:id - Elimina timbratura
+			middleware.RequirePermission(models.PermTimbratureDelete), 
+			handler.DeleteTimbratura)  // DELETE /api/timbrature/:id - Elimina (annulla) timbratura, con motivo
 	}
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package routes
 import (
 	"merendels-backend/handlers"
 	"merendels-backend/middleware"
+	"merendels-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,27 +11,39 @@ import (
 // SetupUserRoleRoutes configura le rotte per user_roles con protezioni JWT
 func SetupUserRoleRoutes(router *gin.RouterGroup) {
 	handler := handlers.NewUserRoleHandler()
+	permissionHandler := handlers.NewPermissionHandler()
 
 	// Rotte per user_roles TUTTE protette da JWT
 	userRoles := router.Group("/user-roles")
+	userRoles.Use(middleware.RateLimit(apiIPRateLimit))
 	userRoles.Use(middleware.AuthMiddleware()) // Tutti gli endpoint richiedono l'autenticazione
+	userRoles.Use(middleware.RateLimit(apiRateLimit))
 	{
 		// OPERAZIONI DI LETTURA - Accessibili a tutti gli utenti autenticati
 		userRoles.GET("", handler.GetAllUserRoles)      // GET /api/user-roles
 		userRoles.GET("/:id", handler.GetUserRoleByID)  // GET /api/user-roles/:id
 		
-		// OPERAZIONI DI SCRITTURA - Solo hierarchy_level <= 1 (Responsabile/Capo)
+		// OPERAZIONI DI SCRITTURA - Solo con permesso roles:manage
 		userRoles.POST("", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermRolesManage), 
 			handler.CreateUserRole)  // POST /api/user-roles
 			
 		userRoles.PUT("/:id", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermRolesManage), 
 			handler.UpdateUserRole)  // PUT /api/user-roles/:id
 			
 		userRoles.DELETE("/:id", 
-			middleware.RequireHierarchyLevel(1), 
+			middleware.RequirePermission(models.PermRolesManage), 
 			handler.DeleteUserRole)  // DELETE /api/user-roles/:id
 
+		// MATRICE RUOLO -> PERMESSI - Solo con permesso roles:manage
+		userRoles.GET("/:id/permissions", 
+			middleware.RequirePermission(models.PermRolesManage), 
+			permissionHandler.GetRolePermissions)  // GET /api/user-roles/:id/permissions
+			
+		userRoles.PUT("/:id/permissions", 
+			middleware.RequirePermission(models.PermRolesManage), 
+			permissionHandler.UpdateRolePermissions)  // PUT /api/user-roles/:id/permissions - Sostituisce i permessi del ruolo
+
 	}
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupUserRoutes configura le rotte per la gestione degli utenti
+func SetupUserRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewUserHandler()
+
+	// Gestione utenti - Solo con permesso users:manage
+	users := router.Group("/users")
+	users.Use(middleware.RateLimit(apiIPRateLimit))
+	users.Use(middleware.AuthMiddleware())
+	users.Use(middleware.RateLimit(apiRateLimit))
+	users.Use(middleware.RequirePermission(models.PermUsersManage))
+	{
+		users.GET("", handler.GetUsers)                                 // GET /api/users - Lista/ricerca utenti
+		users.GET("/:id", handler.GetUserByID)                          // GET /api/users/:id - Dettaglio utente
+		users.PUT("/:id", handler.UpdateUser)                           // PUT /api/users/:id - Modifica nome/email
+		users.PUT("/:id/role", handler.UpdateUserRole)                  // PUT /api/users/:id/role - Assegna ruolo
+		users.PUT("/:id/manager", handler.UpdateUserManager)            // PUT /api/users/:id/manager - Assegna manager
+		users.PUT("/:id/work-contract", handler.UpdateUserWorkContract) // PUT /api/users/:id/work-contract - Assegna contratto di lavoro
+		users.POST("/:id/deactivate", handler.DeactivateUser)           // POST /api/users/:id/deactivate - Disattiva utente
+		users.POST("/:id/reactivate", handler.ReactivateUser)           // POST /api/users/:id/reactivate - Riattiva utente
+		users.POST("/:id/terminate", handler.TerminateUser)             // POST /api/users/:id/terminate - Cessazione rapporto (offboarding)
+		users.GET("/:id/settlement", handler.GetSettlement)             // GET /api/users/:id/settlement - Liquidazione ferie/permessi residui
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"merendels-backend/handlers"
+	"merendels-backend/middleware"
+	"merendels-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWorkContractRoutes configura le rotte per i contratti di lavoro e le regole sulle pause
+func SetupWorkContractRoutes(router *gin.RouterGroup) {
+	handler := handlers.NewWorkContractHandler()
+
+	// Contratti di lavoro - Solo con permesso users:manage
+	contracts := router.Group("/work-contracts")
+	contracts.Use(middleware.RateLimit(apiIPRateLimit))
+	contracts.Use(middleware.AuthMiddleware())
+	contracts.Use(middleware.RateLimit(apiRateLimit))
+	contracts.Use(middleware.RequirePermission(models.PermUsersManage))
+	{
+		contracts.GET("", handler.GetWorkContracts)       // GET /api/work-contracts - Elenco contratti
+		contracts.POST("", handler.CreateWorkContract)    // POST /api/work-contracts - Crea contratto
+		contracts.PUT("/:id", handler.UpdateWorkContract) // PUT /api/work-contracts/:id - Modifica soglie e policy delle pause
+	}
+}
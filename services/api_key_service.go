@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefisso delle API key: le rende riconoscibili nei log e negli strumenti di secret scanning
+const apiKeyPrefix = "mdk_"
+
+type APIKeyService struct {
+	repository        *repositories.APIKeyRepository
+	permissionService *PermissionService
+}
+
+// NewAPIKeyService crea una nuova istanza del servizio
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		repository:        repositories.NewAPIKeyRepository(),
+		permissionService: NewPermissionService(),
+	}
+}
+
+// CreateAPIKey genera una nuova chiave. Il valore in chiaro é restituito solo qui
+func (s *APIKeyService) CreateAPIKey(request *models.CreateAPIKeyRequest, createdBy int, creatorRoleID *int) (*models.CreatedAPIKey, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	scopes, err := normalizeScopes(request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Una chiave non puó concedere permessi che chi la crea non ha
+	var granted []string
+	for _, scope := range scopes {
+		granted = append(granted, models.APIKeyScopePermissions[scope]...)
+	}
+	allowed, err := s.permissionService.HasAllPermissions(creatorRoleID, granted)
+	if err != nil {
+		return nil, fmt.Errorf("error checking creator permissions: %w", err)
+	}
+	if !allowed {
+		return nil, errors.New("cannot grant scopes with permissions you do not have")
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating api key: %w", err)
+	}
+	plainKey := apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		Name:      name,
+		KeyPrefix: plainKey[:len(apiKeyPrefix)+8],
+		KeyHash:   utils.HashToken(plainKey),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+	}
+	if request.ExpiresInDays != nil {
+		if *request.ExpiresInDays <= 0 {
+			return nil, errors.New("expires_in_days must be positive")
+		}
+		expiresAt := time.Now().AddDate(0, 0, *request.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repository.Create(key); err != nil {
+		return nil, fmt.Errorf("error saving api key: %w", err)
+	}
+
+	log.Printf("API key %d (%s) created by user ID %d with scopes %v", key.ID, key.Name, createdBy, key.Scopes)
+	return &models.CreatedAPIKey{APIKey: *key, Key: plainKey}, nil
+}
+
+// GetAPIKeys restituisce tutte le chiavi (senza il valore in chiaro)
+func (s *APIKeyService) GetAPIKeys() ([]models.APIKey, error) {
+	keys, err := s.repository.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revoca una chiave, che smette subito di funzionare
+func (s *APIKeyService) RevokeAPIKey(id int, revokedBy int) error {
+	key, err := s.repository.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error fetching api key: %w", err)
+	}
+	if key == nil {
+		return errors.New("api key not found")
+	}
+
+	revoked, err := s.repository.Revoke(id)
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %w", err)
+	}
+	if !revoked {
+		return errors.New("api key already revoked")
+	}
+
+	log.Printf("API key %d (%s) revoked by user ID %d", id, key.Name, revokedBy)
+	return nil
+}
+
+// Authenticate verifica la chiave ricevuta nell'header X-API-Key, nil se non valida
+func (s *APIKeyService) Authenticate(plainKey string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plainKey, apiKeyPrefix) {
+		return nil, nil
+	}
+
+	key, err := s.repository.GetActiveByHash(utils.HashToken(plainKey))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching api key: %w", err)
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	// L'ultimo utilizzo é informativo, un errore non blocca la richiesta
+	if err := s.repository.TouchLastUsed(key.ID); err != nil {
+		log.Printf("Error updating last use of api key %d: %v", key.ID, err)
+	}
+
+	return key, nil
+}
+
+// normalizeScopes verifica gli scope richiesti e rimuove i duplicati
+func normalizeScopes(requested []string) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if _, known := models.APIKeyScopePermissions[scope]; !known {
+			return nil, errors.New("unknown scope")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	sort.Strings(scopes)
+	return scopes, nil
+}
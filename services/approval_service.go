@@ -12,6 +12,8 @@ type ApprovalService struct {
 	approvalRepository *repositories.ApprovalRepository
 	requestRepository  *repositories.RequestRepository
 	userRepository     *repositories.UserRoleRepository
+	permissionService  *PermissionService
+	teamScopeService   *TeamScopeService
 }
 
 // NewApprovalService crea una nuova istanza del servizio
@@ -20,11 +22,14 @@ func NewApprovalService() *ApprovalService {
 		approvalRepository: repositories.NewApprovalRepository(),
 		requestRepository:  repositories.NewRequestRepository(),
 		userRepository:     repositories.NewUserRoleRepository(),
+		permissionService:  NewPermissionService(),
+		teamScopeService:   NewTeamScopeService(),
 	}
 }
 
 // CreateApproval crea una nuova approvazione con validazioni business
-func (s *ApprovalService) CreateApproval(approverID int, request *models.CreateApprovalRequest) (*models.Approval, error) {
+// approverRoleID é il ruolo dell'approvatore (dai claims), serve per il permesso sul tipo di richiesta
+func (s *ApprovalService) CreateApproval(approverID int, approverRoleID *int, request *models.CreateApprovalRequest) (*models.Approval, error) {
 	// Validazioni base
 	if request.RequestID <= 0 {
 		return nil, errors.New("ID richiesta non valido")
@@ -50,6 +55,20 @@ func (s *ApprovalService) CreateApproval(approverID int, request *models.CreateA
 		return nil, errors.New("non è possibile approvare le proprie richieste")
 	}
 
+	// Verifica il permesso di approvazione per il tipo di richiesta (ferie/permesso)
+	if err := s.checkApprovePermission(approverRoleID, existingRequest.RequestType); err != nil {
+		return nil, err
+	}
+
+	// Un responsabile approva solo le richieste del proprio team
+	scope, err := s.teamScopeService.Resolve(Viewer{UserID: approverID, RoleID: approverRoleID})
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Includes(existingRequest.UserID) {
+		return nil, errors.New("la richiesta non appartiene al tuo team")
+	}
+
 	// Verifica che non esista già un'approvazione per questa richiesta da questo approvatore
 	hasExisting, err := s.approvalRepository.CheckExistingApproval(request.RequestID, approverID)
 	if err != nil {
@@ -86,8 +105,8 @@ func (s *ApprovalService) CreateApproval(approverID int, request *models.CreateA
 	return createdApproval, nil
 }
 
-// GetAllApprovals recupera tutte le approvazioni con paginazione
-func (s *ApprovalService) GetAllApprovals(limit, offset int) ([]models.Approval, error) {
+// GetAllApprovals recupera le approvazioni visibili all'utente (team o tutta l'azienda) con paginazione
+func (s *ApprovalService) GetAllApprovals(viewer Viewer, limit, offset int) ([]models.Approval, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -95,7 +114,12 @@ func (s *ApprovalService) GetAllApprovals(limit, offset int) ([]models.Approval,
 		offset = 0
 	}
 
-	approvals, err := s.approvalRepository.GetAll(limit, offset)
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, err := s.approvalRepository.GetAll(limit, offset, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle approvazioni: %w", err)
 	}
@@ -103,8 +127,8 @@ func (s *ApprovalService) GetAllApprovals(limit, offset int) ([]models.Approval,
 	return approvals, nil
 }
 
-// GetApprovalByID recupera un'approvazione specifica
-func (s *ApprovalService) GetApprovalByID(id int) (*models.Approval, error) {
+// GetApprovalByID recupera un'approvazione specifica, visibile a chi l'ha data e a chi puó vedere la richiesta
+func (s *ApprovalService) GetApprovalByID(id int, viewer Viewer) (*models.Approval, error) {
 	if id <= 0 {
 		return nil, errors.New("ID approvazione non valido")
 	}
@@ -118,15 +142,34 @@ func (s *ApprovalService) GetApprovalByID(id int) (*models.Approval, error) {
 		return nil, errors.New("approvazione non trovata")
 	}
 
+	if viewer.APIKey == nil && approval.ApproverID == viewer.UserID {
+		return approval, nil
+	}
+	visible, err := s.canViewRequest(approval.RequestID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("approvazione non trovata")
+	}
+
 	return approval, nil
 }
 
-// GetApprovalsByRequestID recupera tutte le approvazioni per una richiesta
-func (s *ApprovalService) GetApprovalsByRequestID(requestID int) ([]models.Approval, error) {
+// GetApprovalsByRequestID recupera tutte le approvazioni per una richiesta propria o di un utente nello scope
+func (s *ApprovalService) GetApprovalsByRequestID(requestID int, viewer Viewer) ([]models.Approval, error) {
 	if requestID <= 0 {
 		return nil, errors.New("ID richiesta non valido")
 	}
 
+	visible, err := s.canViewRequest(requestID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("richiesta non trovata")
+	}
+
 	approvals, err := s.approvalRepository.GetByRequestID(requestID)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle approvazioni per richiesta: %w", err)
@@ -156,8 +199,8 @@ func (s *ApprovalService) GetApprovalsByApproverID(approverID, limit, offset int
 	return approvals, nil
 }
 
-// GetApprovalsByStatus recupera approvazioni per status
-func (s *ApprovalService) GetApprovalsByStatus(status models.ApprovalStatus, limit, offset int) ([]models.Approval, error) {
+// GetApprovalsByStatus recupera le approvazioni visibili all'utente per status
+func (s *ApprovalService) GetApprovalsByStatus(viewer Viewer, status models.ApprovalStatus, limit, offset int) ([]models.Approval, error) {
 	if status != models.ApprovalAccepted && 
 	   status != models.ApprovalRejected && 
 	   status != models.ApprovalRevoked {
@@ -171,7 +214,12 @@ func (s *ApprovalService) GetApprovalsByStatus(status models.ApprovalStatus, lim
 		offset = 0
 	}
 
-	approvals, err := s.approvalRepository.GetByStatus(status, limit, offset)
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, err := s.approvalRepository.GetByStatus(status, limit, offset, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle approvazioni per status: %w", err)
 	}
@@ -180,7 +228,7 @@ func (s *ApprovalService) GetApprovalsByStatus(status models.ApprovalStatus, lim
 }
 
 // UpdateApprovalStatus aggiorna lo status di un'approvazione esistente
-func (s *ApprovalService) UpdateApprovalStatus(id int, approverID int, status models.ApprovalStatus, comments *string) (*models.Approval, error) {
+func (s *ApprovalService) UpdateApprovalStatus(id int, approverID int, approverRoleID *int, status models.ApprovalStatus, comments *string) (*models.Approval, error) {
 	if id <= 0 {
 		return nil, errors.New("ID approvazione non valido")
 	}
@@ -203,6 +251,18 @@ func (s *ApprovalService) UpdateApprovalStatus(id int, approverID int, status mo
 		return nil, errors.New("non autorizzato a modificare questa approvazione")
 	}
 
+	// Il permesso sul tipo di richiesta potrebbe essere stato tolto dopo l'approvazione
+	existingRequest, err := s.requestRepository.GetByID(existingApproval.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("errore nel controllo della richiesta: %w", err)
+	}
+	if existingRequest == nil {
+		return nil, errors.New("richiesta non trovata")
+	}
+	if err := s.checkApprovePermission(approverRoleID, existingRequest.RequestType); err != nil {
+		return nil, err
+	}
+
 	// Business logic: non permettere di cambiare un'approvazione già accettata
 	if existingApproval.Status == models.ApprovalAccepted && status != models.ApprovalRevoked {
 		return nil, errors.New("non è possibile modificare un'approvazione già accettata (solo revoca)")
@@ -227,7 +287,7 @@ func (s *ApprovalService) UpdateApprovalStatus(id int, approverID int, status mo
 }
 
 // RevokeApproval revoca un'approvazione esistente (solo per approvazioni accettate)
-func (s *ApprovalService) RevokeApproval(id int, approverID int, reason string) (*models.Approval, error) {
+func (s *ApprovalService) RevokeApproval(id int, approverID int, approverRoleID *int, reason string) (*models.Approval, error) {
 	if id <= 0 {
 		return nil, errors.New("ID approvazione non valido")
 	}
@@ -254,7 +314,19 @@ func (s *ApprovalService) RevokeApproval(id int, approverID int, reason string)
 		comments = &defaultReason
 	}
 
-	return s.UpdateApprovalStatus(id, approverID, models.ApprovalRevoked, comments)
+	return s.UpdateApprovalStatus(id, approverID, approverRoleID, models.ApprovalRevoked, comments)
+}
+
+// checkApprovePermission verifica che il ruolo possa approvare il tipo di richiesta
+func (s *ApprovalService) checkApprovePermission(approverRoleID *int, requestType models.RequestType) error {
+	allowed, err := s.permissionService.HasPermission(approverRoleID, models.ApprovePermissionFor(requestType))
+	if err != nil {
+		return fmt.Errorf("errore nel controllo dei permessi: %w", err)
+	}
+	if !allowed {
+		return errors.New("non autorizzato ad approvare questo tipo di richiesta")
+	}
+	return nil
 }
 
 // DeleteApproval elimina un'approvazione (solo per admin o in casi eccezionali)
@@ -342,19 +414,24 @@ func (s *ApprovalService) GetRequestApprovalStatus(requestID int) (*RequestAppro
 	return status, nil
 }
 
-// GetApprovalStatistics restituisce statistiche sulle approvazioni
-func (s *ApprovalService) GetApprovalStatistics() (*ApprovalStatistics, error) {
-	acceptedCount, err := s.approvalRepository.CountByStatus(models.ApprovalAccepted)
+// GetApprovalStatistics restituisce statistiche sulle approvazioni delle richieste nello scope
+func (s *ApprovalService) GetApprovalStatistics(viewer Viewer) (*ApprovalStatistics, error) {
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptedCount, err := s.approvalRepository.CountByStatus(models.ApprovalAccepted, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel conteggio approvazioni accettate: %w", err)
 	}
 
-	rejectedCount, err := s.approvalRepository.CountByStatus(models.ApprovalRejected)
+	rejectedCount, err := s.approvalRepository.CountByStatus(models.ApprovalRejected, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel conteggio approvazioni rifiutate: %w", err)
 	}
 
-	revokedCount, err := s.approvalRepository.CountByStatus(models.ApprovalRevoked)
+	revokedCount, err := s.approvalRepository.CountByStatus(models.ApprovalRevoked, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel conteggio approvazioni revocate: %w", err)
 	}
@@ -398,4 +475,17 @@ type ApprovalStatistics struct {
 	AcceptanceRate  float64 `json:"acceptance_rate"`
 	RejectionRate   float64 `json:"rejection_rate"`
 	RevocationRate  float64 `json:"revocation_rate"`
-}
\ No newline at end of file
+}
+
+// canViewRequest verifica che la richiesta esista e sia propria o di un utente nello scope
+func (s *ApprovalService) canViewRequest(requestID int, viewer Viewer) (bool, error) {
+	request, err := s.requestRepository.GetByID(requestID)
+	if err != nil {
+		return false, fmt.Errorf("errore nel controllo della richiesta: %w", err)
+	}
+	if request == nil {
+		return false, nil
+	}
+
+	return s.teamScopeService.CanView(viewer, request.UserID)
+}
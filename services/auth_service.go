@@ -1,35 +1,51 @@
 package services
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"merendels-backend/config"
 	"merendels-backend/models"
 	"merendels-backend/repositories"
 	"merendels-backend/utils"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 type AuthService struct {
 	authRepository *repositories.AuthRepository
 	userRepository *repositories.UserRoleRepository
 	// userRepository per validazione role_id
+	refreshTokenRepository *repositories.RefreshTokenRepository
+	impersonationRepository *repositories.ImpersonationRepository
+	sessionService *SessionService
+	revocationService *TokenRevocationService
+	passwordPolicy *PasswordPolicy
+	passwordHasher *PasswordHasher
+	mfaService *MFAService
+	lockoutPolicy LockoutPolicy
 }
 
 // NewAuthService crea una nuova istanza del servizio
 func NewAuthService() *AuthService {
-	return &AuthService{
+	service := &AuthService{
 		authRepository: repositories.NewAuthRepository(),
 		userRepository: repositories.NewUserRoleRepository(),
+		refreshTokenRepository: repositories.NewRefreshTokenRepository(),
+		impersonationRepository: repositories.NewImpersonationRepository(),
+		sessionService: NewSessionService(),
+		revocationService: NewTokenRevocationService(),
+		lockoutPolicy: NewLockoutPolicyFromConfig(),
+		passwordHasher: NewPasswordHasherFromConfig(),
 	}
+	service.passwordPolicy = NewPasswordPolicyFromConfig(service.verifyCredential)
+	service.mfaService = NewMFAService(service)
+
+	return service
 }
 
 // Login verifica le credenziali e restituisce il JWT Token
-func (s *AuthService) Login(request *models.LoginRequest) (*models.LoginResponse, error) {
+func (s *AuthService) Login(request *models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
 	// Business logic: validazione input
 	if request.Email == "" || request.Password == "" {
 		return nil, errors.New("email and password are required")
@@ -46,39 +62,277 @@ func (s *AuthService) Login(request *models.LoginRequest) (*models.LoginResponse
 
 	if loginData == nil {
 		// Registra tentativo fallito per email inesistente
+		s.recordAttempt(nil, email, client, models.LoginFailure)
 		log.Printf("Login attempt for non-existent email: %s", email)
 		return nil, errors.New("invalid email or password")
 	}
 	
-	// Check numero di tentativi falliti negli ultimi x minuti
-	failedAttempts, _ := s.authRepository.GetRecentFailedAttempts(loginData.UserID, 1)
-	if failedAttempts >= 5 {
-		s.authRepository.RecordLoginAttempt(loginData.UserID, models.LoginFailure)
-		return nil, errors.New("too many failed attempts, please try again later")
+	// Account bloccato per troppi tentativi falliti consecutivi
+	if err := s.checkLockout(loginData.UserID); err != nil {
+		s.recordAttempt(&loginData.UserID, email, client, models.LoginLocked)
+		return nil, err
 	}
 
 	// BUSINESS LOGIC: Verifica password con bcrypt + salt
-	// QUESTA È LA FIX: aggiungi il salt alla password prima del confronto
-	passwordWithSalt := request.Password + loginData.Salt
-	err = bcrypt.CompareHashAndPassword([]byte(loginData.PasswordHash), []byte(passwordWithSalt))
-	if err != nil {
+	credential := models.AuthCredential{PasswordHash: loginData.PasswordHash, Salt: loginData.Salt}
+	if !s.verifyCredential(request.Password, credential) {
 		// Password sbagliata, registro tentativo fallito
-		s.authRepository.RecordLoginAttempt(loginData.UserID, models.LoginFailure)
+		s.recordAttempt(&loginData.UserID, email, client, models.LoginFailure)
 		log.Printf("Failed login attempt for user with ID: %d", loginData.UserID)
 		return nil, errors.New("invalid email or password")
 	}
 
+	// Utente disattivato: la password é corretta ma l'accesso non é consentito
+	if loginData.Status != models.UserActive {
+		s.recordAttempt(&loginData.UserID, email, client, models.LoginFailure)
+		log.Printf("Login attempt for inactive user ID: %d", loginData.UserID)
+		return nil, errors.New("account is disabled")
+	}
+
+	// Password corretta: se l'hash é in formato legacy lo aggiorno ora che ho la password in chiaro
+	s.upgradePasswordHash(loginData.UserID, request.Password, credential)
+
+	// 2FA attiva o imposta dal ruolo: i token veri vengono emessi solo dopo il codice TOTP.
+	// Il tentativo non conta come riuscito finché il codice non é verificato
+	if loginData.MFAEnabled || loginData.MFARequired {
+		s.recordAttempt(&loginData.UserID, email, client, models.LoginMFAPending)
+		return s.mfaChallenge(loginData)
+	}
+
 	// Login avvenuto con successo, registro tentativo riuscito
-	s.authRepository.RecordLoginAttempt(loginData.UserID, models.LoginSuccess)
+	s.recordAttempt(&loginData.UserID, email, client, models.LoginSuccess)
+
+	// Genero token di accesso + refresh token (nuova famiglia per questo dispositivo)
+	response, err := s.issueTokens(loginData, "", request.DeviceName, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successful login for user ID: %d (%s)", loginData.UserID, loginData.Email)
+	return response, nil
+}
+
+// mfaChallenge restituisce il token di challenge per il secondo passo del login.
+// Se il ruolo impone la 2FA e l'utente non l'ha ancora configurata avvia l'enrollment
+func (s *AuthService) mfaChallenge(loginData *repositories.LoginData) (*models.LoginResponse, error) {
+	mfaToken, err := utils.GeneratePurposeToken(mfaChallengePurpose, loginData.UserID, loginData.Email, config.MFAChallengeTTL())
+	if err != nil {
+		return nil, fmt.Errorf("error generating mfa token: %w", err)
+	}
+
+	response := &models.LoginResponse{
+		MFARequired: true,
+		MFAToken: mfaToken,
+	}
+	response.User.ID = loginData.UserID
+	response.User.Name = loginData.Name
+	response.User.Email = loginData.Email
+
+	if !loginData.MFAEnabled {
+		enrollment, err := s.mfaService.BeginEnrollment(loginData.UserID, loginData.Email)
+		if err != nil {
+			return nil, err
+		}
+		response.MFAEnrollment = enrollment
+	}
+
+	log.Printf("MFA challenge issued for user ID: %d", loginData.UserID)
+	return response, nil
+}
+
+// VerifyMFA completa il login verificando il codice TOTP (o un codice di recupero)
+// associato al token di challenge e restituisce i token di accesso
+func (s *AuthService) VerifyMFA(request *models.MFAVerifyRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	if request.Code == "" && request.RecoveryCode == "" {
+		return nil, errors.New("mfa code is required")
+	}
+
+	claims, err := utils.ValidatePurposeToken(request.MFAToken, mfaChallengePurpose)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	// Il challenge é monouso
+	revoked, err := s.revocationService.IsRevoked(claims)
+	if err != nil {
+		return nil, fmt.Errorf("error checking mfa token: %w", err)
+	}
+	if revoked {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	loginData, err := s.authRepository.GetUserForToken(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	if loginData.Status != models.UserActive {
+		return nil, errors.New("account is disabled")
+	}
+
+	// Stesso blocco del login: protegge il codice a 6 cifre da tentativi ripetuti
+	if err := s.checkLockout(loginData.UserID); err != nil {
+		s.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginLocked)
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfaService.VerifyLoginCode(loginData.UserID, request.Code, request.RecoveryCode, client.IPAddress)
+	if err != nil {
+		if err.Error() == "invalid mfa code" {
+			s.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginFailure)
+			log.Printf("Failed MFA verification for user ID: %d", loginData.UserID)
+		}
+		return nil, err
+	}
+
+	s.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginSuccess)
+
+	if err := s.revocationService.RevokeToken(claims); err != nil {
+		return nil, fmt.Errorf("error consuming mfa token: %w", err)
+	}
+
+	response, err := s.issueTokens(loginData, "", request.DeviceName, client)
+	if err != nil {
+		return nil, err
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	log.Printf("Successful MFA login for user ID: %d (%s)", loginData.UserID, loginData.Email)
+	return response, nil
+}
+
+// RefreshToken ruota il refresh token e restituisce un nuovo token di accesso
+func (s *AuthService) RefreshToken(request *models.RefreshTokenRequest) (*models.LoginResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, errors.New("refresh token is required")
+	}
+
+	storedToken, err := s.refreshTokenRepository.GetByHash(utils.HashToken(request.RefreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving refresh token: %w", err)
+	}
+	if storedToken == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Token giá ruotato o revocato: qualcuno sta riusando un token vecchio,
+	// invalido l'intera famiglia (sia il legittimo proprietario che l'attaccante devono rifare login)
+	if storedToken.UsedAt != nil || storedToken.RevokedAt != nil {
+		s.revokeFamilyAfterReuse(storedToken)
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	if time.Now().After(storedToken.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	// Marca il token come usato in modo atomico (due refresh concorrenti = riuso)
+	marked, err := s.refreshTokenRepository.MarkUsed(storedToken.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating refresh token: %w", err)
+	}
+	if !marked {
+		s.revokeFamilyAfterReuse(storedToken)
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	// Ricarico i dati utente dal database, cosí ruolo e livello sono aggiornati
+	loginData, err := s.authRepository.GetUserForToken(storedToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if loginData.Status != models.UserActive {
+		if err := s.sessionService.RevokeSession(storedToken.FamilyID); err != nil {
+			log.Printf("Error revoking session %s: %v", storedToken.FamilyID, err)
+		}
+		return nil, errors.New("account is disabled")
+	}
+
+	return s.issueTokens(loginData, storedToken.FamilyID, storedToken.DeviceName, models.ClientInfo{})
+}
+
+// Logout revoca il token di accesso corrente e chiude la sessione con i suoi refresh token
+func (s *AuthService) Logout(claims *utils.JWTClaims) error {
+	if err := s.revocationService.RevokeToken(claims); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		if err := s.sessionService.RevokeSession(claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	// Logout da un token di impersonificazione: chiude la sessione dell'operatore
+	if claims.IsImpersonated() {
+		if err := s.impersonationRepository.EndSession(claims.Actor.ImpersonationID); err != nil {
+			return fmt.Errorf("error ending impersonation session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// LogoutAll revoca tutti i token di accesso e chiude tutte le sessioni dell'utente
+func (s *AuthService) LogoutAll(userID int) error {
+	if err := s.revocationService.RevokeAllUserTokens(userID); err != nil {
+		return err
+	}
+
+	if err := s.sessionService.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// issueTokens genera token di accesso e un nuovo refresh token nella famiglia indicata
+// (se familyID é vuoto viene creata una nuova famiglia, cioé un nuovo login, e la relativa sessione)
+func (s *AuthService) issueTokens(loginData *repositories.LoginData, familyID string, deviceName *string, client models.ClientInfo) (*models.LoginResponse, error) {
+	if familyID == "" {
+		newFamilyID, err := utils.GenerateRandomToken(16)
+		if err != nil {
+			return nil, fmt.Errorf("error generating session id: %w", err)
+		}
+		familyID = newFamilyID
+
+		if err := s.sessionService.CreateSession(loginData.UserID, familyID, deviceName, client); err != nil {
+			return nil, err
+		}
+	}
 
 	// Genero JWT Token
-	token, err := utils.GenerateToken(loginData.UserID, loginData.Email, loginData.RoleID, loginData.HierarchyLevel)
+	token, expiresAt, err := utils.GenerateToken(loginData.UserID, loginData.Email, loginData.RoleID, loginData.HierarchyLevel, familyID, loginData.SecurityVersion)
 	if err != nil {
 		return nil, fmt.Errorf("error generating token: %w", err)
 	}
 
+	// Refresh token opaco, salvo solo l'hash
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	err = s.refreshTokenRepository.Create(&models.RefreshToken{
+		UserID: loginData.UserID,
+		FamilyID: familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		DeviceName: deviceName,
+		ExpiresAt: time.Now().Add(config.RefreshTokenTTL()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error saving refresh token: %w", err)
+	}
+
 	response := &models.LoginResponse{
 		Token: token,
+		ExpiresIn: int(time.Until(expiresAt).Seconds()),
+		RefreshToken: refreshToken,
 		User: struct {
 			ID int `json:"id"`
 			Name string `json:"name"`
@@ -90,10 +344,134 @@ func (s *AuthService) Login(request *models.LoginRequest) (*models.LoginResponse
 		},
 	}
 
-	log.Printf("Successful login for user ID: %d (%s)", loginData.UserID, loginData.Email)
 	return response, nil
 }
 
+// revokeFamilyAfterReuse invalida tutta la famiglia di un refresh token riusato
+func (s *AuthService) revokeFamilyAfterReuse(token *models.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user ID %d (family %s), revoking family", token.UserID, token.FamilyID)
+	if err := s.sessionService.RevokeSession(token.FamilyID); err != nil {
+		log.Printf("Error revoking session %s: %v", token.FamilyID, err)
+	}
+}
+
+// checkLockout restituisce un *LockoutError se l'account é bloccato
+func (s *AuthService) checkLockout(userID int) error {
+	status, err := s.getLockoutStatus(userID)
+	if err != nil {
+		// In caso di errore sul database non blocco il login, ma lo segnalo
+		log.Printf("Error checking lockout for user ID %d: %v", userID, err)
+		return nil
+	}
+
+	if status.Locked {
+		return &LockoutError{LockedUntil: *status.LockedUntil}
+	}
+	return nil
+}
+
+// getLockoutStatus calcola lo stato del blocco dai tentativi falliti consecutivi
+func (s *AuthService) getLockoutStatus(userID int) (*models.LockoutStatus, error) {
+	failures, lastFailure, err := s.authRepository.GetConsecutiveFailures(userID, time.Now().Add(-s.lockoutPolicy.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.LockoutStatus{FailedAttempts: failures}
+	if lastFailure != nil {
+		lockedUntil := s.lockoutPolicy.LockedUntil(failures, *lastFailure)
+		if time.Now().Before(lockedUntil) {
+			status.Locked = true
+			status.LockedUntil = &lockedUntil
+		}
+	}
+
+	return status, nil
+}
+
+// recordAttempt salva il tentativo di login, un errore non blocca il flusso
+func (s *AuthService) recordAttempt(userID *int, email string, client models.ClientInfo, result models.LoginResult) {
+	attempt := &models.AuthLoginAttempt{
+		UserID: userID,
+		Result: result,
+	}
+	if email != "" {
+		attempt.Email = &email
+	}
+	if client.IPAddress != "" {
+		attempt.IPAddress = &client.IPAddress
+	}
+	if client.UserAgent != "" {
+		// Limito la lunghezza, lo user agent é controllato dal client
+		userAgent := client.UserAgent
+		if runes := []rune(userAgent); len(runes) > 512 {
+			userAgent = string(runes[:512])
+		}
+		attempt.UserAgent = &userAgent
+	}
+
+	if err := s.authRepository.RecordLoginAttempt(attempt); err != nil {
+		log.Printf("Error recording login attempt (%s): %v", result, err)
+	}
+}
+
+// GetLoginAttempts restituisce i tentativi di login di un utente e lo stato del blocco (uso amministrativo)
+func (s *AuthService) GetLoginAttempts(userID, limit, offset int) ([]models.AuthLoginAttempt, int, *models.LockoutStatus, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	user, err := s.authRepository.GetUserProfile(userID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if user == nil {
+		return nil, 0, nil, errors.New("user not found")
+	}
+
+	attempts, total, err := s.authRepository.GetLoginAttempts(userID, limit, offset)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error retrieving login attempts: %w", err)
+	}
+
+	status, err := s.getLockoutStatus(userID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error retrieving lockout status: %w", err)
+	}
+
+	return attempts, total, status, nil
+}
+
+// UnlockUser sblocca un account azzerando i tentativi falliti consecutivi
+func (s *AuthService) UnlockUser(userID, adminID int, ipAddress string) error {
+	unlocked, err := s.authRepository.ResetLockout(userID)
+	if err != nil {
+		return fmt.Errorf("error unlocking user: %w", err)
+	}
+	if !unlocked {
+		return errors.New("user not found")
+	}
+
+	details := fmt.Sprintf("unlocked by user ID %d", adminID)
+	event := &models.AuthEvent{
+		UserID: &userID,
+		EventType: models.EventAccountUnlocked,
+		Details: &details,
+	}
+	if ipAddress != "" {
+		event.IPAddress = &ipAddress
+	}
+	if err := s.authRepository.RecordAuthEvent(event); err != nil {
+		log.Printf("Error recording auth event %s for user ID %d: %v", event.EventType, userID, err)
+	}
+
+	log.Printf("User ID %d unlocked by user ID %d", userID, adminID)
+	return nil
+}
+
 // GetUserProfile recupera il profilo completo dell'utente
 func (s *AuthService) GetUserProfile(userID int) (*models.User, error) {
 	if userID <= 0 {
@@ -113,14 +491,20 @@ func (s *AuthService) GetUserProfile(userID int) (*models.User, error) {
 }
 
 // Register crea un nuovo utente con credenziali hashate
-func (s *AuthService) Register(request *models.CreateAuthCredentialRequest, userDetails *models.CreateUserRequest) (*models.LoginResponse, error) {
+func (s *AuthService) Register(request *models.CreateAuthCredentialRequest, userDetails *models.CreateUserRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	// Registrazione libera disabilitata di default: gli utenti entrano tramite invito
+	if !config.OpenRegistrationEnabled() {
+		return nil, errors.New("registration is disabled")
+	}
+
 	// Validazioni
 	if request.Password == "" {
 		return nil, errors.New("password cannot be empty")
 	}
 
-	if len(request.Password) < 6 {
-		return nil, errors.New("password must be at least 6 characters")
+	// Password policy (nuovo utente, nessuno storico)
+	if err := s.passwordPolicy.Validate(request.Password, nil); err != nil {
+		return nil, err
 	}
 
 	if userDetails.Email == "" || userDetails.Name == "" {
@@ -139,6 +523,13 @@ func (s *AuthService) Register(request *models.CreateAuthCredentialRequest, user
 		return nil, errors.New("email already registered")
 	}
 
+	// Ruolo e manager non sono scelti dal client: ruolo di default da configurazione, nessun manager
+	userDetails.RoleID = nil
+	userDetails.ManagerID = nil
+	if roleID := config.OpenRegistrationRoleID(); roleID > 0 {
+		userDetails.RoleID = &roleID
+	}
+
 	// Business logic: valida role_id se fornito
 	if userDetails.RoleID != nil && *userDetails.RoleID > 0 {
 		role, err := s.userRepository.GetByID(*userDetails.RoleID)
@@ -177,55 +568,140 @@ func (s *AuthService) Register(request *models.CreateAuthCredentialRequest, user
 		Password: request.Password,
 	}
 
-	return s.Login(loginRequest)
+	return s.Login(loginRequest, client)
 }
 
-// ChangePassword cambia la password di un utente
-func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+// ChangePassword cambia la password di un utente dopo aver verificato quella attuale.
+// Tutte le sessioni vengono invalidate e vengono restituiti nuovi token per il dispositivo corrente
+func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string, client models.ClientInfo) (*models.LoginResponse, error) {
 	// Validazioni
 	if newPassword == "" {
-		return errors.New("password must not be empty")
+		return nil, errors.New("password must not be empty")
 	}
 
-	if len(newPassword) < 6 {
-		return errors.New("password must have at least 6 characters")
+	// La password corrente passa dallo stesso conteggio e blocco del login:
+	// con un token rubato non la si puó indovinare per tentativi
+	if err := s.checkLockout(userID); err != nil {
+		s.recordAttempt(&userID, "", client, models.LoginLocked)
+		return nil, err
 	}
 
-	// TODO: Da implementare verifica della password corrente
+	// Verifica della password corrente
+	credential, err := s.authRepository.GetCredentialsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving credentials: %w", err)
+	}
+	if credential == nil || !s.verifyCredential(currentPassword, *credential) {
+		s.recordAttempt(&userID, "", client, models.LoginFailure)
+		log.Printf("Failed password change for user ID %d: wrong current password", userID)
+		return nil, errors.New("current password is incorrect")
+	}
+
+	// Password policy, compreso il controllo sulle ultime password usate
+	if err := s.validateNewPassword(userID, newPassword); err != nil {
+		return nil, err
+	}
 
+	// Salva la nuova password e invalida tutte le sessioni esistenti (anche quella corrente)
+	if err := s.setPassword(userID, newPassword); err != nil {
+		return nil, err
+	}
+
+	// Nuova sessione per il dispositivo che ha cambiato la password
+	loginData, err := s.authRepository.GetUserForToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return nil, errors.New("user not found")
+	}
+
+	response, err := s.issueTokens(loginData, "", nil, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Password changed for user ID %d", userID)
+	return response, nil
+}
+
+// validateNewPassword applica la password policy confrontando anche le ultime password dell'utente
+func (s *AuthService) validateNewPassword(userID int, newPassword string) error {
+	credential, err := s.authRepository.GetCredentialsByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("error retrieving credentials: %w", err)
+	}
+
+	var previousPasswords []models.AuthCredential
+	if credential != nil {
+		previousPasswords = append(previousPasswords, *credential)
+	}
+
+	if historySize := PasswordHistorySize(); historySize > 1 {
+		history, err := s.authRepository.GetPasswordHistory(userID, historySize-1)
+		if err != nil {
+			return fmt.Errorf("error retrieving password history: %w", err)
+		}
+		previousPasswords = append(previousPasswords, history...)
+	}
+
+	return s.passwordPolicy.Validate(newPassword, previousPasswords)
+}
+
+// setPassword salva la nuova password e chiude tutte le sessioni dell'utente
+func (s *AuthService) setPassword(userID int, newPassword string) error {
 	// Hash della nuova password
 	passwordHash, salt, err := s.hashPassword(newPassword)
 	if err != nil {
 		return fmt.Errorf("error hashing new password: %w", err)
 	}
 
-	// Aggiorna nel database
+	// Aggiorna nel database (incrementa anche la versione di sicurezza)
 	_, err = s.authRepository.UpdatePassword(userID, passwordHash, salt)
 	if err != nil {
 		return fmt.Errorf("error updating password: %w", err)
 	}
+	securityVersions.invalidate(userID)
+
+	// Invalida tutte le sessioni esistenti
+	if err := s.LogoutAll(userID); err != nil {
+		return fmt.Errorf("error invalidating sessions: %w", err)
+	}
 
-	log.Printf("Password changed for user ID %d;", userID)
 	return nil
 }
 
-// hashPassword genera hash bcrypt + salt casuale
+// verifyCredential confronta una password con una credenziale salvata (argon2id o bcrypt + salt legacy)
+func (s *AuthService) verifyCredential(password string, credential models.AuthCredential) bool {
+	return s.passwordHasher.Verify(password, credential)
+}
+
+// hashPassword genera l'hash argon2id della password (il salt é incluso nell'hash)
 func (s *AuthService) hashPassword(password string) (string, string, error) {
-	// Genera salt casuale (16 bytes = 32 char hex)
-	saltBytes := make([]byte, 16)
-	_, err := rand.Read(saltBytes)
-	if err != nil {
-		return "", "", err
+	return s.passwordHasher.Hash(password)
+}
+
+// upgradePasswordHash ricalcola l'hash di una credenziale legacy dopo un login riuscito,
+// cosí gli utenti migrano ad argon2id senza dover reimpostare la password.
+// Un errore non blocca il login
+func (s *AuthService) upgradePasswordHash(userID int, password string, credential models.AuthCredential) {
+	if !s.passwordHasher.NeedsRehash(credential) {
+		return
 	}
-	salt := hex.EncodeToString(saltBytes)
 
-	// Hash con bcrypt (cost 12, bilanciato tra sicurezza e performance)
-	passwordWithSalt := password + salt
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(passwordWithSalt), 12)
+	passwordHash, salt, err := s.passwordHasher.Hash(password)
 	if err != nil {
-		return "", "", err
+		log.Printf("Error rehashing password for user ID %d: %v", userID, err)
+		return
 	}
 
-	return string(hashedPassword), salt, nil
-
+	// Aggiorna solo se nel frattempo la password non é stata cambiata
+	updated, err := s.authRepository.ReplacePasswordHash(userID, credential.PasswordHash, passwordHash, salt)
+	if err != nil {
+		log.Printf("Error saving rehashed password for user ID %d: %v", userID, err)
+		return
+	}
+	if updated {
+		log.Printf("Password hash upgraded to argon2id for user ID %d", userID)
+	}
 }
\ No newline at end of file
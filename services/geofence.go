@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"merendels-backend/models"
+)
+
+const (
+	earthRadiusMeters = 6371000.0
+	// maxGeofenceAccuracy precisione peggiore accettata: oltre, la posizione non prova nulla
+	maxGeofenceAccuracy = 150.0
+)
+
+// geofenceResult esito del controllo di una posizione sulle sedi
+type geofenceResult struct {
+	status  models.GeofenceStatus
+	site    *models.OfficeSite // Sede in cui si trova o, se fuori, la piú vicina (nil senza posizione)
+	policy  models.GeofencePolicy
+	flagged bool
+}
+
+// validatePosition controlla le coordinate della request: latitudine e longitudine vanno date insieme
+func validatePosition(latitude, longitude, accuracy *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+	if latitude != nil && (*latitude < -90 || *latitude > 90 || *longitude < -180 || *longitude > 180) {
+		return errors.New("invalid coordinates")
+	}
+	if accuracy != nil && *accuracy < 0 {
+		return errors.New("invalid coordinates")
+	}
+	return nil
+}
+
+// distanceMeters distanza tra due punti sulla superficie terrestre (formula dell'emisenoverso)
+func distanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// evaluateGeofence confronta la posizione con le sedi attive (sites non vuoto).
+// Dentro una sede se la distanza non supera il raggio: la precisione dichiarata dal client non allarga
+// il geofence, decide solo se la posizione é affidabile. Fuori da tutte vale la policy della sede
+// piú vicina; senza posizione affidabile la piú restrittiva
+func evaluateGeofence(sites []models.OfficeSite, latitude, longitude, accuracy *float64) geofenceResult {
+	if latitude == nil || (accuracy != nil && *accuracy > maxGeofenceAccuracy) {
+		result := geofenceResult{status: models.GeofenceNoPosition, policy: models.GeofencePolicyAllow}
+		for _, site := range sites {
+			if policyRank(site.Policy) > policyRank(result.policy) {
+				result.policy = site.Policy
+			}
+		}
+		result.flagged = result.policy == models.GeofencePolicyFlag
+		return result
+	}
+
+	var nearest *models.OfficeSite
+	nearestDistance := math.MaxFloat64
+	for i := range sites {
+		distance := distanceMeters(*latitude, *longitude, sites[i].Latitude, sites[i].Longitude)
+		if distance <= float64(sites[i].RadiusMeters) {
+			return geofenceResult{status: models.GeofenceInside, site: &sites[i], policy: sites[i].Policy}
+		}
+		if distance < nearestDistance {
+			nearest, nearestDistance = &sites[i], distance
+		}
+	}
+
+	return geofenceResult{
+		status:  models.GeofenceOutside,
+		site:    nearest,
+		policy:  nearest.Policy,
+		flagged: nearest.Policy == models.GeofencePolicyFlag,
+	}
+}
+
+// policyRank ordina le policy dalla piú permissiva alla piú restrittiva
+func policyRank(policy models.GeofencePolicy) int {
+	switch policy {
+	case models.GeofencePolicyReject:
+		return 2
+	case models.GeofencePolicyFlag:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isValidGeofencePolicy verifica che la policy sia una di quelle previste
+func isValidGeofencePolicy(policy models.GeofencePolicy) bool {
+	return policy == models.GeofencePolicyReject || policy == models.GeofencePolicyFlag || policy == models.GeofencePolicyAllow
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// impersonationAllowedWrites rotte non in sola lettura consentite durante l'impersonificazione
+var impersonationAllowedWrites = map[string]bool{
+	"POST /api/auth/logout":   true, // Chiude la sessione di impersonificazione
+	"POST /api/auth/validate": true,
+}
+
+// impersonationBlockedPrefixes rotte vietate durante l'impersonificazione anche in lettura, insieme a tutte
+// le esportazioni (.../export): dati personali che inoltre verrebbero registrati a nome dell'utente impersonato
+var impersonationBlockedPrefixes = []string{
+	"/api/privacy/",
+}
+
+type ImpersonationService struct {
+	impersonationRepository *repositories.ImpersonationRepository
+	authRepository          *repositories.AuthRepository
+	permissionService       *PermissionService
+}
+
+// NewImpersonationService crea una nuova istanza del servizio
+func NewImpersonationService() *ImpersonationService {
+	return &ImpersonationService{
+		impersonationRepository: repositories.NewImpersonationRepository(),
+		authRepository:          repositories.NewAuthRepository(),
+		permissionService:       NewPermissionService(),
+	}
+}
+
+// StartImpersonation emette un token per vedere l'applicazione come l'utente indicato.
+// Il token contiene il claim act con l'operatore, dura IMPERSONATION_TTL e non ha refresh token
+func (s *ImpersonationService) StartImpersonation(actor *utils.JWTClaims, request *models.StartImpersonationRequest, ipAddress string) (*models.ImpersonationResponse, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if actor.IsImpersonated() {
+		return nil, errors.New("cannot impersonate while impersonating")
+	}
+	if request.UserID == actor.UserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	target, err := s.authRepository.GetUserForToken(request.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if target == nil {
+		return nil, errors.New("user not found")
+	}
+	if target.Status != models.UserActive {
+		return nil, errors.New("user is not active")
+	}
+
+	// Un operatore non puó assumere l'identitá di un altro operatore del supporto
+	targetCanImpersonate, err := s.permissionService.HasPermission(target.RoleID, models.PermUsersImpersonate)
+	if err != nil {
+		return nil, err
+	}
+	if targetCanImpersonate {
+		return nil, errors.New("cannot impersonate another administrator")
+	}
+
+	// Né di un utente con ruolo piú alto o con permessi che l'operatore non ha (es. company:read_all, privacy:manage)
+	if outranks(target.HierarchyLevel, actor.HierarchyLevel) {
+		return nil, errors.New("cannot impersonate a user with a higher role")
+	}
+	covered, err := s.permissionService.CoversRole(actor.RoleID, target.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, errors.New("cannot impersonate a user with permissions you do not have")
+	}
+
+	session := &models.ImpersonationSession{
+		ActorID:      actor.UserID,
+		ActorEmail:   actor.Email,
+		TargetUserID: target.UserID,
+		TargetEmail:  target.Email,
+		Reason:       reason,
+		ExpiresAt:    time.Now().Add(config.ImpersonationTTL()),
+	}
+	if ipAddress != "" {
+		session.IPAddress = &ipAddress
+	}
+	if err := s.impersonationRepository.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("error creating impersonation session: %w", err)
+	}
+
+	token, expiresAt, err := utils.GenerateImpersonationToken(
+		target.UserID,
+		target.Email,
+		target.RoleID,
+		target.HierarchyLevel,
+		target.SecurityVersion,
+		&utils.ActorClaim{
+			UserID:          actor.UserID,
+			Email:           actor.Email,
+			SecurityVersion: actor.SecurityVersion,
+			ImpersonationID: session.ID,
+		},
+		time.Until(session.ExpiresAt),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	log.Printf("Impersonation %d started: user ID %d (%s) acting as user ID %d (%s), reason: %s",
+		session.ID, actor.UserID, actor.Email, target.UserID, target.Email, reason)
+
+	return &models.ImpersonationResponse{
+		Token:     token,
+		ExpiresIn: int(time.Until(expiresAt).Seconds()),
+		Session:   session,
+	}, nil
+}
+
+// IsAllowed indica se la chiamata é consentita durante l'impersonificazione:
+// sola lettura (esclusi dati personali ed esportazioni), piú il logout per chiudere la sessione
+func (s *ImpersonationService) IsAllowed(method, route string) bool {
+	if strings.HasSuffix(route, "/export") {
+		return false
+	}
+	for _, prefix := range impersonationBlockedPrefixes {
+		if strings.HasPrefix(route, prefix) {
+			return false
+		}
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return impersonationAllowedWrites[method+" "+route]
+}
+
+// RecordCall salva nell'audit una chiamata eseguita durante l'impersonificazione, un errore non blocca la risposta
+func (s *ImpersonationService) RecordCall(claims *utils.JWTClaims, method, path string, statusCode int, ipAddress string) {
+	if !claims.IsImpersonated() {
+		return
+	}
+
+	entry := &models.ImpersonationAuditEntry{
+		SessionID:    claims.Actor.ImpersonationID,
+		ActorID:      claims.Actor.UserID,
+		TargetUserID: claims.UserID,
+		Method:       method,
+		Path:         path,
+		StatusCode:   statusCode,
+	}
+	if ipAddress != "" {
+		entry.IPAddress = &ipAddress
+	}
+	if runes := []rune(entry.Path); len(runes) > 512 {
+		entry.Path = string(runes[:512])
+	}
+
+	if err := s.impersonationRepository.RecordCall(entry); err != nil {
+		log.Printf("Error recording impersonated call (session %d, %s %s): %v", entry.SessionID, method, path, err)
+	}
+}
+
+// GetSessions restituisce le sessioni di impersonificazione (uso amministrativo)
+func (s *ImpersonationService) GetSessions(limit, offset int) ([]models.ImpersonationSession, int, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	sessions, total, err := s.impersonationRepository.GetSessions(limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving impersonation sessions: %w", err)
+	}
+
+	return sessions, total, nil
+}
+
+// GetSessionAuditLog restituisce una sessione con tutte le chiamate eseguite
+func (s *ImpersonationService) GetSessionAuditLog(sessionID int) (*models.ImpersonationSession, []models.ImpersonationAuditEntry, error) {
+	session, err := s.impersonationRepository.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving impersonation session: %w", err)
+	}
+	if session == nil {
+		return nil, nil, errors.New("impersonation session not found")
+	}
+
+	entries, err := s.impersonationRepository.GetAuditLog(sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving impersonation audit log: %w", err)
+	}
+
+	return session, entries, nil
+}
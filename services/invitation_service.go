@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Scopo del token firmato contenuto nel link di invito
+const invitationPurpose = "invitation"
+
+type InvitationService struct {
+	invitationRepository *repositories.InvitationRepository
+	authRepository       *repositories.AuthRepository
+	userRoleRepository   *repositories.UserRoleRepository
+	permissionService    *PermissionService
+	authService          *AuthService
+	mailer               utils.Mailer
+}
+
+// NewInvitationService crea una nuova istanza del servizio
+func NewInvitationService(authService *AuthService, mailer utils.Mailer) *InvitationService {
+	return &InvitationService{
+		invitationRepository: repositories.NewInvitationRepository(),
+		authRepository:       repositories.NewAuthRepository(),
+		userRoleRepository:   repositories.NewUserRoleRepository(),
+		permissionService:    NewPermissionService(),
+		authService:          authService,
+		mailer:               mailer,
+	}
+}
+
+// CreateInvitation crea un invito e lo invia via email. Eventuali inviti ancora aperti
+// per la stessa email vengono revocati
+func (s *InvitationService) CreateInvitation(request *models.CreateInvitationRequest, invitedBy int) (*models.UserInvitation, error) {
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	name := strings.TrimSpace(request.Name)
+	if email == "" || name == "" {
+		return nil, errors.New("name and email are required")
+	}
+
+	exists, err := s.authRepository.CheckEmailExists(email)
+	if err != nil {
+		return nil, fmt.Errorf("error checking email: %w", err)
+	}
+	if exists {
+		return nil, errors.New("email already registered")
+	}
+
+	if request.RoleID != nil {
+		role, err := s.userRoleRepository.GetByID(*request.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("error validating role: %w", err)
+		}
+		if role == nil {
+			return nil, errors.New("invalid role_id")
+		}
+
+		// Non si puó invitare con un ruolo piú alto del proprio o con permessi che non si hanno
+		inviter, err := s.authRepository.GetUserForToken(invitedBy)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving inviter: %w", err)
+		}
+		if inviter == nil || outranks(&role.HierarchyLevel, inviter.HierarchyLevel) {
+			return nil, errors.New("cannot invite with a role higher than your own")
+		}
+		covered, err := s.permissionService.CoversRole(inviter.RoleID, &role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking role permissions: %w", err)
+		}
+		if !covered {
+			return nil, errors.New("cannot invite with a role that has permissions you do not have")
+		}
+	}
+
+	if request.ManagerID != nil {
+		manager, err := s.authRepository.GetUserProfile(*request.ManagerID)
+		if err != nil {
+			return nil, fmt.Errorf("error validating manager: %w", err)
+		}
+		if manager == nil {
+			return nil, errors.New("invalid manager_id")
+		}
+	}
+
+	ttl := config.InvitationTTL()
+	if request.ExpiresInHours != nil {
+		if *request.ExpiresInHours <= 0 {
+			return nil, errors.New("expires_in_hours must be greater than 0")
+		}
+		ttl = time.Duration(*request.ExpiresInHours) * time.Hour
+	}
+
+	// Token firmato (non falsificabile) e salvato come hash (monouso e revocabile)
+	token, err := utils.GeneratePurposeToken(invitationPurpose, 0, email, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("error generating invitation token: %w", err)
+	}
+
+	if err := s.invitationRepository.RevokePendingForEmail(email); err != nil {
+		return nil, fmt.Errorf("error revoking previous invitations: %w", err)
+	}
+
+	invitation := &models.UserInvitation{
+		Email:     email,
+		Name:      name,
+		RoleID:    request.RoleID,
+		ManagerID: request.ManagerID,
+		TokenHash: utils.HashToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.invitationRepository.Create(invitation); err != nil {
+		return nil, fmt.Errorf("error creating invitation: %w", err)
+	}
+	invitation.Status = models.InvitationPending
+
+	invitationURL := config.InvitationURL()
+	link := invitationURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Ciao %s,\n\nsei stato invitato ad accedere a Merendels.\n"+
+			"Per attivare il tuo account e scegliere la password apri questo link (valido fino al %s):\n\n%s\n",
+		name, invitation.ExpiresAt.Format("02/01/2006 15:04"), link)
+
+	// L'invito resta valido anche se l'email non parte: puó essere revocato e ricreato
+	if err := s.mailer.Send(email, "Invito a Merendels", body); err != nil {
+		log.Printf("Error sending invitation email for invitation ID %d: %v", invitation.ID, err)
+	}
+
+	log.Printf("Invitation ID %d created for %s by user ID %d", invitation.ID, email, invitedBy)
+	return invitation, nil
+}
+
+// GetInvitations restituisce gli inviti, filtrati per stato se indicato
+func (s *InvitationService) GetInvitations(status string) ([]models.UserInvitation, error) {
+	filter := models.InvitationStatus(strings.ToUpper(status))
+	switch filter {
+	case "", models.InvitationPending, models.InvitationAccepted, models.InvitationRevoked, models.InvitationExpired:
+	default:
+		return nil, errors.New("invalid status filter")
+	}
+
+	invitations, err := s.invitationRepository.GetAll(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving invitations: %w", err)
+	}
+
+	for i := range invitations {
+		invitations[i].Status = invitationStatus(&invitations[i])
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation annulla un invito non ancora accettato
+func (s *InvitationService) RevokeInvitation(id int) error {
+	invitation, err := s.invitationRepository.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error retrieving invitation: %w", err)
+	}
+	if invitation == nil {
+		return errors.New("invitation not found")
+	}
+	if invitation.AcceptedAt != nil {
+		return errors.New("invitation already accepted")
+	}
+
+	if _, err := s.invitationRepository.Revoke(id); err != nil {
+		return fmt.Errorf("error revoking invitation: %w", err)
+	}
+
+	log.Printf("Invitation ID %d revoked", id)
+	return nil
+}
+
+// AcceptInvitation crea l'account dell'invitato con la password scelta ed effettua il login
+func (s *InvitationService) AcceptInvitation(request *models.AcceptInvitationRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	if request.Token == "" || request.Password == "" {
+		return nil, errors.New("token and password are required")
+	}
+
+	// Firma, scadenza e scopo del token
+	claims, err := utils.ValidatePurposeToken(request.Token, invitationPurpose)
+	if err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	invitation, err := s.invitationRepository.GetByTokenHash(utils.HashToken(request.Token))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving invitation: %w", err)
+	}
+	if invitation == nil || invitation.Email != claims.Email || invitationStatus(invitation) != models.InvitationPending {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	// Password policy (nuovo utente, nessuno storico)
+	if err := s.authService.passwordPolicy.Validate(request.Password, nil); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.authRepository.CheckEmailExists(invitation.Email)
+	if err != nil {
+		return nil, fmt.Errorf("error checking email: %w", err)
+	}
+	if exists {
+		return nil, errors.New("email already registered")
+	}
+
+	passwordHash, salt, err := s.authService.hashPassword(request.Password)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
+	user := &models.User{
+		Name:      invitation.Name,
+		Email:     invitation.Email,
+		RoleID:    invitation.RoleID,
+		ManagerID: invitation.ManagerID,
+	}
+	if request.Name != nil && strings.TrimSpace(*request.Name) != "" {
+		user.Name = strings.TrimSpace(*request.Name)
+	}
+
+	accepted, err := s.invitationRepository.AcceptWithNewUser(invitation.ID, user, passwordHash, salt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+	if !accepted {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	log.Printf("Invitation ID %d accepted, created user ID %d", invitation.ID, user.ID)
+
+	// Login automatico (se il ruolo impone la 2FA restituisce la challenge di enrollment)
+	loginRequest := &models.LoginRequest{
+		Email:      user.Email,
+		Password:   request.Password,
+		DeviceName: request.DeviceName,
+	}
+	return s.authService.Login(loginRequest, client)
+}
+
+// invitationStatus calcola lo stato corrente di un invito
+func invitationStatus(invitation *models.UserInvitation) models.InvitationStatus {
+	switch {
+	case invitation.AcceptedAt != nil:
+		return models.InvitationAccepted
+	case invitation.RevokedAt != nil:
+		return models.InvitationRevoked
+	case !time.Now().Before(invitation.ExpiresAt):
+		return models.InvitationExpired
+	default:
+		return models.InvitationPending
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"merendels-backend/config"
+	"time"
+)
+
+// LockoutError account temporaneamente bloccato per troppi tentativi falliti.
+// Il messaggio é lo stesso usato dagli handler per riconoscere l'errore
+type LockoutError struct {
+	LockedUntil time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return "too many failed attempts, please try again later"
+}
+
+// RetryAfter secondi da attendere prima di riprovare (almeno 1)
+func (e *LockoutError) RetryAfter() int {
+	seconds := int(time.Until(e.LockedUntil).Seconds()) + 1
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// LockoutPolicy soglie del blocco account con back-off progressivo
+type LockoutPolicy struct {
+	MaxFailedAttempts int
+	Window            time.Duration
+	BaseLockout       time.Duration
+	MaxLockout        time.Duration
+}
+
+// NewLockoutPolicyFromConfig legge le soglie dalle variabili d'ambiente
+func NewLockoutPolicyFromConfig() LockoutPolicy {
+	return LockoutPolicy{
+		MaxFailedAttempts: config.LoginMaxFailedAttempts(),
+		Window:            config.LoginAttemptWindow(),
+		BaseLockout:       config.LoginLockoutBase(),
+		MaxLockout:        config.LoginLockoutMax(),
+	}
+}
+
+// LockedUntil calcola la fine del blocco dato il numero di fallimenti consecutivi e l'ultimo fallimento.
+// Raggiunta la soglia il blocco dura BaseLockout e raddoppia ad ogni fallimento successivo, fino a MaxLockout.
+// Restituisce lo zero time se l'account non é bloccato
+func (p LockoutPolicy) LockedUntil(failures int, lastFailure time.Time) time.Time {
+	if p.MaxFailedAttempts <= 0 || failures < p.MaxFailedAttempts {
+		return time.Time{}
+	}
+
+	duration := p.BaseLockout
+	for i := p.MaxFailedAttempts; i < failures && duration < p.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > p.MaxLockout {
+		duration = p.MaxLockout
+	}
+
+	return lastFailure.Add(duration)
+}
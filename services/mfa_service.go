@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"strings"
+	"time"
+)
+
+// Scopo del token di challenge restituito dal primo passo del login
+const mfaChallengePurpose = "mfa"
+
+// Numero di codici di recupero generati ad ogni attivazione/rigenerazione
+const mfaRecoveryCodeCount = 10
+
+type MFAService struct {
+	mfaRepository  *repositories.MFARepository
+	authRepository *repositories.AuthRepository
+	authService    *AuthService
+}
+
+// NewMFAService crea una nuova istanza del servizio
+func NewMFAService(authService *AuthService) *MFAService {
+	return &MFAService{
+		mfaRepository:  repositories.NewMFARepository(),
+		authRepository: repositories.NewAuthRepository(),
+		authService:    authService,
+	}
+}
+
+// GetStatus restituisce lo stato della 2FA per l'utente
+func (s *MFAService) GetStatus(userID int) (*models.MFAStatusResponse, error) {
+	loginData, err := s.authRepository.GetUserForToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return nil, errors.New("user not found")
+	}
+
+	status := &models.MFAStatusResponse{
+		Enabled:  loginData.MFAEnabled,
+		Required: loginData.MFARequired,
+	}
+
+	if loginData.MFAEnabled {
+		mfa, err := s.mfaRepository.GetByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving mfa configuration: %w", err)
+		}
+		if mfa != nil {
+			status.EnabledAt = mfa.EnabledAt
+		}
+
+		status.RemainingRecoveryCodes, err = s.mfaRepository.CountRemainingRecoveryCodes(userID)
+		if err != nil {
+			return nil, fmt.Errorf("error counting recovery codes: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// BeginEnrollment genera un nuovo segreto TOTP in attesa di conferma con Activate
+func (s *MFAService) BeginEnrollment(userID int, email string) (*models.MFAEnrollment, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating mfa secret: %w", err)
+	}
+
+	saved, err := s.mfaRepository.SavePending(userID, secret)
+	if err != nil {
+		return nil, fmt.Errorf("error saving mfa secret: %w", err)
+	}
+	if !saved {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	issuer := config.MFAIssuer()
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(issuer, email, secret),
+	}, nil
+}
+
+// Activate conferma l'enrollment con un codice valido e restituisce i codici di recupero
+func (s *MFAService) Activate(userID int, code string, ipAddress string) ([]string, error) {
+	mfa, err := s.mfaRepository.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving mfa configuration: %w", err)
+	}
+	if mfa == nil {
+		return nil, errors.New("mfa enrollment not started")
+	}
+	if mfa.EnabledAt != nil {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	return s.activate(mfa, code, ipAddress)
+}
+
+// RegenerateRecoveryCodes invalida i codici di recupero esistenti e ne genera di nuovi
+func (s *MFAService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	mfa, err := s.getEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCode(mfa, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepository.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	log.Printf("MFA recovery codes regenerated for user ID %d", userID)
+	return codes, nil
+}
+
+// Disable disattiva la 2FA dopo aver verificato password e codice.
+// Non é consentito se il ruolo dell'utente la rende obbligatoria
+func (s *MFAService) Disable(userID int, request *models.MFADisableRequest, ipAddress string) error {
+	loginData, err := s.authRepository.GetUserForToken(userID)
+	if err != nil {
+		return fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return errors.New("user not found")
+	}
+	if loginData.MFARequired {
+		return errors.New("mfa is required for your role")
+	}
+
+	mfa, err := s.getEnabled(userID)
+	if err != nil {
+		return err
+	}
+
+	credential, err := s.authRepository.GetCredentialsByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("error retrieving credentials: %w", err)
+	}
+	if credential == nil || !s.authService.verifyCredential(request.Password, *credential) {
+		return errors.New("current password is incorrect")
+	}
+
+	if err := s.verifyCode(mfa, request.Code); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepository.Delete(userID); err != nil {
+		return fmt.Errorf("error disabling mfa: %w", err)
+	}
+
+	s.recordEvent(userID, models.EventMFADisabled, ipAddress)
+	log.Printf("MFA disabled for user ID %d", userID)
+	return nil
+}
+
+// VerifyLoginCode completa il secondo passo del login. Se l'enrollment era in attesa
+// (ruolo con 2FA obbligatoria) viene attivato e vengono restituiti i codici di recupero
+func (s *MFAService) VerifyLoginCode(userID int, code, recoveryCode, ipAddress string) ([]string, error) {
+	mfa, err := s.mfaRepository.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving mfa configuration: %w", err)
+	}
+	if mfa == nil {
+		return nil, errors.New("mfa enrollment not started")
+	}
+
+	if mfa.EnabledAt == nil {
+		return s.activate(mfa, code, ipAddress)
+	}
+
+	if recoveryCode != "" {
+		used, err := s.mfaRepository.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return nil, fmt.Errorf("error checking recovery code: %w", err)
+		}
+		if !used {
+			return nil, errors.New("invalid mfa code")
+		}
+		s.recordEvent(userID, models.EventMFARecoveryCodeUsed, ipAddress)
+		return nil, nil
+	}
+
+	return nil, s.verifyCode(mfa, code)
+}
+
+// activate verifica il primo codice, attiva la 2FA e genera i codici di recupero
+func (s *MFAService) activate(mfa *models.UserMFA, code string, ipAddress string) ([]string, error) {
+	if code == "" {
+		return nil, errors.New("mfa code is required")
+	}
+
+	step, valid := utils.ValidateTOTP(mfa.Secret, code, time.Now())
+	if !valid {
+		return nil, errors.New("invalid mfa code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfaRepository.Enable(mfa.UserID, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("error enabling mfa: %w", err)
+	}
+	if !enabled {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	s.recordEvent(mfa.UserID, models.EventMFAEnabled, ipAddress)
+	log.Printf("MFA enabled for user ID %d", mfa.UserID)
+	return codes, nil
+}
+
+// getEnabled recupera la configurazione 2FA attiva dell'utente
+func (s *MFAService) getEnabled(userID int) (*models.UserMFA, error) {
+	mfa, err := s.mfaRepository.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving mfa configuration: %w", err)
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return nil, errors.New("mfa not enabled")
+	}
+	return mfa, nil
+}
+
+// verifyCode controlla un codice TOTP impedendo il riuso dello stesso codice
+func (s *MFAService) verifyCode(mfa *models.UserMFA, code string) error {
+	if code == "" {
+		return errors.New("mfa code is required")
+	}
+
+	step, valid := utils.ValidateTOTP(mfa.Secret, code, time.Now())
+	if !valid || step <= mfa.LastUsedStep {
+		return errors.New("invalid mfa code")
+	}
+
+	marked, err := s.mfaRepository.MarkStepUsed(mfa.UserID, step)
+	if err != nil {
+		return fmt.Errorf("error saving mfa code usage: %w", err)
+	}
+	if !marked {
+		return errors.New("invalid mfa code")
+	}
+
+	return nil
+}
+
+// recordEvent registra l'evento di sicurezza, un errore non blocca il flusso
+func (s *MFAService) recordEvent(userID int, eventType models.AuthEventType, ipAddress string) {
+	event := &models.AuthEvent{
+		UserID:    &userID,
+		EventType: eventType,
+	}
+	if ipAddress != "" {
+		event.IPAddress = &ipAddress
+	}
+
+	if err := s.authRepository.RecordAuthEvent(event); err != nil {
+		log.Printf("Error recording auth event %s for user ID %d: %v", eventType, userID, err)
+	}
+}
+
+// generateRecoveryCodes genera i codici di recupero (formato xxxxx-xxxxx) e i rispettivi hash
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery codes: %w", err)
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode rende il confronto indipendente da trattini, spazi e maiuscole
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
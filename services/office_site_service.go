@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"strings"
+)
+
+type OfficeSiteService struct {
+	repository *repositories.OfficeSiteRepository
+}
+
+// NewOfficeSiteService crea una nuova istanza del servizio
+func NewOfficeSiteService() *OfficeSiteService {
+	return &OfficeSiteService{
+		repository: repositories.NewOfficeSiteRepository(),
+	}
+}
+
+// GetOfficeSites restituisce tutte le sedi, anche quelle disattivate
+func (s *OfficeSiteService) GetOfficeSites() ([]models.OfficeSite, error) {
+	sites, err := s.repository.GetAll(false)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching office sites: %w", err)
+	}
+	return sites, nil
+}
+
+// CreateOfficeSite crea una nuova sede con il suo geofence
+func (s *OfficeSiteService) CreateOfficeSite(request *models.SaveOfficeSiteRequest) (*models.OfficeSite, error) {
+	site := &models.OfficeSite{IsActive: true}
+	if err := s.apply(site, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.Create(site); err != nil {
+		return nil, fmt.Errorf("error creating office site: %w", err)
+	}
+
+	log.Printf("Office site %d (%s) created with %dm radius and %s policy", site.ID, site.Name, site.RadiusMeters, site.Policy)
+	return site, nil
+}
+
+// UpdateOfficeSite modifica una sede: le timbrature giá registrate mantengono l'esito del controllo
+func (s *OfficeSiteService) UpdateOfficeSite(id int, request *models.SaveOfficeSiteRequest) (*models.OfficeSite, error) {
+	site, err := s.repository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching office site: %w", err)
+	}
+	if site == nil {
+		return nil, errors.New("office site not found")
+	}
+
+	if err := s.apply(site, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.Update(site); err != nil {
+		return nil, fmt.Errorf("error updating office site: %w", err)
+	}
+
+	log.Printf("Office site %d (%s) updated", site.ID, site.Name)
+	return site, nil
+}
+
+// apply valida la request e la copia sulla sede
+func (s *OfficeSiteService) apply(site *models.OfficeSite, request *models.SaveOfficeSiteRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if err := validatePosition(request.Latitude, request.Longitude, nil); err != nil {
+		return err
+	}
+	if request.RadiusMeters <= 0 {
+		return errors.New("radius must be greater than 0")
+	}
+	if !isValidGeofencePolicy(request.Policy) {
+		return errors.New("invalid geofence policy")
+	}
+
+	exists, err := s.repository.ExistsByName(name, site.ID)
+	if err != nil {
+		return fmt.Errorf("error checking office site name: %w", err)
+	}
+	if exists {
+		return errors.New("office site name already exists")
+	}
+
+	site.Name = name
+	site.Latitude = *request.Latitude
+	site.Longitude = *request.Longitude
+	site.RadiusMeters = request.RadiusMeters
+	site.Policy = request.Policy
+	if request.IsActive != nil {
+		site.IsActive = *request.IsActive
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcLoginTTL tempo massimo tra l'avvio del login SSO e il ritorno dall'identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcProviders client dell'identity provider condiviso tra le richieste, cosí discovery
+// e chiavi pubbliche restano in cache (ricreato se cambia l'issuer configurato)
+var oidcProviders = &oidcProviderCache{}
+
+type oidcProviderCache struct {
+	mu       sync.Mutex
+	provider *utils.OIDCProvider
+}
+
+func (c *oidcProviderCache) get(issuer string) *utils.OIDCProvider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil || c.provider.Issuer() != issuer {
+		c.provider = utils.NewOIDCProvider(issuer)
+	}
+	return c.provider
+}
+
+type OIDCService struct {
+	oidcRepository     *repositories.OIDCRepository
+	authRepository     *repositories.AuthRepository
+	userRoleRepository *repositories.UserRoleRepository
+	authService        *AuthService
+}
+
+// NewOIDCService crea una nuova istanza del servizio
+func NewOIDCService(authService *AuthService) *OIDCService {
+	return &OIDCService{
+		oidcRepository:     repositories.NewOIDCRepository(),
+		authRepository:     repositories.NewAuthRepository(),
+		userRoleRepository: repositories.NewUserRoleRepository(),
+		authService:        authService,
+	}
+}
+
+// BeginLogin avvia il login SSO: genera state, nonce e code_verifier PKCE e restituisce
+// l'URL dell'identity provider su cui reindirizzare il browser
+func (s *OIDCService) BeginLogin() (*models.OIDCLoginStartResponse, error) {
+	if !config.OIDCEnabled() {
+		return nil, errors.New("sso is not configured")
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating state: %w", err)
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	// 64 caratteri esadecimali, nel range 43-128 richiesto da RFC 7636
+	codeVerifier, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating code verifier: %w", err)
+	}
+
+	provider := oidcProviders.get(config.OIDCIssuer())
+	authorizationURL, err := provider.AuthorizationURL(
+		config.OIDCClientID(),
+		config.OIDCRedirectURL(),
+		config.OIDCScopes(),
+		state,
+		nonce,
+		utils.PKCEChallenge(codeVerifier),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error building authorization url: %w", err)
+	}
+
+	loginRequest := &models.OIDCLoginRequest{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}
+	if err := s.oidcRepository.CreateLoginRequest(loginRequest); err != nil {
+		return nil, fmt.Errorf("error saving sso login request: %w", err)
+	}
+
+	// Pulizia opportunistica dei login non conclusi
+	if deleted, err := s.oidcRepository.DeleteExpiredLoginRequests(); err != nil {
+		log.Printf("Error cleaning expired sso login requests: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Removed %d expired sso login requests", deleted)
+	}
+
+	return &models.OIDCLoginStartResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+		ExpiresAt:        loginRequest.ExpiresAt,
+	}, nil
+}
+
+// CompleteLogin conclude il login SSO: verifica lo state, scambia il codice, valida l'ID token,
+// risolve (o crea) l'utente e restituisce i normali token Merendels.
+// Se l'utente ha la 2FA attiva o il ruolo la impone restituisce lo stesso challenge del login con password:
+// un account del provider con la stessa email non basta per un ruolo protetto dalla 2FA
+func (s *OIDCService) CompleteLogin(request *models.OIDCCallbackRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	if !config.OIDCEnabled() {
+		return nil, errors.New("sso is not configured")
+	}
+
+	// 1. State monouso e non scaduto (protezione CSRF)
+	loginRequest, err := s.oidcRepository.ConsumeLoginRequest(utils.HashToken(request.State))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sso login request: %w", err)
+	}
+	if loginRequest == nil {
+		return nil, errors.New("invalid or expired sso state")
+	}
+
+	// 2. Scambio del codice (con code_verifier PKCE) e verifica dell'ID token
+	issuer := config.OIDCIssuer()
+	provider := oidcProviders.get(issuer)
+
+	rawIDToken, err := provider.ExchangeCode(config.OIDCClientID(), config.OIDCClientSecret(), config.OIDCRedirectURL(), request.Code, loginRequest.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO code exchange failed: %v", err)
+		return nil, errors.New("sso authentication failed")
+	}
+
+	claims, err := provider.VerifyIDToken(rawIDToken, config.OIDCClientID(), loginRequest.Nonce)
+	if err != nil {
+		log.Printf("SSO id token rejected: %v", err)
+		return nil, errors.New("sso authentication failed")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	// 3. Utente collegato all'identitá
+	userID, err := s.resolveUser(issuer, claims.Subject, email, bool(claims.EmailVerified), claims.Name)
+	if err != nil {
+		if err.Error() == "sso user not provisioned" {
+			s.authService.recordAttempt(nil, email, client, models.LoginFailure)
+			log.Printf("SSO login for unprovisioned subject %s (%s)", claims.Subject, email)
+		}
+		return nil, err
+	}
+
+	loginData, err := s.authRepository.GetUserForToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		return nil, errors.New("sso user not provisioned")
+	}
+
+	if loginData.Status != models.UserActive {
+		s.authService.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginFailure)
+		log.Printf("SSO login attempt for inactive user ID: %d", loginData.UserID)
+		return nil, errors.New("account is disabled")
+	}
+
+	// 4. Secondo fattore locale, come per il login con password
+	if loginData.MFAEnabled || loginData.MFARequired {
+		s.authService.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginMFAPending)
+		return s.authService.mfaChallenge(loginData)
+	}
+
+	s.authService.recordAttempt(&loginData.UserID, loginData.Email, client, models.LoginSuccess)
+
+	// 5. Token di accesso + refresh token, come per il login con password
+	response, err := s.authService.issueTokens(loginData, "", request.DeviceName, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successful SSO login for user ID: %d (%s)", loginData.UserID, loginData.Email)
+	return response, nil
+}
+
+// resolveUser trova l'utente per l'identitá dell'identity provider. In ordine:
+// identitá giá collegata, utente esistente con la stessa email (solo se verificata dal provider),
+// creazione just-in-time se abilitata
+func (s *OIDCService) resolveUser(issuer, subject, email string, emailVerified bool, name string) (int, error) {
+	var identityEmail *string
+	if email != "" {
+		identityEmail = &email
+	}
+
+	identity, err := s.oidcRepository.GetIdentity(issuer, subject)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving identity: %w", err)
+	}
+	if identity != nil {
+		if err := s.oidcRepository.TouchIdentity(identity.ID, identityEmail); err != nil {
+			log.Printf("Error updating identity %d: %v", identity.ID, err)
+		}
+		return identity.UserID, nil
+	}
+
+	// Un'email non verificata potrebbe essere stata scelta da chiunque: non la usiamo per collegare account
+	if email == "" || !emailVerified {
+		return 0, errors.New("sso user not provisioned")
+	}
+
+	existingUserID, err := s.oidcRepository.GetUserIDByEmail(email)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving user by email: %w", err)
+	}
+	if existingUserID != nil {
+		identity := &models.UserIdentity{UserID: *existingUserID, Issuer: issuer, Subject: subject, Email: identityEmail}
+		if err := s.oidcRepository.CreateIdentity(identity); err != nil {
+			return 0, fmt.Errorf("error linking identity: %w", err)
+		}
+		log.Printf("SSO identity %s linked to existing user ID %d", subject, *existingUserID)
+		return *existingUserID, nil
+	}
+
+	if !config.OIDCJITProvisioning() {
+		return 0, errors.New("sso user not provisioned")
+	}
+
+	return s.provisionUser(issuer, subject, email, name)
+}
+
+// provisionUser crea l'utente al primo login SSO con il ruolo di default
+func (s *OIDCService) provisionUser(issuer, subject, email, name string) (int, error) {
+	user := &models.User{
+		Name:  strings.TrimSpace(name),
+		Email: email,
+	}
+	if user.Name == "" {
+		user.Name = email
+	}
+
+	if roleID := config.OIDCDefaultRoleID(); roleID > 0 {
+		role, err := s.userRoleRepository.GetByID(roleID)
+		if err != nil {
+			return 0, fmt.Errorf("error validating default role: %w", err)
+		}
+		if role == nil {
+			return 0, errors.New("invalid sso default role")
+		}
+		user.RoleID = &roleID
+	}
+
+	identity := &models.UserIdentity{Issuer: issuer, Subject: subject, Email: &email}
+	if err := s.oidcRepository.CreateUserWithIdentity(user, identity); err != nil {
+		return 0, fmt.Errorf("error provisioning sso user: %w", err)
+	}
+
+	log.Printf("SSO user provisioned: ID %d (%s)", user.ID, user.Email)
+	return user.ID, nil
+}
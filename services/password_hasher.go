@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Prefisso degli hash argon2id in formato PHC ($argon2id$v=19$m=...,t=...,p=...$salt$hash).
+// Tutto il resto é considerato formato legacy: bcrypt di password + salt esadecimale
+const argon2idPrefix = "$argon2id$"
+
+// Argon2Params parametri di costo di argon2id
+type Argon2Params struct {
+	MemoryKB    uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordHasher calcola e verifica gli hash delle password.
+// I nuovi hash sono argon2id (salt incluso nella stringa), quelli bcrypt restano verificabili
+// e vengono aggiornati al primo login riuscito
+type PasswordHasher struct {
+	params Argon2Params
+}
+
+// NewPasswordHasherFromConfig crea l'hasher con i parametri delle variabili d'ambiente
+// (default: raccomandazioni OWASP per argon2id)
+func NewPasswordHasherFromConfig() *PasswordHasher {
+	return &PasswordHasher{params: Argon2Params{
+		MemoryKB:    uint32(config.GetEnvInt("ARGON2_MEMORY_KB", 64*1024)),
+		Iterations:  uint32(config.GetEnvInt("ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(config.GetEnvInt("ARGON2_PARALLELISM", 2)),
+		SaltLength:  16,
+		KeyLength:   32,
+	}}
+}
+
+// Hash calcola l'hash argon2id della password. Il salt é incluso nell'hash,
+// quindi il valore per la colonna salt é sempre vuoto
+func (h *PasswordHasher) Hash(password string) (string, string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.MemoryKB, h.params.Parallelism, h.params.KeyLength)
+
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.params.MemoryKB,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return encoded, "", nil
+}
+
+// Verify confronta la password con la credenziale salvata, in qualunque formato
+func (h *PasswordHasher) Verify(password string, credential models.AuthCredential) bool {
+	if strings.HasPrefix(credential.PasswordHash, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2idHash(credential.PasswordHash)
+		if err != nil {
+			return false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKB, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+
+	// Formato legacy: bcrypt di password + salt esadecimale
+	passwordWithSalt := password + credential.Salt
+	err := bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(passwordWithSalt))
+	return err == nil
+}
+
+// NeedsRehash indica se la credenziale va ricalcolata: hash legacy o parametri argon2id non piú attuali
+func (h *PasswordHasher) NeedsRehash(credential models.AuthCredential) bool {
+	if !strings.HasPrefix(credential.PasswordHash, argon2idPrefix) {
+		return true
+	}
+
+	params, _, _, err := decodeArgon2idHash(credential.PasswordHash)
+	if err != nil {
+		return true
+	}
+
+	return params.MemoryKB != h.params.MemoryKB ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.KeyLength != h.params.KeyLength
+}
+
+// decodeArgon2idHash interpreta un hash argon2id in formato PHC
+func decodeArgon2idHash(encoded string) (*Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	params := &Argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKB, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if params.MemoryKB == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// PasswordPolicyError errore di validazione della password, il messaggio é mostrabile all'utente
+type PasswordPolicyError struct {
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+// PasswordRule singola regola della policy. previousPasswords contiene le ultime
+// credenziali dell'utente (vuoto in fase di registrazione)
+type PasswordRule interface {
+	Validate(password string, previousPasswords []models.AuthCredential) error
+}
+
+// PasswordPolicy applica in ordine un insieme di regole
+type PasswordPolicy struct {
+	rules []PasswordRule
+}
+
+// NewPasswordPolicy crea una policy con le regole indicate
+func NewPasswordPolicy(rules ...PasswordRule) *PasswordPolicy {
+	return &PasswordPolicy{rules: rules}
+}
+
+// NewPasswordPolicyFromConfig crea la policy a partire dalle variabili d'ambiente
+func NewPasswordPolicyFromConfig(verify func(password string, credential models.AuthCredential) bool) *PasswordPolicy {
+	rules := []PasswordRule{
+		MinLengthRule{MinLength: config.GetEnvInt("PASSWORD_MIN_LENGTH", 8)},
+		CharacterClassesRule{
+			RequireUpper:  config.GetEnvBool("PASSWORD_REQUIRE_UPPERCASE", true),
+			RequireLower:  config.GetEnvBool("PASSWORD_REQUIRE_LOWERCASE", true),
+			RequireDigit:  config.GetEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol: config.GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		},
+	}
+
+	if path := config.GetEnv("PASSWORD_BREACHED_LIST_FILE", ""); path != "" {
+		rules = append(rules, BreachedPasswordRule{Passwords: loadBreachedPasswords(path)})
+	}
+
+	rules = append(rules, HistoryRule{Verify: verify})
+
+	return NewPasswordPolicy(rules...)
+}
+
+// PasswordHistorySize numero di password precedenti (compresa l'attuale) che non possono essere riusate
+func PasswordHistorySize() int {
+	return config.GetEnvInt("PASSWORD_HISTORY_SIZE", 5)
+}
+
+// Validate verifica la password rispetto a tutte le regole
+func (p *PasswordPolicy) Validate(password string, previousPasswords []models.AuthCredential) error {
+	for _, rule := range p.rules {
+		if err := rule.Validate(password, previousPasswords); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinLengthRule lunghezza minima in caratteri
+type MinLengthRule struct {
+	MinLength int
+}
+
+func (r MinLengthRule) Validate(password string, _ []models.AuthCredential) error {
+	if len([]rune(password)) < r.MinLength {
+		return &PasswordPolicyError{Message: fmt.Sprintf("password must be at least %d characters", r.MinLength)}
+	}
+	return nil
+}
+
+// CharacterClassesRule classi di caratteri obbligatorie
+type CharacterClassesRule struct {
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+func (r CharacterClassesRule) Validate(password string, _ []models.AuthCredential) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSymbol = true
+		}
+	}
+
+	if r.RequireUpper && !hasUpper {
+		return &PasswordPolicyError{Message: "password must contain an uppercase letter"}
+	}
+	if r.RequireLower && !hasLower {
+		return &PasswordPolicyError{Message: "password must contain a lowercase letter"}
+	}
+	if r.RequireDigit && !hasDigit {
+		return &PasswordPolicyError{Message: "password must contain a digit"}
+	}
+	if r.RequireSymbol && !hasSymbol {
+		return &PasswordPolicyError{Message: "password must contain a symbol"}
+	}
+	return nil
+}
+
+// BreachedPasswordRule rifiuta password presenti in una lista di password compromesse
+type BreachedPasswordRule struct {
+	Passwords map[string]struct{}
+}
+
+func (r BreachedPasswordRule) Validate(password string, _ []models.AuthCredential) error {
+	if _, found := r.Passwords[strings.ToLower(password)]; found {
+		return &PasswordPolicyError{Message: "password appears in a list of breached passwords"}
+	}
+	return nil
+}
+
+// HistoryRule impedisce il riuso delle ultime password dell'utente
+type HistoryRule struct {
+	Verify func(password string, credential models.AuthCredential) bool
+}
+
+func (r HistoryRule) Validate(password string, previousPasswords []models.AuthCredential) error {
+	for _, previous := range previousPasswords {
+		if r.Verify(password, previous) {
+			return &PasswordPolicyError{Message: "password was used recently, choose a different one"}
+		}
+	}
+	return nil
+}
+
+// Le liste di password compromesse possono essere grandi: le carico una sola volta per file
+var (
+	breachedListsMu sync.Mutex
+	breachedLists   = make(map[string]map[string]struct{})
+)
+
+// loadBreachedPasswords legge un file con una password per riga (confronto case-insensitive)
+func loadBreachedPasswords(path string) map[string]struct{} {
+	breachedListsMu.Lock()
+	defer breachedListsMu.Unlock()
+
+	if passwords, loaded := breachedLists[path]; loaded {
+		return passwords
+	}
+
+	passwords := make(map[string]struct{})
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening breached password list %s: %v", path, err)
+		breachedLists[path] = passwords
+		return passwords
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading breached password list %s: %v", path, err)
+	}
+
+	log.Printf("Loaded %d breached passwords from %s", len(passwords), path)
+	breachedLists[path] = passwords
+	return passwords
+}
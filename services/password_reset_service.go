@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type PasswordResetService struct {
+	resetRepository *repositories.PasswordResetRepository
+	authRepository  *repositories.AuthRepository
+	authService     *AuthService
+	mailer          utils.Mailer
+}
+
+// NewPasswordResetService crea una nuova istanza del servizio
+func NewPasswordResetService(authService *AuthService, mailer utils.Mailer) *PasswordResetService {
+	return &PasswordResetService{
+		resetRepository: repositories.NewPasswordResetRepository(),
+		authRepository:  repositories.NewAuthRepository(),
+		authService:     authService,
+		mailer:          mailer,
+	}
+}
+
+// RequestReset genera un token monouso e lo invia via email.
+// Non restituisce mai errori di business per non rivelare quali email sono registrate
+func (s *PasswordResetService) RequestReset(request *models.PasswordResetRequest, ipAddress string) error {
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if email == "" {
+		return errors.New("email is required")
+	}
+
+	loginData, err := s.authRepository.GetUserForLogin(email)
+	if err != nil {
+		return fmt.Errorf("error retrieving user data: %w", err)
+	}
+	if loginData == nil {
+		log.Printf("Password reset requested for non-existent email: %s", email)
+		return nil
+	}
+
+	// Token ed email in background: la risposta arriva nello stesso tempo che l'email sia registrata o no
+	go s.sendResetLink(loginData, ipAddress)
+	return nil
+}
+
+// sendResetLink genera il token e invia il link; gli errori vengono solo loggati
+func (s *PasswordResetService) sendResetLink(loginData *repositories.LoginData, ipAddress string) {
+	// Limite semplice contro l'invio ripetuto di email allo stesso utente
+	recent, err := s.resetRepository.CountRecentForUser(loginData.UserID, 1)
+	if err != nil {
+		log.Printf("Error checking recent reset requests for user ID %d: %v", loginData.UserID, err)
+		return
+	}
+	if recent > 0 {
+		log.Printf("Password reset for user ID %d throttled", loginData.UserID)
+		return
+	}
+
+	// Un solo token valido alla volta
+	if err := s.resetRepository.InvalidateForUser(loginData.UserID); err != nil {
+		log.Printf("Error invalidating previous reset tokens for user ID %d: %v", loginData.UserID, err)
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Error generating reset token for user ID %d: %v", loginData.UserID, err)
+		return
+	}
+
+	ttl := config.PasswordResetTokenTTL()
+	err = s.resetRepository.Create(&models.PasswordResetToken{
+		UserID:    loginData.UserID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		log.Printf("Error saving reset token for user ID %d: %v", loginData.UserID, err)
+		return
+	}
+
+	s.recordEvent(loginData.UserID, models.EventPasswordResetRequested, ipAddress)
+
+	resetURL := config.PasswordResetURL()
+	link := resetURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Ciao %s,\n\nabbiamo ricevuto una richiesta di reset della password per il tuo account Merendels.\n"+
+			"Per scegliere una nuova password apri questo link (valido %d minuti, utilizzabile una sola volta):\n\n%s\n\n"+
+			"Se non hai richiesto il reset puoi ignorare questa email.\n",
+		loginData.Name, int(ttl.Minutes()), link)
+
+	if err := s.mailer.Send(loginData.Email, "Reset password Merendels", body); err != nil {
+		log.Printf("Error sending password reset email to user ID %d: %v", loginData.UserID, err)
+	}
+
+	log.Printf("Password reset requested for user ID %d", loginData.UserID)
+}
+
+// ConfirmReset imposta la nuova password consumando il token e chiude tutte le sessioni
+func (s *PasswordResetService) ConfirmReset(request *models.PasswordResetConfirmRequest, ipAddress string) error {
+	if request.Token == "" || request.NewPassword == "" {
+		return errors.New("token and new password are required")
+	}
+
+	resetToken, err := s.resetRepository.GetValidByHash(utils.HashToken(request.Token))
+	if err != nil {
+		return fmt.Errorf("error retrieving reset token: %w", err)
+	}
+	if resetToken == nil {
+		return errors.New("invalid or expired reset token")
+	}
+
+	// Password policy, compreso il controllo sulle ultime password usate
+	if err := s.authService.validateNewPassword(resetToken.UserID, request.NewPassword); err != nil {
+		return err
+	}
+
+	// Consumo il token prima di cambiare la password (due richieste concorrenti = una sola valida)
+	consumed, err := s.resetRepository.MarkUsed(resetToken.ID)
+	if err != nil {
+		return fmt.Errorf("error consuming reset token: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.authService.setPassword(resetToken.UserID, request.NewPassword); err != nil {
+		return err
+	}
+
+	s.recordEvent(resetToken.UserID, models.EventPasswordResetCompleted, ipAddress)
+
+	log.Printf("Password reset completed for user ID %d", resetToken.UserID)
+	return nil
+}
+
+// recordEvent registra l'evento di sicurezza, un errore non blocca il flusso
+func (s *PasswordResetService) recordEvent(userID int, eventType models.AuthEventType, ipAddress string) {
+	event := &models.AuthEvent{
+		UserID:    &userID,
+		EventType: eventType,
+	}
+	if ipAddress != "" {
+		event.IPAddress = &ipAddress
+	}
+
+	if err := s.authRepository.RecordAuthEvent(event); err != nil {
+		log.Printf("Error recording auth event %s for user ID %d: %v", eventType, userID, err)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rolePermissionCache tiene in memoria i permessi di ogni ruolo, cosí il middleware
+// non interroga il database ad ogni richiesta. Viene svuotata quando la matrice cambia
+type rolePermissionCache struct {
+	mu      sync.RWMutex
+	entries map[int]cachedRolePermissions
+}
+
+type cachedRolePermissions struct {
+	permissions map[string]bool
+	expiresAt   time.Time
+}
+
+// Cache condivisa tra tutte le istanze del servizio (una per gruppo di rotte)
+var rolePermissions = &rolePermissionCache{entries: make(map[int]cachedRolePermissions)}
+
+func (c *rolePermissionCache) get(roleID int) (map[string]bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.entries[roleID]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.permissions, true
+}
+
+func (c *rolePermissionCache) set(roleID int, permissions map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[roleID] = cachedRolePermissions{
+		permissions: permissions,
+		expiresAt:   time.Now().Add(config.PermissionCacheTTL()),
+	}
+}
+
+func (c *rolePermissionCache) invalidate(roleID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, roleID)
+}
+
+type PermissionService struct {
+	repository         *repositories.PermissionRepository
+	userRoleRepository *repositories.UserRoleRepository
+}
+
+// NewPermissionService crea una nuova istanza del servizio
+func NewPermissionService() *PermissionService {
+	return &PermissionService{
+		repository:         repositories.NewPermissionRepository(),
+		userRoleRepository: repositories.NewUserRoleRepository(),
+	}
+}
+
+// HasPermission verifica se il ruolo (preso dai claims del token) possiede il permesso
+func (s *PermissionService) HasPermission(roleID *int, permission string) (bool, error) {
+	if roleID == nil || permission == "" {
+		return false, nil
+	}
+
+	permissions, err := s.rolePermissionSet(*roleID)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// GetPermissionsForRole restituisce i permessi del ruolo (vuoto se l'utente non ha un ruolo)
+func (s *PermissionService) GetPermissionsForRole(roleID *int) ([]string, error) {
+	if roleID == nil {
+		return []string{}, nil
+	}
+
+	permissions, err := s.rolePermissionSet(*roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(permissions))
+	for code := range permissions {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// CoversRole verifica che il ruolo holderRoleID possieda tutti i permessi del ruolo targetRoleID
+// (senza ruolo non si hanno permessi). Impedisce di ottenere permessi che non si hanno giá
+func (s *PermissionService) CoversRole(holderRoleID, targetRoleID *int) (bool, error) {
+	if targetRoleID == nil {
+		return true, nil
+	}
+
+	required, err := s.rolePermissionSet(*targetRoleID)
+	if err != nil {
+		return false, err
+	}
+	codes := make([]string, 0, len(required))
+	for code := range required {
+		codes = append(codes, code)
+	}
+	return s.HasAllPermissions(holderRoleID, codes)
+}
+
+// HasAllPermissions verifica che il ruolo possieda tutti i permessi indicati
+func (s *PermissionService) HasAllPermissions(roleID *int, permissions []string) (bool, error) {
+	if len(permissions) == 0 {
+		return true, nil
+	}
+	if roleID == nil {
+		return false, nil
+	}
+
+	held, err := s.rolePermissionSet(*roleID)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !held[permission] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// outranks indica se il livello a é superiore al livello b (hierarchy_level piú basso = ruolo piú alto,
+// senza ruolo si é all'ultimo livello)
+func outranks(a, b *int) bool {
+	if a == nil {
+		return false
+	}
+	return b == nil || *a < *b
+}
+
+// GetAllPermissions restituisce il catalogo dei permessi assegnabili
+func (s *PermissionService) GetAllPermissions() ([]models.Permission, error) {
+	return s.repository.GetAll()
+}
+
+// GetRolePermissions restituisce i permessi di un ruolo per l'API di amministrazione
+func (s *PermissionService) GetRolePermissions(roleID int) (*models.RolePermissions, error) {
+	if err := s.checkRoleExists(roleID); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.repository.GetByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RolePermissions{RoleID: roleID, Permissions: codes}, nil
+}
+
+// UpdateRolePermissions sostituisce i permessi del ruolo. L'amministratore non puó togliere
+// roles:manage al proprio ruolo, altrimenti nessuno potrebbe piú modificare la matrice
+func (s *PermissionService) UpdateRolePermissions(roleID int, adminRoleID *int, request *models.UpdateRolePermissionsRequest) (*models.RolePermissions, error) {
+	if err := s.checkRoleExists(roleID); err != nil {
+		return nil, err
+	}
+
+	catalog, err := s.repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(catalog))
+	for _, permission := range catalog {
+		known[permission.Code] = true
+	}
+
+	// Rimuovo i duplicati e verifico che ogni codice esista
+	seen := make(map[string]bool, len(request.Permissions))
+	codes := make([]string, 0, len(request.Permissions))
+	for _, code := range request.Permissions {
+		if !known[code] {
+			return nil, errors.New("unknown permission")
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	if adminRoleID != nil && *adminRoleID == roleID && !seen[models.PermRolesManage] {
+		return nil, errors.New("cannot remove roles:manage from your own role")
+	}
+
+	if err := s.repository.ReplaceForRole(roleID, codes); err != nil {
+		return nil, fmt.Errorf("error updating role permissions: %w", err)
+	}
+	rolePermissions.invalidate(roleID)
+
+	log.Printf("Permissions of role ID %d updated: %v", roleID, codes)
+	return &models.RolePermissions{RoleID: roleID, Permissions: codes}, nil
+}
+
+// rolePermissionSet legge i permessi del ruolo dalla cache o dal database
+func (s *PermissionService) rolePermissionSet(roleID int) (map[string]bool, error) {
+	if permissions, ok := rolePermissions.get(roleID); ok {
+		return permissions, nil
+	}
+
+	codes, err := s.repository.GetByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		permissions[code] = true
+	}
+	rolePermissions.set(roleID, permissions)
+
+	return permissions, nil
+}
+
+// checkRoleExists restituisce "role not found" se il ruolo non esiste
+func (s *PermissionService) checkRoleExists(roleID int) error {
+	if roleID <= 0 {
+		return errors.New("invalid role ID")
+	}
+
+	role, err := s.userRoleRepository.GetByID(roleID)
+	if err != nil {
+		return fmt.Errorf("error fetching role: %w", err)
+	}
+	if role == nil {
+		return errors.New("role not found")
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"strings"
+	"time"
+)
+
+type PrivacyService struct {
+	privacyRepository      *repositories.PrivacyRepository
+	userRepository         *repositories.UserRepository
+	timbratureRepository   *repositories.TimbratureRepository
+	requestRepository      *repositories.RequestRepository
+	leaveBalanceRepository *repositories.LeaveBalanceRepository
+}
+
+// NewPrivacyService crea una nuova istanza del servizio
+func NewPrivacyService() *PrivacyService {
+	return &PrivacyService{
+		privacyRepository:      repositories.NewPrivacyRepository(),
+		userRepository:         repositories.NewUserRepository(),
+		timbratureRepository:   repositories.NewTimbratureRepository(),
+		requestRepository:      repositories.NewRequestRepository(),
+		leaveBalanceRepository: repositories.NewLeaveBalanceRepository(),
+	}
+}
+
+// ExportUserData raccoglie tutti i dati personali dell'utente e registra la richiesta evasa
+func (s *PrivacyService) ExportUserData(userID, adminID int) (*models.PersonalDataExport, error) {
+	user, err := s.userRepository.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	export := &models.PersonalDataExport{
+		GeneratedAt: time.Now(),
+		Profile:     user,
+	}
+
+	if export.Timbrature, err = s.privacyRepository.GetTimbrature(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving timbrature: %w", err)
+	}
+	if export.Corrections, err = s.privacyRepository.GetTimbratureCorrections(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving timbrature corrections: %w", err)
+	}
+	if export.Requests, err = s.privacyRepository.GetRequests(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving requests: %w", err)
+	}
+	if export.Approvals, err = s.privacyRepository.GetApprovals(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving approvals: %w", err)
+	}
+	if export.LeaveBalance, err = s.leaveBalanceRepository.GetByUserID(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving leave balance: %w", err)
+	}
+	if export.Settlement, err = s.leaveBalanceRepository.GetSettlementByUserID(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving settlement: %w", err)
+	}
+	if export.LoginAttempts, err = s.privacyRepository.GetLoginAttempts(userID, user.Email); err != nil {
+		return nil, fmt.Errorf("error retrieving login attempts: %w", err)
+	}
+	if export.AuthEvents, err = s.privacyRepository.GetAuthEvents(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving auth events: %w", err)
+	}
+	if export.Sessions, err = s.privacyRepository.GetSessions(userID); err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %w", err)
+	}
+
+	// Senza registro la richiesta non risulta evasa: meglio fallire che consegnare dati non tracciati
+	request := &models.PrivacyRequest{
+		UserID:      userID,
+		RequestType: models.PrivacyExport,
+		PerformedBy: adminID,
+	}
+	if err := s.privacyRepository.RecordRequest(request); err != nil {
+		return nil, fmt.Errorf("error recording privacy request: %w", err)
+	}
+
+	log.Printf("Personal data of user ID %d exported by user ID %d", userID, adminID)
+	return export, nil
+}
+
+// BuildExportArchive crea l'archivio ZIP dell'esportazione, con un file JSON per ogni categoria di dati
+func (s *PrivacyService) BuildExportArchive(export *models.PersonalDataExport) ([]byte, error) {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"timbrature.json", export.Timbrature},
+		{"timbrature_corrections.json", export.Corrections},
+		{"requests.json", export.Requests},
+		{"approvals.json", export.Approvals},
+		{"leave_balance.json", map[string]interface{}{"leave_balance": export.LeaveBalance, "settlement": export.Settlement}},
+		{"login_attempts.json", export.LoginAttempts},
+		{"auth_events.json", export.AuthEvents},
+		{"sessions.json", export.Sessions},
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	for _, file := range files {
+		header := &zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.GeneratedAt,
+		}
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", file.name, err)
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", file.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("error closing archive: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// EraseUser cancella l'utente per pseudonimizzazione (diritto all'oblio, art. 17 GDPR).
+// Solo per utenti cessati: timbrature, richieste e saldi restano perché obblighi di legge,
+// ma non sono piú riconducibili alla persona
+func (s *PrivacyService) EraseUser(userID, adminID int, request *models.EraseUserRequest) (*models.ErasureResult, error) {
+	if userID == adminID {
+		return nil, errors.New("cannot erase your own account")
+	}
+
+	user, err := s.userRepository.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	erased, err := s.privacyRepository.IsErased(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking erasure: %w", err)
+	}
+	if erased {
+		return nil, errors.New("user is already erased")
+	}
+
+	// Prima l'offboarding (chiusura timbrature, liquidazione), poi la cancellazione
+	if user.Status != models.UserTerminated {
+		return nil, errors.New("user must be terminated before erasure")
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(request.ConfirmEmail), user.Email) {
+		return nil, errors.New("confirmation email does not match")
+	}
+
+	retainedTimbrature, err := s.timbratureRepository.CountByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error counting timbrature: %w", err)
+	}
+	retainedRequests, err := s.requestRepository.CountByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error counting requests: %w", err)
+	}
+
+	data := &repositories.ErasureData{
+		UserID:        userID,
+		Email:         user.Email,
+		PseudonymName: fmt.Sprintf("Utente cancellato %d", userID),
+		PseudonymMail: fmt.Sprintf("erased-%d@erased.invalid", userID),
+		ErasedAt:      time.Now(),
+		Request: &models.PrivacyRequest{
+			UserID:      userID,
+			RequestType: models.PrivacyErasure,
+			PerformedBy: adminID,
+		},
+	}
+	if request.Reason != nil && strings.TrimSpace(*request.Reason) != "" {
+		reason := strings.TrimSpace(*request.Reason)
+		data.Request.Reason = &reason
+	}
+
+	done, deletedAttempts, err := s.privacyRepository.Erase(data)
+	if err != nil {
+		return nil, fmt.Errorf("error erasing user: %w", err)
+	}
+	securityVersions.invalidate(userID)
+	if !done {
+		return nil, errors.New("user is already erased")
+	}
+
+	log.Printf("User ID %d erased by user ID %d (%d timbrature and %d requests retained)",
+		userID, adminID, retainedTimbrature, retainedRequests)
+
+	return &models.ErasureResult{
+		UserID:               userID,
+		PseudonymName:        data.PseudonymName,
+		ErasedAt:             data.ErasedAt,
+		RetainedTimbrature:   retainedTimbrature,
+		RetainedRequests:     retainedRequests,
+		DeletedLoginAttempts: deletedAttempts,
+	}, nil
+}
+
+// GetPrivacyRequests restituisce il registro delle richieste evase
+func (s *PrivacyService) GetPrivacyRequests(limit, offset int) ([]models.PrivacyRequest, int, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	requests, total, err := s.privacyRepository.GetPrivacyRequests(limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving privacy requests: %w", err)
+	}
+
+	return requests, total, nil
+}
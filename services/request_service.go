@@ -13,6 +13,7 @@ type RequestService struct {
 	requestRepository *repositories.RequestRepository
 	approvalRepository *repositories.ApprovalRepository
 	leaveBalanceRepository *repositories.LeaveBalanceRepository
+	teamScopeService *TeamScopeService
 }
 
 // NewRequestService crea una nuova istanza del servizio
@@ -21,6 +22,7 @@ func NewRequestService() *RequestService {
 		requestRepository: repositories.NewRequestRepository(),
 		approvalRepository: repositories.NewApprovalRepository(),
 		leaveBalanceRepository: repositories.NewLeaveBalanceRepository(),
+		teamScopeService: NewTeamScopeService(),
 	}
 }
 
@@ -40,7 +42,7 @@ func (s *RequestService) CreateRequest(userID int, request *models.CreateRequest
 	}
 
 	// Calcola i giorni richiesti
-	days := s.calculateWorkingDays(request.StartDate, request.EndDate)
+	days := calculateWorkingDays(request.StartDate, request.EndDate)
 	if days <= 0 {
 		return nil, errors.New("la richiesta deve coprire almeno un giorno lavorativo")
 	}
@@ -114,8 +116,8 @@ func (s *RequestService) CreateRequest(userID int, request *models.CreateRequest
 	return createdRequest, nil
 }
 
-// GetAllRequests recupera tutte le richieste con paginazione
-func (s *RequestService) GetAllRequests(limit, offset int) ([]models.Request, error) {
+// GetAllRequests recupera le richieste visibili all'utente (team o tutta l'azienda) con paginazione
+func (s *RequestService) GetAllRequests(viewer Viewer, limit, offset int) ([]models.Request, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -123,7 +125,12 @@ func (s *RequestService) GetAllRequests(limit, offset int) ([]models.Request, er
 		offset = 0
 	}
 
-	requests, err := s.requestRepository.GetAll(limit, offset)
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.requestRepository.GetAll(limit, offset, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle richieste: %w", err)
 	}
@@ -131,8 +138,8 @@ func (s *RequestService) GetAllRequests(limit, offset int) ([]models.Request, er
 	return requests, nil
 }
 
-// GetRequestByID recupera una richiesta specifica
-func (s *RequestService) GetRequestByID(id int) (*models.Request, error) {
+// GetRequestByID recupera una richiesta specifica, se propria o di un utente nello scope
+func (s *RequestService) GetRequestByID(id int, viewer Viewer) (*models.Request, error) {
 	if id <= 0 {
 		return nil, errors.New("ID richiesta non valido")
 	}
@@ -146,6 +153,15 @@ func (s *RequestService) GetRequestByID(id int) (*models.Request, error) {
 		return nil, errors.New("richiesta non trovata")
 	}
 
+	// Le richieste fuori dallo scope risultano inesistenti
+	visible, err := s.teamScopeService.CanView(viewer, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("richiesta non trovata")
+	}
+
 	return request, nil
 }
 
@@ -191,13 +207,21 @@ func (s *RequestService) GetUserRequestsWithStatus(userID, limit, offset int) ([
 	return requests, nil
 }
 
-// GetRequestsByDateRange recupera richieste in un range di date
-func (s *RequestService) GetRequestsByDateRange(startDate, endDate time.Time) ([]models.Request, error) {
+// GetRequestsByDateRange recupera le richieste in un range di date: le proprie e quelle degli utenti nello scope
+func (s *RequestService) GetRequestsByDateRange(viewer Viewer, startDate, endDate time.Time) ([]models.Request, error) {
 	if startDate.After(endDate) {
 		return nil, errors.New("data inizio non può essere successiva alla data fine")
 	}
 
-	requests, err := s.requestRepository.GetByDateRange(startDate, endDate)
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.CompanyWide && viewer.APIKey == nil {
+		scope = &models.TeamScope{UserIDs: append(append([]int{}, scope.UserIDs...), viewer.UserID)}
+	}
+
+	requests, err := s.requestRepository.GetByDateRange(startDate, endDate, scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle richieste per range date: %w", err)
 	}
@@ -205,9 +229,14 @@ func (s *RequestService) GetRequestsByDateRange(startDate, endDate time.Time) ([
 	return requests, nil
 }
 
-// GetPendingRequests recupera richieste in attesa di approvazione
-func (s *RequestService) GetPendingRequests() ([]models.Request, error) {
-	requests, err := s.requestRepository.GetPendingRequests()
+// GetPendingRequests recupera le richieste in attesa di approvazione visibili all'utente
+func (s *RequestService) GetPendingRequests(viewer Viewer) ([]models.Request, error) {
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.requestRepository.GetPendingRequests(scope)
 	if err != nil {
 		return nil, fmt.Errorf("errore nel recupero delle richieste in attesa: %w", err)
 	}
@@ -320,7 +349,7 @@ func (s *RequestService) DeleteRequest(id int, userID int) error {
 }
 
 // calculateWorkingDays calcola i giorni lavorativi tra due date (esclusi weekend)
-func (s *RequestService) calculateWorkingDays(startDate, endDate time.Time) int {
+func calculateWorkingDays(startDate, endDate time.Time) int {
 	count := 0
 	current := startDate
 
@@ -387,8 +416,8 @@ func (s *RequestService) checkLeaveBalance(userID int, daysRequested int, reques
 	return true, nil
 }
 
-// GetRequestWithApprovals recupera una richiesta con tutte le sue approvazioni
-func (s *RequestService) GetRequestWithApprovals(id int) (*repositories.RequestWithApprovals, error) {
+// GetRequestWithApprovals recupera una richiesta con tutte le sue approvazioni, se propria o di un utente nello scope
+func (s *RequestService) GetRequestWithApprovals(id int, viewer Viewer) (*repositories.RequestWithApprovals, error) {
 	if id <= 0 {
 		return nil, errors.New("ID richiesta non valido")
 	}
@@ -402,5 +431,13 @@ func (s *RequestService) GetRequestWithApprovals(id int) (*repositories.RequestW
 		return nil, errors.New("richiesta non trovata")
 	}
 
+	visible, err := s.teamScopeService.CanView(viewer, requestWithApprovals.Request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("richiesta non trovata")
+	}
+
 	return requestWithApprovals, nil
 }
\ No newline at end of file
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"merendels-backend/config"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"sync"
+	"time"
+)
+
+// securityVersionCache tiene in memoria la versione di sicurezza di ogni utente per poco tempo,
+// cosí il middleware non interroga il database ad ogni richiesta
+type securityVersionCache struct {
+	mu       sync.RWMutex
+	versions map[int]cachedSecurityVersion
+}
+
+type cachedSecurityVersion struct {
+	version   int
+	expiresAt time.Time
+}
+
+// Cache condivisa tra tutte le istanze del servizio (una per gruppo di rotte)
+var securityVersions = &securityVersionCache{versions: make(map[int]cachedSecurityVersion)}
+
+func (c *securityVersionCache) get(userID int) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.versions[userID]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.version, true
+}
+
+func (c *securityVersionCache) set(userID, version int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Pulizia delle voci scadute ad ogni inserimento
+	now := time.Now()
+	for id, entry := range c.versions {
+		if now.After(entry.expiresAt) {
+			delete(c.versions, id)
+		}
+	}
+
+	c.versions[userID] = cachedSecurityVersion{
+		version:   version,
+		expiresAt: now.Add(config.SecurityVersionCacheTTL()),
+	}
+}
+
+func (c *securityVersionCache) invalidate(userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.versions, userID)
+}
+
+func (c *securityVersionCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions = make(map[int]cachedSecurityVersion)
+}
+
+type SecurityVersionService struct {
+	userRepository *repositories.UserRepository
+}
+
+// NewSecurityVersionService crea una nuova istanza del servizio
+func NewSecurityVersionService() *SecurityVersionService {
+	return &SecurityVersionService{
+		userRepository: repositories.NewUserRepository(),
+	}
+}
+
+// IsCurrent verifica che il token sia stato emesso con la versione di sicurezza attuale dell'utente.
+// Se ruolo, manager, password o stato sono cambiati il client deve fare refresh per avere claims aggiornati.
+// Per i token di impersonificazione vale lo stesso controllo anche per l'operatore
+func (s *SecurityVersionService) IsCurrent(claims *utils.JWTClaims) (bool, error) {
+	current, err := s.isCurrentVersion(claims.UserID, claims.SecurityVersion)
+	if err != nil || !current || claims.Actor == nil {
+		return current, err
+	}
+
+	return s.isCurrentVersion(claims.Actor.UserID, claims.Actor.SecurityVersion)
+}
+
+// isCurrentVersion confronta la versione del token con quella attuale dell'utente (in cache)
+func (s *SecurityVersionService) isCurrentVersion(userID, tokenVersion int) (bool, error) {
+	version, cached := securityVersions.get(userID)
+	if !cached {
+		current, err := s.userRepository.GetSecurityVersion(userID)
+		if err != nil {
+			return false, fmt.Errorf("error fetching security version: %w", err)
+		}
+		securityVersions.set(userID, current)
+		version = current
+	}
+
+	return version != 0 && tokenVersion == version, nil
+}
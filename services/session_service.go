@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"sync"
+	"time"
+)
+
+// sessionTouchInterval intervallo minimo tra due aggiornamenti di last_seen_at della stessa sessione
+const sessionTouchInterval = time.Minute
+
+// sessionCache tiene in memoria per poco tempo lo stato delle sessioni, cosí il middleware
+// non interroga il database ad ogni richiesta
+type sessionCache struct {
+	mu       sync.RWMutex
+	sessions map[string]cachedSession
+}
+
+type cachedSession struct {
+	active    bool
+	touchedAt time.Time
+	expiresAt time.Time
+}
+
+// Cache condivisa tra tutte le istanze del servizio (una per gruppo di rotte)
+var authSessions = &sessionCache{sessions: make(map[string]cachedSession)}
+
+func (c *sessionCache) get(sessionID string) (cachedSession, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.sessions[sessionID]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return cachedSession{}, false
+	}
+	return entry, true
+}
+
+func (c *sessionCache) set(sessionID string, entry cachedSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Pulizia delle voci scadute ad ogni inserimento
+	now := time.Now()
+	for id, cached := range c.sessions {
+		if now.After(cached.expiresAt) {
+			delete(c.sessions, id)
+		}
+	}
+
+	c.sessions[sessionID] = entry
+}
+
+func (c *sessionCache) invalidate(sessionIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		delete(c.sessions, sessionID)
+	}
+}
+
+type SessionService struct {
+	sessionRepository      *repositories.AuthSessionRepository
+	refreshTokenRepository *repositories.RefreshTokenRepository
+}
+
+// NewSessionService crea una nuova istanza del servizio
+func NewSessionService() *SessionService {
+	return &SessionService{
+		sessionRepository:      repositories.NewAuthSessionRepository(),
+		refreshTokenRepository: repositories.NewRefreshTokenRepository(),
+	}
+}
+
+// CreateSession registra una nuova sessione di login con dispositivo, IP e user agent
+func (s *SessionService) CreateSession(userID int, sessionID string, deviceName *string, client models.ClientInfo) error {
+	session := &models.AuthSession{
+		UserID:     userID,
+		SessionID:  sessionID,
+		DeviceName: deviceName,
+	}
+	session.IPAddress, session.UserAgent = clientInfoFields(client)
+
+	if err := s.sessionRepository.Create(session); err != nil {
+		return fmt.Errorf("error saving session: %w", err)
+	}
+	return nil
+}
+
+// IsActive verifica che la sessione del token non sia stata chiusa e aggiorna l'ultimo accesso.
+// I token senza sid (es. impersonificazione) non appartengono a nessuna sessione
+func (s *SessionService) IsActive(claims *utils.JWTClaims, client models.ClientInfo) (bool, error) {
+	if claims.SessionID == "" {
+		return true, nil
+	}
+
+	entry, cached := authSessions.get(claims.SessionID)
+	if !cached {
+		session, err := s.sessionRepository.GetBySessionID(claims.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("error fetching session: %w", err)
+		}
+		entry = cachedSession{
+			active:    session != nil && session.RevokedAt == nil && session.UserID == claims.UserID,
+			expiresAt: time.Now().Add(config.SessionCacheTTL()),
+		}
+		if session != nil {
+			entry.touchedAt = session.LastSeenAt
+		}
+	}
+
+	if entry.active && time.Since(entry.touchedAt) >= sessionTouchInterval {
+		// L'ultimo accesso é informativo, un errore non blocca la richiesta
+		ipAddress, userAgent := clientInfoFields(client)
+		if err := s.sessionRepository.TouchLastSeen(claims.SessionID, ipAddress, userAgent); err != nil {
+			log.Printf("Error updating last seen of session for user ID %d: %v", claims.UserID, err)
+		}
+		entry.touchedAt = time.Now()
+		cached = false
+	}
+
+	if !cached {
+		authSessions.set(claims.SessionID, entry)
+	}
+
+	return entry.active, nil
+}
+
+// GetSessions restituisce le sessioni attive dell'utente, segnando quella corrente
+func (s *SessionService) GetSessions(userID int, currentSessionID string) ([]models.AuthSession, error) {
+	sessions, err := s.sessionRepository.GetActiveByUserID(userID, time.Now().Add(-config.RefreshTokenTTL()))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %w", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = currentSessionID != "" && sessions[i].SessionID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession chiude una sessione dell'utente (es. telefono perso).
+// Ritorna true se era la sessione corrente
+func (s *SessionService) RevokeUserSession(userID, id int, currentSessionID string) (bool, error) {
+	session, err := s.sessionRepository.GetByID(id)
+	if err != nil {
+		return false, fmt.Errorf("error retrieving session: %w", err)
+	}
+	// Le sessioni di altri utenti risultano inesistenti
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return false, errors.New("session not found")
+	}
+
+	if err := s.RevokeSession(session.SessionID); err != nil {
+		return false, err
+	}
+
+	log.Printf("Session %d revoked by user ID %d", id, userID)
+	return session.SessionID == currentSessionID, nil
+}
+
+// RevokeOtherSessions chiude tutte le sessioni dell'utente tranne quella corrente
+func (s *SessionService) RevokeOtherSessions(userID int, currentSessionID string) (int, error) {
+	if currentSessionID == "" {
+		return 0, errors.New("current token has no session")
+	}
+
+	sessionIDs, err := s.revokeAll(userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("User ID %d signed out %d other sessions", userID, len(sessionIDs))
+	return len(sessionIDs), nil
+}
+
+// RevokeSession chiude la sessione e revoca i suoi refresh token
+func (s *SessionService) RevokeSession(sessionID string) error {
+	if err := s.sessionRepository.Revoke(sessionID); err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	authSessions.invalidate(sessionID)
+
+	if err := s.refreshTokenRepository.RevokeFamily(sessionID); err != nil {
+		return fmt.Errorf("error revoking refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions chiude tutte le sessioni dell'utente e revoca tutti i suoi refresh token
+func (s *SessionService) RevokeAllSessions(userID int) error {
+	if _, err := s.revokeAll(userID, ""); err != nil {
+		return err
+	}
+
+	if err := s.refreshTokenRepository.RevokeAllForUser(userID); err != nil {
+		return fmt.Errorf("error revoking refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// revokeAll chiude le sessioni dell'utente tranne exceptSessionID, con i relativi refresh token
+func (s *SessionService) revokeAll(userID int, exceptSessionID string) ([]string, error) {
+	sessionIDs, err := s.sessionRepository.RevokeAllForUser(userID, exceptSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	authSessions.invalidate(sessionIDs...)
+
+	for _, sessionID := range sessionIDs {
+		if err := s.refreshTokenRepository.RevokeFamily(sessionID); err != nil {
+			return nil, fmt.Errorf("error revoking refresh tokens: %w", err)
+		}
+	}
+
+	return sessionIDs, nil
+}
+
+// clientInfoFields converte IP e user agent nei campi opzionali della sessione
+func clientInfoFields(client models.ClientInfo) (*string, *string) {
+	var ipAddress, userAgent *string
+	if client.IPAddress != "" {
+		ipAddress = &client.IPAddress
+	}
+	if client.UserAgent != "" {
+		// Limito la lunghezza, lo user agent é controllato dal client
+		value := client.UserAgent
+		if runes := []rune(value); len(runes) > 512 {
+			value = string(runes[:512])
+		}
+		userAgent = &value
+	}
+	return ipAddress, userAgent
+}
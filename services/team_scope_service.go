@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+)
+
+// Viewer identifica chi consulta i dati di gruppo: un utente (ruolo dai claims) oppure un'API key
+type Viewer struct {
+	UserID int
+	RoleID *int
+	APIKey *models.APIKey
+}
+
+type TeamScopeService struct {
+	permissionService *PermissionService
+	userRepository    *repositories.UserRepository
+}
+
+// NewTeamScopeService crea una nuova istanza del servizio
+func NewTeamScopeService() *TeamScopeService {
+	return &TeamScopeService{
+		permissionService: NewPermissionService(),
+		userRepository:    repositories.NewUserRepository(),
+	}
+}
+
+// Resolve calcola quali utenti puó vedere chi fa la richiesta: tutta l'azienda con il permesso
+// company:read_all, altrimenti l'albero dei sottoposti ricavato da users.manager_id.
+// Le API key non hanno un team: vedono tutta l'azienda solo se uno scope lo concede
+func (s *TeamScopeService) Resolve(viewer Viewer) (*models.TeamScope, error) {
+	if viewer.APIKey != nil {
+		return &models.TeamScope{
+			CompanyWide: viewer.APIKey.GrantsPermission(models.PermCompanyReadAll),
+			UserIDs:     []int{},
+		}, nil
+	}
+
+	companyWide, err := s.permissionService.HasPermission(viewer.RoleID, models.PermCompanyReadAll)
+	if err != nil {
+		return nil, fmt.Errorf("error checking company permission: %w", err)
+	}
+	if companyWide {
+		return &models.TeamScope{CompanyWide: true}, nil
+	}
+
+	subordinates, err := s.userRepository.GetSubordinateIDs(viewer.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching subordinates: %w", err)
+	}
+
+	return &models.TeamScope{UserIDs: subordinates}, nil
+}
+
+// CanView verifica se chi fa la richiesta puó vedere i dati dell'utente: i propri sempre, gli altri solo se nello scope
+func (s *TeamScopeService) CanView(viewer Viewer, userID int) (bool, error) {
+	if viewer.APIKey == nil && viewer.UserID == userID {
+		return true, nil
+	}
+
+	scope, err := s.Resolve(viewer)
+	if err != nil {
+		return false, err
+	}
+	return scope.Includes(userID), nil
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"strings"
+	"time"
+)
+
+type TimbratureCorrectionService struct {
+	correctionRepository *repositories.TimbratureCorrectionRepository
+	timbratureRepository *repositories.TimbratureRepository
+	teamScopeService     *TeamScopeService
+}
+
+// NewTimbratureCorrectionService crea una nuova istanza del servizio
+func NewTimbratureCorrectionService() *TimbratureCorrectionService {
+	return &TimbratureCorrectionService{
+		correctionRepository: repositories.NewTimbratureCorrectionRepository(),
+		timbratureRepository: repositories.NewTimbratureRepository(),
+		teamScopeService:     NewTeamScopeService(),
+	}
+}
+
+// CreateCorrection registra la proposta di correzione del dipendente, in attesa del responsabile
+func (s *TimbratureCorrectionService) CreateCorrection(userID int, request *models.CreateTimbraturaCorrectionRequest) (*models.TimbraturaCorrection, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	correction := &models.TimbraturaCorrection{
+		UserID:         userID,
+		CorrectionType: request.CorrectionType,
+		Reason:         reason,
+		Status:         models.CorrectionPending,
+	}
+
+	switch request.CorrectionType {
+	case models.CorrectionAdd:
+		if request.TimbraturaID != nil {
+			return nil, errors.New("timbratura_id is not allowed when adding a timbratura")
+		}
+		if request.Timestamp == nil || request.ActionType == nil || request.Location == nil {
+			return nil, errors.New("timestamp, action_type and location are required")
+		}
+		correction.Timestamp = request.Timestamp
+		correction.ActionType = request.ActionType
+		correction.Location = request.Location
+
+	case models.CorrectionModify, models.CorrectionRemove:
+		original, err := s.getOwnTimbratura(userID, request.TimbraturaID)
+		if err != nil {
+			return nil, err
+		}
+		correction.TimbraturaID = &original.ID
+
+		if request.CorrectionType == models.CorrectionModify {
+			// I valori non indicati restano quelli della timbratura originale
+			timestamp, actionType, location := original.Timestamp, original.ActionType, original.Location
+			if request.Timestamp != nil {
+				timestamp = *request.Timestamp
+			}
+			if request.ActionType != nil {
+				actionType = *request.ActionType
+			}
+			if request.Location != nil {
+				location = *request.Location
+			}
+			if timestamp.Equal(original.Timestamp) && actionType == original.ActionType && location == original.Location {
+				return nil, errors.New("correction does not change the timbratura")
+			}
+			correction.Timestamp = &timestamp
+			correction.ActionType = &actionType
+			correction.Location = &location
+		}
+
+	default:
+		return nil, errors.New("invalid correction type")
+	}
+
+	if err := s.validateCorrection(correction); err != nil {
+		return nil, err
+	}
+
+	if err := s.correctionRepository.Create(correction); err != nil {
+		return nil, fmt.Errorf("error creating correction: %w", err)
+	}
+
+	log.Printf("User %d requested %s correction %d", userID, correction.CorrectionType, correction.ID)
+	return correction, nil
+}
+
+// ApproveCorrection approva la correzione e la applica: la timbratura originale viene annullata, non cancellata
+func (s *TimbratureCorrectionService) ApproveCorrection(id int, reviewer Viewer, comment *string) (*models.TimbraturaCorrection, error) {
+	correction, err := s.getReviewableCorrection(id, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nel frattempo le timbrature possono essere cambiate: si ripetono i controlli della creazione
+	if correction.TimbraturaID != nil {
+		original, err := s.timbratureRepository.GetByID(*correction.TimbraturaID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching timbratura: %w", err)
+		}
+		if original == nil || original.UserID != correction.UserID {
+			return nil, errors.New("timbratura not found")
+		}
+	}
+	if err := s.validateCorrection(correction); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.correctionRepository.Apply(correction, reviewer.UserID, comment, time.Now(), checkCorrectionSequence)
+	if err != nil {
+		if err == errCorrectionBreaksSequence {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error applying correction: %w", err)
+	}
+	if !applied {
+		return nil, errors.New("correction is not pending")
+	}
+
+	log.Printf("Correction %d for user %d approved by user %d", correction.ID, correction.UserID, reviewer.UserID)
+	return correction, nil
+}
+
+// RejectCorrection rifiuta la correzione, le timbrature restano invariate
+func (s *TimbratureCorrectionService) RejectCorrection(id int, reviewer Viewer, comment *string) (*models.TimbraturaCorrection, error) {
+	correction, err := s.getReviewableCorrection(id, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := s.correctionRepository.Reject(id, reviewer.UserID, comment)
+	if err != nil {
+		return nil, fmt.Errorf("error rejecting correction: %w", err)
+	}
+	if !rejected {
+		return nil, errors.New("correction is not pending")
+	}
+
+	log.Printf("Correction %d for user %d rejected by user %d", correction.ID, correction.UserID, reviewer.UserID)
+
+	rejectedCorrection, err := s.correctionRepository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching correction: %w", err)
+	}
+	return rejectedCorrection, nil
+}
+
+// CancelCorrection ritira una correzione del dipendente ancora in attesa
+func (s *TimbratureCorrectionService) CancelCorrection(id, userID int) error {
+	correction, err := s.correctionRepository.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error fetching correction: %w", err)
+	}
+	if correction == nil || correction.UserID != userID {
+		return errors.New("correction not found")
+	}
+
+	cancelled, err := s.correctionRepository.Cancel(id, userID)
+	if err != nil {
+		return fmt.Errorf("error cancelling correction: %w", err)
+	}
+	if !cancelled {
+		return errors.New("correction is not pending")
+	}
+
+	log.Printf("User %d cancelled correction %d", userID, id)
+	return nil
+}
+
+// GetCorrection recupera una correzione: il dipendente vede le proprie, il responsabile quelle del team
+func (s *TimbratureCorrectionService) GetCorrection(id int, viewer Viewer) (*models.TimbraturaCorrection, error) {
+	correction, err := s.correctionRepository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching correction: %w", err)
+	}
+	if correction == nil {
+		return nil, errors.New("correction not found")
+	}
+
+	if viewer.APIKey != nil || correction.UserID != viewer.UserID {
+		scope, err := s.teamScopeService.Resolve(viewer)
+		if err != nil {
+			return nil, err
+		}
+		if !scope.Includes(correction.UserID) {
+			return nil, errors.New("correction not found")
+		}
+	}
+
+	return correction, nil
+}
+
+// GetMyCorrections recupera le correzioni richieste dall'utente
+func (s *TimbratureCorrectionService) GetMyCorrections(userID, limit, offset int) ([]models.TimbraturaCorrection, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	corrections, err := s.correctionRepository.GetByUserID(userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching corrections: %w", err)
+	}
+	return corrections, nil
+}
+
+// GetCorrections recupera le correzioni visibili al responsabile (team o tutta l'azienda), filtrate per status
+func (s *TimbratureCorrectionService) GetCorrections(viewer Viewer, status *models.CorrectionStatus, limit, offset int) ([]models.TimbraturaCorrection, error) {
+	if status != nil {
+		switch *status {
+		case models.CorrectionPending, models.CorrectionApproved, models.CorrectionRejected, models.CorrectionCancelled:
+		default:
+			return nil, errors.New("invalid correction status")
+		}
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	corrections, err := s.correctionRepository.GetAll(status, limit, offset, scope)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching corrections: %w", err)
+	}
+	return corrections, nil
+}
+
+// getOwnTimbratura recupera la timbratura (non annullata) da correggere, solo se dell'utente
+func (s *TimbratureCorrectionService) getOwnTimbratura(userID int, timbraturaID *int) (*models.Timbrature, error) {
+	if timbraturaID == nil {
+		return nil, errors.New("timbratura_id is required")
+	}
+
+	timbratura, err := s.timbratureRepository.GetByID(*timbraturaID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbratura: %w", err)
+	}
+	if timbratura == nil || timbratura.UserID != userID {
+		return nil, errors.New("timbratura not found")
+	}
+
+	pending, err := s.correctionRepository.HasPendingForTimbratura(timbratura.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking pending corrections: %w", err)
+	}
+	if pending {
+		return nil, errors.New("timbratura already has a pending correction")
+	}
+
+	return timbratura, nil
+}
+
+// getReviewableCorrection recupera una correzione in attesa che il responsabile puó approvare o rifiutare
+func (s *TimbratureCorrectionService) getReviewableCorrection(id int, reviewer Viewer) (*models.TimbraturaCorrection, error) {
+	correction, err := s.correctionRepository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching correction: %w", err)
+	}
+	if correction == nil {
+		return nil, errors.New("correction not found")
+	}
+
+	if correction.UserID == reviewer.UserID {
+		return nil, errors.New("cannot review your own correction")
+	}
+
+	// Un responsabile approva solo le correzioni del proprio team
+	scope, err := s.teamScopeService.Resolve(reviewer)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Includes(correction.UserID) {
+		return nil, errors.New("correction does not belong to your team")
+	}
+
+	if correction.Status != models.CorrectionPending {
+		return nil, errors.New("correction is not pending")
+	}
+
+	return correction, nil
+}
+
+// errCorrectionBreaksSequence la correzione lascerebbe due timbrature consecutive incompatibili
+var errCorrectionBreaksSequence = errors.New("correction breaks the timbrature sequence")
+
+// validateCorrection controlla i valori proposti e che la correzione non rompa la sequenza
+// ENTRATA -> (PAUSA_INIZIO -> PAUSA_FINE)* -> USCITA con le timbrature vicine
+func (s *TimbratureCorrectionService) validateCorrection(correction *models.TimbraturaCorrection) error {
+	if correction.CorrectionType != models.CorrectionRemove {
+		if !isValidAction(*correction.ActionType) {
+			return errors.New("invalid action type")
+		}
+		if *correction.Location != models.LocationOffice && *correction.Location != models.LocationSmart {
+			return errors.New("invalid location")
+		}
+		if correction.Timestamp.After(time.Now()) {
+			return errors.New("timestamp cannot be in the future")
+		}
+	}
+
+	neighbours, err := s.correctionRepository.GetNeighbours(correction)
+	if err != nil {
+		return fmt.Errorf("error checking timbrature sequence: %w", err)
+	}
+	if neighbours == nil {
+		return errors.New("timbratura not found")
+	}
+
+	return checkCorrectionSequence(correction, neighbours)
+}
+
+// checkCorrectionSequence verifica la sequenza dopo la correzione: intorno alla nuova timbratura (ADD, MODIFY)
+// e nel vuoto lasciato da quella annullata (MODIFY, REMOVE), salvo che la nuova finisca nello stesso vuoto
+func checkCorrectionSequence(correction *models.TimbraturaCorrection, neighbours *models.CorrectionNeighbours) error {
+	adds := correction.CorrectionType != models.CorrectionRemove
+
+	if adds {
+		if !canFollow(neighbours.AddedPrevious, *correction.ActionType) {
+			return errCorrectionBreaksSequence
+		}
+		if neighbours.AddedNext != nil && checkSequence(correction.ActionType, neighbours.AddedNext.ActionType) != nil {
+			return errCorrectionBreaksSequence
+		}
+	}
+
+	if correction.TimbraturaID != nil && neighbours.RemovedNext != nil {
+		sameGap := adds &&
+			sameTimbratura(neighbours.AddedPrevious, neighbours.RemovedPrevious) &&
+			sameTimbratura(neighbours.AddedNext, neighbours.RemovedNext)
+		if !sameGap && !canFollow(neighbours.RemovedPrevious, neighbours.RemovedNext.ActionType) {
+			return errCorrectionBreaksSequence
+		}
+	}
+
+	return nil
+}
+
+// canFollow verifica che action possa seguire la timbratura previous (nil se non ce ne sono prima)
+func canFollow(previous *models.Timbrature, action models.ActionType) bool {
+	var last *models.ActionType
+	if previous != nil {
+		last = &previous.ActionType
+	}
+	return checkSequence(last, action) == nil
+}
+
+// sameTimbratura verifica che siano la stessa timbratura (o entrambe assenti)
+func sameTimbratura(a, b *models.Timbrature) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID
+}
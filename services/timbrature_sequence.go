@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"merendels-backend/models"
+)
+
+// isValidAction verifica che l'azione sia una di quelle previste
+func isValidAction(action models.ActionType) bool {
+	switch action {
+	case models.ActionEnter, models.ActionExit, models.ActionBreakStart, models.ActionBreakEnd:
+		return true
+	}
+	return false
+}
+
+// workStateAfter stato lavorativo dopo l'azione indicata (nil = nessuna timbratura)
+func workStateAfter(action *models.ActionType) models.WorkState {
+	if action == nil {
+		return models.WorkStateNotWorking
+	}
+	switch *action {
+	case models.ActionEnter, models.ActionBreakEnd:
+		return models.WorkStateWorking
+	case models.ActionBreakStart:
+		return models.WorkStateOnBreak
+	default:
+		return models.WorkStateNotWorking
+	}
+}
+
+// checkSequence verifica che action possa seguire l'azione last (nil se é la prima timbratura):
+// ENTRATA -> (PAUSA_INIZIO -> PAUSA_FINE)* -> USCITA
+func checkSequence(last *models.ActionType, action models.ActionType) error {
+	state := workStateAfter(last)
+
+	switch action {
+	case models.ActionEnter:
+		switch state {
+		case models.WorkStateWorking:
+			return errors.New("cannot enter twice in a row - you must exit first")
+		case models.WorkStateOnBreak:
+			return errors.New("you are on break - end the break first")
+		}
+
+	case models.ActionExit:
+		if last == nil {
+			return errors.New("first timbratura must be ENTRATA")
+		}
+		switch state {
+		case models.WorkStateNotWorking:
+			return errors.New("cannot exit twice in a row - you must enter first")
+		case models.WorkStateOnBreak:
+			return errors.New("you are on break - end the break first")
+		}
+
+	case models.ActionBreakStart:
+		switch state {
+		case models.WorkStateNotWorking:
+			return errors.New("cannot start a break - you are not working")
+		case models.WorkStateOnBreak:
+			return errors.New("cannot start a break - you are already on break")
+		}
+
+	case models.ActionBreakEnd:
+		if state != models.WorkStateOnBreak {
+			return errors.New("cannot end a break - you are not on break")
+		}
+	}
+
+	return nil
+}
@@ -6,58 +6,81 @@ import (
 	"log"
 	"merendels-backend/models"
 	"merendels-backend/repositories"
+	"strings"
 	"time"
 )
 
 type TimbratureService struct {
-	repository *repositories.TimbratureRepository
+	repository     *repositories.TimbratureRepository
+	revisionRepository *repositories.TimbratureRevisionRepository
+	userRepository   *repositories.UserRepository
+	workContractRepository *repositories.WorkContractRepository
+	officeSiteRepository *repositories.OfficeSiteRepository
+	teamScopeService *TeamScopeService
 }
 
 // NewTimbratureService crea la nuova istanza della repo
 func NewTimbratureService() *TimbratureService {
 	return &TimbratureService{
-		repository: repositories.NewTimbratureRepository(),
+		repository:     repositories.NewTimbratureRepository(),
+		revisionRepository: repositories.NewTimbratureRevisionRepository(),
+		userRepository:   repositories.NewUserRepository(),
+		workContractRepository: repositories.NewWorkContractRepository(),
+		officeSiteRepository: repositories.NewOfficeSiteRepository(),
+		teamScopeService: NewTeamScopeService(),
 	}
 }
 
-// CreateTimbrature crea una nuova timbratura con validazioni business
-func (s *TimbratureService) CreateTimbrature(userID int, request *models.CreateTimbratureRequest) (*models.TimbratureResponse, error) {
+// CreateTimbrature crea una nuova timbratura del dipendente autenticato, con gli eventuali avvisi sulle pause
+func (s *TimbratureService) CreateTimbrature(userID int, request *models.CreateTimbratureRequest) (*models.TimbratureResponse, []models.WorkWarning, error) {
+	return s.createTimbratura(userID, request, &models.TimbraturaRevision{
+		Source:    models.RevisionSourceUser,
+		ChangedBy: &userID,
+	})
+}
+
+// createTimbratura crea una nuova timbratura con validazioni business, revision indica origine e autore per lo storico.
+// Restituisce anche gli avvisi sulle pause previste dal contratto del dipendente
+func (s *TimbratureService) createTimbratura(userID int, request *models.CreateTimbratureRequest, revision *models.TimbraturaRevision) (*models.TimbratureResponse, []models.WorkWarning, error) {
 	// Validazioni base
-	if request.ActionType != models.ActionEnter && request.ActionType != models.ActionExit {
+	if !isValidAction(request.ActionType) {
 		// Azione non valida
-		return nil, errors.New("invalid action type")
+		return nil, nil, errors.New("invalid action type")
 	}
 
 	if request.Location != models.LocationOffice && request.Location != models.LocationSmart {
 		// Location non valida
-		return nil, errors.New("invalid location")
+		return nil, nil, errors.New("invalid location")
 	}
 
-	// Verifica sequenza ENTRATA -> USCITA
+	if err := validatePosition(request.Latitude, request.Longitude, request.Accuracy); err != nil {
+		return nil, nil, err
+	}
+
+	// Verifica sequenza ENTRATA -> (PAUSA_INIZIO -> PAUSA_FINE)* -> USCITA
 	lastTimbrature, err := s.repository.GetLastTimbratureByUserID(userID)
 	if err != nil {
-		return nil, fmt.Errorf("error checking last timbrature: %w", err)
+		return nil, nil, fmt.Errorf("error checking last timbrature: %w", err)
 	}
 
 	// Validazione sequenza logica
+	var lastAction *models.ActionType
 	if lastTimbrature != nil {
-		if lastTimbrature.ActionType == request.ActionType {
-			if request.ActionType == models.ActionEnter {
-				return nil, errors.New("cannot enter twice in a row - you must exit first")
-			} else {
-				return nil, errors.New("cannot exit twice in a row - you must enter first")
-			}
-		}
-	} else {
-		// Prima timbratura ever - deve essere ENTRATA
-		if request.ActionType == models.ActionExit {
-			return nil, errors.New("first timbratura must be ENTRATA")
-		}
+		lastAction = &lastTimbrature.ActionType
+	}
+	if err := checkSequence(lastAction, request.ActionType); err != nil {
+		return nil, nil, err
 	}
 
 	// Timestamp generato dal server (anti-frode)
 	now := time.Now()
 
+	// Regole sulle pause del contratto
+	warnings, err := s.checkBreakRules(userID, request.ActionType, lastTimbrature, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// CreateRequest -> Timbrature model
 	timbrature := &models.Timbrature{
 		UserID: userID,
@@ -65,29 +88,134 @@ func (s *TimbratureService) CreateTimbrature(userID int, request *models.CreateT
 		ActionType: request.ActionType,
 		Location: request.Location,
 		Geolocation: request.Geolocation,
+		Latitude: request.Latitude,
+		Longitude: request.Longitude,
+		Accuracy: request.Accuracy,
+	}
+
+	// Geofence delle sedi: i kiosk sono dispositivi fissi in sede, si controllano solo i dispositivi dei dipendenti
+	if timbrature.Location == models.LocationOffice && revision.Source != models.RevisionSourceKiosk {
+		if err := s.applyGeofence(timbrature); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Salva nel database
-	err = s.repository.Create(timbrature)
+	err = s.repository.Create(timbrature, revision)
 	if err != nil {
-		return nil, fmt.Errorf("error creating timbrature: %w", err)
+		return nil, nil, fmt.Errorf("error creating timbrature: %w", err)
 	}
 
 	// Timbrature → Response
-	response := &models.TimbratureResponse{
-		ID:          timbrature.ID,
-		UserID:      timbrature.UserID,
-		Timestamp:   timbrature.Timestamp,
-		ActionType:  timbrature.ActionType,
-		Location:    timbrature.Location,
-		Geolocation: timbrature.Geolocation,
-	}
+	response := models.TimbratureResponse(*timbrature)
 
 	// Log per audit
 	log.Printf("User %d created %s timbratura at %s", 
 		userID, request.ActionType, now.Format("2006-01-02 15:04:05"))
 
-	return response, nil
+	return &response, warnings, nil
+}
+
+// applyGeofence controlla la posizione di una timbratura UFFICIO sulle sedi attive e ne registra l'esito.
+// Con policy REJECT le timbrature fuori sede o senza posizione vengono rifiutate. Senza sedi non controlla nulla
+func (s *TimbratureService) applyGeofence(timbratura *models.Timbrature) error {
+	sites, err := s.officeSiteRepository.GetAll(true)
+	if err != nil {
+		return fmt.Errorf("error fetching office sites: %w", err)
+	}
+	if len(sites) == 0 {
+		return nil
+	}
+
+	result := evaluateGeofence(sites, timbratura.Latitude, timbratura.Longitude, timbratura.Accuracy)
+	if result.status != models.GeofenceInside && result.policy == models.GeofencePolicyReject {
+		log.Printf("User %d %s timbratura rejected by geofence (%s)", timbratura.UserID, timbratura.ActionType, result.status)
+		if result.status == models.GeofenceNoPosition {
+			return errors.New("position is required for office timbrature")
+		}
+		return errors.New("timbratura is outside the office geofence")
+	}
+
+	timbratura.GeofenceStatus = &result.status
+	if result.site != nil {
+		timbratura.OfficeSiteID = &result.site.ID
+	}
+	timbratura.GeofenceFlagged = result.flagged
+
+	return nil
+}
+
+// checkBreakRules applica le regole sulle pause del contratto del dipendente alla nuova timbratura:
+// PAUSA_FINE prima della pausa minima (rifiutata con policy ENFORCE) e USCITA oltre la soglia senza pausa sufficiente
+func (s *TimbratureService) checkBreakRules(userID int, action models.ActionType, last *models.Timbrature, now time.Time) ([]models.WorkWarning, error) {
+	warnings := []models.WorkWarning{}
+	if action != models.ActionBreakEnd && action != models.ActionExit {
+		return warnings, nil
+	}
+
+	contract, err := s.workContractRepository.GetForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching work contract: %w", err)
+	}
+	if contract == nil || contract.MinBreakMinutes <= 0 {
+		return warnings, nil
+	}
+
+	if action == models.ActionBreakEnd {
+		breakDuration := now.Sub(last.Timestamp)
+		if breakDuration >= time.Duration(contract.MinBreakMinutes)*time.Minute {
+			return warnings, nil
+		}
+		if contract.BreakPolicy == models.BreakPolicyEnforce {
+			return nil, errors.New("break too short")
+		}
+		return append(warnings, models.WorkWarning{
+			Code: models.WarningBreakTooShort,
+			Message: fmt.Sprintf("Break of %d minutes, contract %s requires at least %d minutes",
+				int(breakDuration/time.Minute), contract.Name, contract.MinBreakMinutes),
+		}), nil
+	}
+
+	// USCITA: tempo lavorato e in pausa dall'ultima ENTRATA
+	punches, err := s.repository.GetByUserIDBetween(userID, now.Add(-maxWorkInterval), now)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbrature: %w", err)
+	}
+	worked, breaks := shiftDurations(punches, now)
+	if warning := breakWarning(contract, worked, breaks); warning != nil {
+		warnings = append(warnings, *warning)
+	}
+
+	return warnings, nil
+}
+
+// CreateKioskTimbratura registra la timbratura di un dipendente da un kiosk autenticato con API key
+func (s *TimbratureService) CreateKioskTimbratura(request *models.KioskTimbratureRequest, apiKey *models.APIKey) (*models.TimbratureResponse, []models.WorkWarning, error) {
+	user, err := s.userRepository.GetByID(request.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, errors.New("user not found")
+	}
+	if user.Status != models.UserActive {
+		return nil, nil, errors.New("user is not active")
+	}
+
+	response, warnings, err := s.createTimbratura(request.UserID, &models.CreateTimbratureRequest{
+		ActionType:  request.ActionType,
+		Location:    request.Location,
+		Geolocation: request.Geolocation,
+	}, &models.TimbraturaRevision{
+		Source:   models.RevisionSourceKiosk,
+		APIKeyID: &apiKey.ID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("Timbratura %d for user %d recorded by kiosk %s", response.ID, request.UserID, apiKey.Name)
+	return response, warnings, nil
 }
 
 // GetUserTimbrature recupera le timbrature dell'utente autenticato
@@ -160,20 +288,39 @@ func (s *TimbratureService) GetLastTimbrature(userID int) (*models.TimbratureRes
 		return nil, nil // Nessuna timbratura precedente
 	}
 
-	response := &models.TimbratureResponse{
-		ID:          timbratura.ID,
-		UserID:      timbratura.UserID,
-		Timestamp:   timbratura.Timestamp,
-		ActionType:  timbratura.ActionType,
-		Location:    timbratura.Location,
-		Geolocation: timbratura.Geolocation,
+	response := models.TimbratureResponse(*timbratura)
+	return &response, nil
+}
+
+// GetWorkSummary calcola le ore lavorate dell'utente dal giorno from al giorno to (inclusi),
+// accoppiando ENTRATA/USCITA anche a cavallo della mezzanotte
+func (s *TimbratureService) GetWorkSummary(userID int, from, to time.Time) (*models.WorkSummary, error) {
+	periodStart := startOfDay(from)
+	periodEnd := startOfDay(to).AddDate(0, 0, 1)
+
+	if !periodStart.Before(periodEnd) {
+		return nil, errors.New("invalid date range: from is after to")
+	}
+	if periodStart.AddDate(0, 0, maxSummaryDays).Before(periodEnd) {
+		return nil, errors.New("date range too long")
+	}
+
+	// Margine prima e dopo il periodo per accoppiare i turni a cavallo degli estremi
+	punches, err := s.repository.GetByUserIDBetween(userID, periodStart.Add(-maxWorkInterval), periodEnd.Add(maxWorkInterval))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbrature: %w", err)
+	}
+
+	contract, err := s.workContractRepository.GetForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching work contract: %w", err)
 	}
 
-	return response, nil
+	return buildWorkSummary(userID, punches, periodStart, periodEnd, time.Now(), contract), nil
 }
 
-// GetAllTimbrature recupera tutte le timbrature (solo per admin)
-func (s *TimbratureService) GetAllTimbrature(limit, offset int) ([]models.TimbratureResponse, error) {
+// GetAllTimbrature recupera le timbrature visibili all'utente (team o tutta l'azienda)
+func (s *TimbratureService) GetAllTimbrature(viewer Viewer, limit, offset int) ([]models.TimbratureResponse, error) {
 	//  Validazioni paginazione
 	if limit <= 0 || limit > 100 {
 		limit = 20
@@ -182,7 +329,12 @@ func (s *TimbratureService) GetAllTimbrature(limit, offset int) ([]models.Timbra
 		offset = 0
 	}
 
-	timbrature, err := s.repository.GetAll(limit, offset)
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	timbrature, err := s.repository.GetAll(limit, offset, scope)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching all timbrature: %w", err)
 	}
@@ -206,19 +358,18 @@ func (s *TimbratureService) GetWorkingStatus(userID int) (*WorkingStatusResponse
 	status := &WorkingStatusResponse{
 		UserID:    userID,
 		IsWorking: false,
+		OnBreak:   false,
+		Status:    models.WorkStateNotWorking,
 		LastTimbratura: nil,
 	}
 
 	if lastTimbratura != nil {
-		status.IsWorking = (lastTimbratura.ActionType == models.ActionEnter)
-		status.LastTimbratura = &models.TimbratureResponse{
-			ID:          lastTimbratura.ID,
-			UserID:      lastTimbratura.UserID,
-			Timestamp:   lastTimbratura.Timestamp,
-			ActionType:  lastTimbratura.ActionType,
-			Location:    lastTimbratura.Location,
-			Geolocation: lastTimbratura.Geolocation,
-		}
+		// In pausa il dipendente resta nel turno: is_working vale true anche con on_break
+		status.Status = workStateAfter(&lastTimbratura.ActionType)
+		status.IsWorking = status.Status != models.WorkStateNotWorking
+		status.OnBreak = status.Status == models.WorkStateOnBreak
+		lastResponse := models.TimbratureResponse(*lastTimbratura)
+		status.LastTimbratura = &lastResponse
 	}
 
 	return status, nil
@@ -228,19 +379,198 @@ func (s *TimbratureService) GetWorkingStatus(userID int) (*WorkingStatusResponse
 type WorkingStatusResponse struct {
 	UserID         int                       `json:"user_id"`
 	IsWorking      bool                      `json:"is_working"`
+	OnBreak        bool                      `json:"on_break"`
+	Status         models.WorkState          `json:"status"` // NOT_WORKING, WORKING, ON_BREAK
 	LastTimbratura *models.TimbratureResponse `json:"last_timbratura"`
 }
 
-// DeleteTimbratura elimina una timbratura (solo per correzioni admin)
-func (s *TimbratureService) DeleteTimbratura(id int) error {
-	//  Solo admin dovrebbero poter eliminare timbrature
-	// La validazione del ruolo admin verrà fatta nell'handler
+// GetEmployeesStatus restituisce lo stato di oggi dei dipendenti attivi visibili all'utente
+func (s *TimbratureService) GetEmployeesStatus(viewer Viewer) ([]EmployeeStatusResponse, error) {
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepository.GetActiveUsers()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching active users: %w", err)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	result := make([]EmployeeStatusResponse, 0, len(users))
+
+	for _, user := range users {
+		if !scope.Includes(user.ID) {
+			continue
+		}
+
+		status := EmployeeStatusResponse{
+			ID:       user.ID,
+			Name:     user.Name,
+			WorkMode: "unknown",
+		}
+
+		// Considero solo l'ultima timbratura se é di oggi
+		lastTimbratura, err := s.repository.GetLastTimbratureByUserID(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching last timbratura: %w", err)
+		}
+		if lastTimbratura != nil && lastTimbratura.Timestamp.Format("2006-01-02") == today {
+			state := workStateAfter(&lastTimbratura.ActionType)
+			status.IsWorking = state != models.WorkStateNotWorking
+			status.OnBreak = state == models.WorkStateOnBreak
+			status.WorkMode = string(lastTimbratura.Location) // "UFFICIO" o "SMART"
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// EmployeeStatusResponse rappresenta lo stato di oggi di un dipendente
+type EmployeeStatusResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	IsWorking bool   `json:"is_working"`
+	OnBreak   bool   `json:"on_break"`
+	WorkMode  string `json:"work_mode"`
+}
+
+// DeleteTimbratura elimina una timbratura annullandola: resta nel database con autore e motivo,
+// esclusa da elenchi e calcoli, e l'operazione finisce nello storico delle revisioni
+func (s *TimbratureService) DeleteTimbratura(id int, viewer Viewer, reason string) (*models.TimbratureResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	timbratura, err := s.repository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbratura: %w", err)
+	}
+	if timbratura == nil {
+		return nil, errors.New("timbratura not found")
+	}
+
+	// Un responsabile elimina solo le timbrature del proprio team
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Includes(timbratura.UserID) {
+		return nil, errors.New("timbratura not found")
+	}
+
+	voided, err := s.repository.Void(id, viewer.UserID, reason)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting timbratura: %w", err)
+	}
+	if voided == nil {
+		return nil, errors.New("timbratura not found")
+	}
+
+	log.Printf("Timbratura %d of user %d voided by user %d: %s", id, voided.UserID, viewer.UserID, reason)
+
+	response := models.TimbratureResponse(*voided)
+	return &response, nil
+}
+
+// GetDayHistory restituisce lo storico completo di un giorno di un dipendente visibile all'utente:
+// tutte le timbrature, anche annullate, con le revisioni (creazioni e annullamenti, chi, quando e perché)
+func (s *TimbratureService) GetDayHistory(viewer Viewer, userID int, date time.Time) (*models.TimbratureDayHistory, error) {
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Includes(userID) {
+		return nil, errors.New("user not found")
+	}
+
+	dayStart := startOfDay(date)
+	entries, err := s.revisionRepository.GetHistoryByUserIDBetween(userID, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbrature history: %w", err)
+	}
+
+	history := &models.TimbratureDayHistory{
+		UserID:     userID,
+		Date:       dayStart.Format("2006-01-02"),
+		Timbrature: entries,
+		Revisions:  []models.TimbraturaRevision{},
+	}
+	if len(entries) == 0 {
+		return history, nil
+	}
+
+	ids := make([]int, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	history.Revisions, err = s.revisionRepository.GetByTimbraturaIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching timbrature revisions: %w", err)
+	}
+
+	return history, nil
+}
+
+// GetGeofenceFlagged restituisce le timbrature fuori sede ancora da verificare dei dipendenti visibili all'utente
+func (s *TimbratureService) GetGeofenceFlagged(viewer Viewer, limit, offset int) ([]models.TimbratureResponse, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	scope, err := s.teamScopeService.Resolve(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	timbrature, err := s.repository.GetGeofenceFlagged(limit, offset, scope)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching flagged timbrature: %w", err)
+	}
+
+	responses := make([]models.TimbratureResponse, 0, len(timbrature))
+	for _, t := range timbrature {
+		responses = append(responses, models.TimbratureResponse(t))
+	}
+	return responses, nil
+}
+
+// ReviewGeofence conferma una timbratura fuori sede segnalata, togliendo la segnalazione.
+// Se la timbratura non é valida va invece eliminata (annullata) con il suo motivo
+func (s *TimbratureService) ReviewGeofence(id int, viewer Viewer) error {
+	timbratura, err := s.repository.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("error fetching timbratura: %w", err)
+	}
+	if timbratura == nil {
+		return errors.New("timbratura not found")
+	}
 
-	err := s.repository.Delete(id)
+	scope, err := s.teamScopeService.Resolve(viewer)
 	if err != nil {
-		return fmt.Errorf("error deleting timbratura: %w", err)
+		return err
+	}
+	if !scope.Includes(timbratura.UserID) {
+		return errors.New("timbratura not found")
+	}
+	if timbratura.UserID == viewer.UserID {
+		return errors.New("cannot review your own timbratura")
 	}
 
-	log.Printf("Timbratura %d deleted", id)
+	reviewed, err := s.repository.MarkGeofenceReviewed(id, viewer.UserID)
+	if err != nil {
+		return fmt.Errorf("error reviewing timbratura: %w", err)
+	}
+	if !reviewed {
+		return errors.New("timbratura is not flagged")
+	}
+
+	log.Printf("Flagged timbratura %d of user %d reviewed by user %d", id, timbratura.UserID, viewer.UserID)
 	return nil
-}
\ No newline at end of file
+}
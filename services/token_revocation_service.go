@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"sync"
+	"time"
+)
+
+// revokedTokenCache tiene in memoria i jti giá revocati fino alla loro scadenza,
+// cosí le richieste con un token revocato non interrogano ogni volta il database
+type revokedTokenCache struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+}
+
+// Cache condivisa tra tutte le istanze del servizio (una per gruppo di rotte)
+var revokedTokens = &revokedTokenCache{tokens: make(map[string]time.Time)}
+
+func (c *revokedTokenCache) contains(jti string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	expiresAt, exists := c.tokens[jti]
+	return exists && time.Now().Before(expiresAt)
+}
+
+func (c *revokedTokenCache) add(jti string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Pulizia delle voci scadute ad ogni inserimento
+	now := time.Now()
+	for id, exp := range c.tokens {
+		if now.After(exp) {
+			delete(c.tokens, id)
+		}
+	}
+
+	c.tokens[jti] = expiresAt
+}
+
+type TokenRevocationService struct {
+	repository *repositories.TokenRevocationRepository
+}
+
+// NewTokenRevocationService crea una nuova istanza del servizio
+func NewTokenRevocationService() *TokenRevocationService {
+	return &TokenRevocationService{
+		repository: repositories.NewTokenRevocationRepository(),
+	}
+}
+
+// RevokeToken revoca il singolo token (logout della sessione corrente)
+func (s *TokenRevocationService) RevokeToken(claims *utils.JWTClaims) error {
+	if claims == nil || claims.ID == "" {
+		return errors.New("token has no jti")
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err := s.repository.RevokeToken(claims.ID, claims.UserID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+	revokedTokens.add(claims.ID, expiresAt)
+
+	// Pulizia opportunistica della denylist
+	if deleted, err := s.repository.DeleteExpired(); err != nil {
+		log.Printf("Error cleaning expired revoked tokens: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Removed %d expired revoked tokens", deleted)
+	}
+
+	log.Printf("Token %s revoked for user ID %d", claims.ID, claims.UserID)
+	return nil
+}
+
+// RevokeAllUserTokens revoca tutti i token emessi finora per l'utente (logout da tutte le sessioni)
+func (s *TokenRevocationService) RevokeAllUserTokens(userID int) error {
+	// Il claim iat ha precisione al secondo: i token emessi nello stesso secondo restano fuori
+	// dal confronto ma perdono comunque validitá con la nuova versione di sicurezza,
+	// mentre quelli emessi subito dopo (es. nuovo login) la ricevono e restano validi
+	revokedBefore := time.Now().Truncate(time.Second)
+
+	err := s.repository.RevokeAllUserTokens(userID, revokedBefore)
+	if err != nil {
+		return fmt.Errorf("error revoking user tokens: %w", err)
+	}
+	securityVersions.invalidate(userID)
+
+	log.Printf("All tokens revoked for user ID %d", userID)
+	return nil
+}
+
+// IsRevoked verifica se il token é stato revocato singolarmente o tramite logout globale
+func (s *TokenRevocationService) IsRevoked(claims *utils.JWTClaims) (bool, error) {
+	if claims.ID != "" && revokedTokens.contains(claims.ID) {
+		return true, nil
+	}
+
+	revoked, revokedBefore, err := s.repository.GetRevocationStatus(claims.ID, claims.UserID)
+	if err != nil {
+		return false, fmt.Errorf("error checking token revocation: %w", err)
+	}
+
+	if revoked {
+		if claims.ExpiresAt != nil {
+			revokedTokens.add(claims.ID, claims.ExpiresAt.Time)
+		}
+		return true, nil
+	}
+
+	// Token emessi prima dell'ultimo logout globale non sono piú validi
+	if revokedBefore != nil && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(*revokedBefore)) {
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -3,18 +3,21 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"merendels-backend/models"
 	"merendels-backend/repositories"
 )
 
 type UserRoleService struct {
 	repository *repositories.UserRoleRepository
+	userRepository *repositories.UserRepository
 }
 
 // Nuova istanza del service
 func NewUserRoleRepository() *UserRoleService {
 	return &UserRoleService{
 		repository: repositories.NewUserRoleRepository(),
+		userRepository: repositories.NewUserRepository(),
 	}
 }
 
@@ -42,6 +45,7 @@ func (s *UserRoleService) CreateUserRole(request *models.CreateUserRoleRequest)
 	userRole := &models.UserRole{
 		Name: request.Name,
 		HierarchyLevel: request.HierarchyLevel,
+		MFARequired: request.MFARequired,
 	}
 
 	// Salvo nel database tramite la repository
@@ -54,6 +58,7 @@ func (s *UserRoleService) CreateUserRole(request *models.CreateUserRoleRequest)
 		ID: createdRole.ID,
 		Name: createdRole.Name,
 		HierarchyLevel: createdRole.HierarchyLevel,
+		MFARequired: createdRole.MFARequired,
 	}
 	
 	return response, nil
@@ -128,9 +133,12 @@ func (s *UserRoleService) UpdateUserRole(id int, request *models.CreateUserRoleR
 		}
 	}	
 
+	hierarchyChanged := request.HierarchyLevel != existingRole.HierarchyLevel
+
 	// aggiorno i dati del ruolo esistente
 	existingRole.Name = request.Name
 	existingRole.HierarchyLevel = request.HierarchyLevel
+	existingRole.MFARequired = request.MFARequired
 
 	// salva tramite repository
 	success, err := s.repository.Update(existingRole)
@@ -141,6 +149,16 @@ func (s *UserRoleService) UpdateUserRole(id int, request *models.CreateUserRoleR
 		return nil, errors.New("failed to update user role")
 	}
 
+	// Il hierarchy_level é nei token: gli utenti del ruolo devono rinnovarli
+	if hierarchyChanged {
+		affected, err := s.userRepository.BumpSecurityVersionByRole(id)
+		if err != nil {
+			return nil, fmt.Errorf("error updating security version: %w", err)
+		}
+		securityVersions.invalidateAll()
+		log.Printf("Hierarchy level of role ID %d changed, %d users must refresh their tokens", id, affected)
+	}
+
 	return existingRole, nil
 }
 
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"merendels-backend/utils"
+	"strings"
+	"time"
+)
+
+type UserService struct {
+	userRepository         *repositories.UserRepository
+	userRoleRepository     *repositories.UserRoleRepository
+	timbratureRepository   *repositories.TimbratureRepository
+	requestRepository      *repositories.RequestRepository
+	leaveBalanceRepository *repositories.LeaveBalanceRepository
+	workContractRepository *repositories.WorkContractRepository
+	authService            *AuthService
+	permissionService      *PermissionService
+}
+
+// NewUserService crea una nuova istanza del servizio
+func NewUserService() *UserService {
+	return &UserService{
+		userRepository:         repositories.NewUserRepository(),
+		userRoleRepository:     repositories.NewUserRoleRepository(),
+		timbratureRepository:   repositories.NewTimbratureRepository(),
+		requestRepository:      repositories.NewRequestRepository(),
+		leaveBalanceRepository: repositories.NewLeaveBalanceRepository(),
+		workContractRepository: repositories.NewWorkContractRepository(),
+		authService:            NewAuthService(),
+		permissionService:      NewPermissionService(),
+	}
+}
+
+// GetUsers cerca gli utenti con filtri e paginazione
+func (s *UserService) GetUsers(filter *models.UserFilter) ([]models.UserDetails, int, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Search = strings.TrimSpace(filter.Search)
+
+	if filter.Status != "" {
+		filter.Status = models.UserStatus(strings.ToUpper(string(filter.Status)))
+		if !isValidUserStatus(filter.Status) {
+			return nil, 0, errors.New("invalid status filter")
+		}
+	}
+
+	users, total, err := s.userRepository.GetAll(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// GetUserByID recupera un utente per ID
+func (s *UserService) GetUserByID(id int) (*models.UserDetails, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid ID: must be greater than 0")
+	}
+
+	user, err := s.userRepository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return user, nil
+}
+
+// UpdateUser aggiorna nome ed email verificando l'unicitá dell'email
+func (s *UserService) UpdateUser(id int, actor *utils.JWTClaims, request *models.UpdateUserRequest) (*models.UserDetails, error) {
+	target, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, target); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(request.Name)
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if name == "" || email == "" {
+		return nil, errors.New("name and email are required")
+	}
+
+	exists, err := s.userRepository.EmailExistsForOtherUser(email, id)
+	if err != nil {
+		return nil, fmt.Errorf("error checking email: %w", err)
+	}
+	if exists {
+		return nil, errors.New("email already registered")
+	}
+
+	if err := s.userRepository.Update(id, name, email); err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return s.GetUserByID(id)
+}
+
+// UpdateUserRole assegna un nuovo ruolo all'utente. Il proprio ruolo non si cambia, e non si puó
+// assegnare un ruolo piú alto del proprio o con permessi che chi agisce non ha
+func (s *UserService) UpdateUserRole(id int, actor *utils.JWTClaims, request *models.UpdateUserRoleRequest) (*models.UserDetails, error) {
+	if id == actor.UserID {
+		return nil, errors.New("cannot change your own role")
+	}
+
+	target, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, target); err != nil {
+		return nil, err
+	}
+
+	if request.RoleID != nil {
+		role, err := s.userRoleRepository.GetByID(*request.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("error validating role: %w", err)
+		}
+		if role == nil {
+			return nil, errors.New("invalid role_id")
+		}
+
+		if outranks(&role.HierarchyLevel, actor.HierarchyLevel) {
+			return nil, errors.New("cannot assign a role higher than your own")
+		}
+		covered, err := s.permissionService.CoversRole(actor.RoleID, &role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking role permissions: %w", err)
+		}
+		if !covered {
+			return nil, errors.New("cannot assign a role that has permissions you do not have")
+		}
+	}
+
+	if err := s.userRepository.UpdateRole(id, request.RoleID); err != nil {
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+	securityVersions.invalidate(id)
+
+	log.Printf("Role of user ID %d changed to %v", id, formatOptionalID(request.RoleID))
+	return s.GetUserByID(id)
+}
+
+// UpdateUserManager assegna un nuovo manager impedendo cicli nella catena dei manager
+func (s *UserService) UpdateUserManager(id int, actor *utils.JWTClaims, request *models.UpdateUserManagerRequest) (*models.UserDetails, error) {
+	target, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, target); err != nil {
+		return nil, err
+	}
+
+	if request.ManagerID != nil {
+		if *request.ManagerID == id {
+			return nil, errors.New("user cannot be their own manager")
+		}
+
+		manager, err := s.userRepository.GetByID(*request.ManagerID)
+		if err != nil {
+			return nil, fmt.Errorf("error validating manager: %w", err)
+		}
+		if manager == nil {
+			return nil, errors.New("invalid manager_id")
+		}
+		if manager.Status != models.UserActive {
+			return nil, errors.New("manager is not active")
+		}
+
+		// Se l'utente é giá sopra il nuovo manager nella catena si creerebbe un ciclo
+		cycle, err := s.userRepository.IsInManagerChain(*request.ManagerID, id)
+		if err != nil {
+			return nil, fmt.Errorf("error checking manager chain: %w", err)
+		}
+		if cycle {
+			return nil, errors.New("manager assignment would create a cycle")
+		}
+	}
+
+	if err := s.userRepository.UpdateManager(id, request.ManagerID); err != nil {
+		return nil, fmt.Errorf("error updating manager: %w", err)
+	}
+	securityVersions.invalidate(id)
+
+	log.Printf("Manager of user ID %d changed to %v", id, formatOptionalID(request.ManagerID))
+	return s.GetUserByID(id)
+}
+
+// UpdateUserWorkContract assegna il contratto di lavoro (nil = torna al contratto predefinito)
+func (s *UserService) UpdateUserWorkContract(id int, actor *utils.JWTClaims, request *models.UpdateUserWorkContractRequest) (*models.UserDetails, error) {
+	target, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, target); err != nil {
+		return nil, err
+	}
+
+	if request.WorkContractID != nil {
+		contract, err := s.workContractRepository.GetByID(*request.WorkContractID)
+		if err != nil {
+			return nil, fmt.Errorf("error validating work contract: %w", err)
+		}
+		if contract == nil {
+			return nil, errors.New("invalid work_contract_id")
+		}
+	}
+
+	if err := s.userRepository.UpdateWorkContract(id, request.WorkContractID); err != nil {
+		return nil, fmt.Errorf("error updating work contract: %w", err)
+	}
+
+	log.Printf("Work contract of user ID %d changed to %v", id, formatOptionalID(request.WorkContractID))
+	return s.GetUserByID(id)
+}
+
+// DeactivateUser disattiva l'utente e chiude tutte le sue sessioni
+func (s *UserService) DeactivateUser(id int, actor *utils.JWTClaims) (*models.UserDetails, error) {
+	adminID := actor.UserID
+	if id == adminID {
+		return nil, errors.New("cannot deactivate your own account")
+	}
+
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, user); err != nil {
+		return nil, err
+	}
+	if user.Status != models.UserActive {
+		return nil, errors.New("user is not active")
+	}
+
+	if err := s.userRepository.UpdateStatus(id, models.UserInactive); err != nil {
+		return nil, fmt.Errorf("error deactivating user: %w", err)
+	}
+	securityVersions.invalidate(id)
+
+	if err := s.authService.LogoutAll(id); err != nil {
+		return nil, fmt.Errorf("error invalidating sessions: %w", err)
+	}
+
+	log.Printf("User ID %d deactivated by user ID %d", id, adminID)
+	return s.GetUserByID(id)
+}
+
+// ReactivateUser riattiva un utente disattivato
+func (s *UserService) ReactivateUser(id int, actor *utils.JWTClaims) (*models.UserDetails, error) {
+	adminID := actor.UserID
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, user); err != nil {
+		return nil, err
+	}
+	if user.Status != models.UserInactive {
+		return nil, errors.New("user is not inactive")
+	}
+
+	if err := s.userRepository.UpdateStatus(id, models.UserActive); err != nil {
+		return nil, fmt.Errorf("error reactivating user: %w", err)
+	}
+	securityVersions.invalidate(id)
+
+	log.Printf("User ID %d reactivated by user ID %d", id, adminID)
+	return s.GetUserByID(id)
+}
+
+// TerminateUser conclude il rapporto di lavoro: chiude l'eventuale ENTRATA aperta, annulla le richieste
+// in attesa e quelle approvate successive alla cessazione, registra la liquidazione del saldo e chiude le sessioni.
+// La storia di timbrature e approvazioni resta intatta
+func (s *UserService) TerminateUser(id int, actor *utils.JWTClaims, request *models.TerminateUserRequest) (*models.OffboardingResult, error) {
+	adminID := actor.UserID
+	if id == adminID {
+		return nil, errors.New("cannot terminate your own account")
+	}
+
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanManage(actor, user); err != nil {
+		return nil, err
+	}
+	if user.Status == models.UserTerminated {
+		return nil, errors.New("user is already terminated")
+	}
+
+	terminationDate, err := time.ParseInLocation("2006-01-02", request.TerminationDate, time.Local)
+	if err != nil {
+		return nil, errors.New("invalid termination date format, use YYYY-MM-DD")
+	}
+	now := time.Now()
+	if terminationDate.After(now) {
+		return nil, errors.New("termination date cannot be in the future")
+	}
+
+	data := &repositories.TerminationData{
+		UserID:              id,
+		TerminationDate:     terminationDate,
+		PerformedBy:         adminID,
+		CancellationComment: "Annullata per cessazione del rapporto di lavoro",
+	}
+	if request.Reason != nil && strings.TrimSpace(*request.Reason) != "" {
+		data.CancellationComment += ": " + strings.TrimSpace(*request.Reason)
+	}
+
+	// Turno aperto: lo chiudo a fine giornata di cessazione (o adesso, se prima) rispettando la sequenza
+	// ENTRATA -> (PAUSA_INIZIO -> PAUSA_FINE)* -> USCITA, quindi chiudendo prima l'eventuale pausa
+	lastTimbratura, err := s.timbratureRepository.GetLastTimbratureByUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching last timbratura: %w", err)
+	}
+	var lastAction *models.ActionType
+	if lastTimbratura != nil {
+		lastAction = &lastTimbratura.ActionType
+	}
+	if state := workStateAfter(lastAction); state != models.WorkStateNotWorking {
+		closeAt := terminationDate.AddDate(0, 0, 1).Add(-time.Second)
+		if closeAt.After(now) || closeAt.Before(lastTimbratura.Timestamp) {
+			closeAt = now
+		}
+
+		if state == models.WorkStateOnBreak {
+			breakEndAt := closeAt.Add(-time.Second)
+			if breakEndAt.Before(lastTimbratura.Timestamp) {
+				breakEndAt = lastTimbratura.Timestamp
+			}
+			data.ClosingTimbrature = append(data.ClosingTimbrature, models.Timbrature{
+				UserID:     id,
+				Timestamp:  breakEndAt,
+				ActionType: models.ActionBreakEnd,
+				Location:   lastTimbratura.Location,
+			})
+		}
+		data.ClosingTimbrature = append(data.ClosingTimbrature, models.Timbrature{
+			UserID:     id,
+			Timestamp:  closeAt,
+			ActionType: models.ActionExit,
+			Location:   lastTimbratura.Location,
+		})
+	}
+
+	// Richieste in attesa che si estendono oltre la data di cessazione
+	pendingRequests, err := s.requestRepository.GetPendingByUserEndingAfter(id, terminationDate)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pending requests: %w", err)
+	}
+	for _, pending := range pendingRequests {
+		data.CancelRequestIDs = append(data.CancelRequestIDs, pending.ID)
+	}
+
+	// Le approvazioni non scalano leave_balance: il residuo é il maturato meno i giorni approvati
+	// goduti fino alla cessazione. Le ferie approvate che iniziano dopo vengono revocate e non scalano nulla
+	approvedRequests, err := s.requestRepository.GetApprovedByUser(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching approved requests: %w", err)
+	}
+	takenDays := map[models.RequestType]float32{}
+	for _, approved := range approvedRequests {
+		// Le date delle richieste arrivano dal DB senza fuso: confronto sul giorno di calendario
+		lastDay := time.Date(terminationDate.Year(), terminationDate.Month(), terminationDate.Day(), 0, 0, 0, 0, approved.StartDate.Location())
+		if approved.StartDate.After(lastDay) {
+			data.CancelRequestIDs = append(data.CancelRequestIDs, approved.ID)
+			continue
+		}
+		endDate := approved.EndDate
+		if endDate.After(lastDay) {
+			endDate = lastDay
+		}
+		takenDays[approved.RequestType] += float32(calculateWorkingDays(approved.StartDate, endDate))
+	}
+
+	balance, err := s.leaveBalanceRepository.GetByUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching leave balance: %w", err)
+	}
+	data.Settlement = &models.LeaveSettlement{
+		UserID:           id,
+		TerminationDate:  terminationDate,
+		ResidualHolidays: -takenDays[models.RequestHolidays],
+		ResidualPermits:  -takenDays[models.RequestPermits],
+		CreatedBy:        adminID,
+	}
+	if balance != nil {
+		data.Settlement.ResidualHolidays += balance.AccumulatedHolidays
+		data.Settlement.ResidualPermits += balance.AccumulatedPermits
+	}
+
+	terminated, err := s.userRepository.Terminate(data)
+	if err != nil {
+		return nil, fmt.Errorf("error terminating user: %w", err)
+	}
+	securityVersions.invalidate(id)
+	if !terminated {
+		return nil, errors.New("user is already terminated")
+	}
+
+	if err := s.authService.LogoutAll(id); err != nil {
+		return nil, fmt.Errorf("error invalidating sessions: %w", err)
+	}
+
+	result := &models.OffboardingResult{
+		CancelledRequestIDs: data.CancelRequestIDs,
+		Settlement:          data.Settlement,
+	}
+	if result.CancelledRequestIDs == nil {
+		result.CancelledRequestIDs = []int{}
+	}
+	result.ClosedTimbrature = []models.TimbratureResponse{}
+	for _, closing := range data.ClosingTimbrature {
+		result.ClosedTimbrature = append(result.ClosedTimbrature, models.TimbratureResponse(closing))
+	}
+
+	result.User, err = s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User ID %d terminated on %s by user ID %d (%d pending requests cancelled)",
+		id, request.TerminationDate, adminID, len(data.CancelRequestIDs))
+	return result, nil
+}
+
+// GetSettlement restituisce la liquidazione finale di un utente cessato
+func (s *UserService) GetSettlement(id int) (*models.LeaveSettlement, error) {
+	if _, err := s.GetUserByID(id); err != nil {
+		return nil, err
+	}
+
+	settlement, err := s.leaveBalanceRepository.GetSettlementByUserID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching settlement: %w", err)
+	}
+	if settlement == nil {
+		return nil, errors.New("settlement not found")
+	}
+
+	return settlement, nil
+}
+
+// isValidUserStatus verifica che lo stato sia tra quelli previsti
+func isValidUserStatus(status models.UserStatus) bool {
+	switch status {
+	case models.UserActive, models.UserInactive, models.UserTerminated:
+		return true
+	}
+	return false
+}
+
+// checkCanManage impedisce di gestire utenti con ruolo piú alto del proprio o con permessi che chi agisce
+// non ha: altrimenti basterebbe users:manage per cambiare email (e quindi reset password) a un superiore
+func (s *UserService) checkCanManage(actor *utils.JWTClaims, target *models.UserDetails) error {
+	if outranks(target.HierarchyLevel, actor.HierarchyLevel) {
+		return errors.New("cannot manage a user with a higher role")
+	}
+	covered, err := s.permissionService.CoversRole(actor.RoleID, target.RoleID)
+	if err != nil {
+		return fmt.Errorf("error checking role permissions: %w", err)
+	}
+	if !covered {
+		return errors.New("cannot manage a user with permissions you do not have")
+	}
+	return nil
+}
+
+// formatOptionalID formatta un ID opzionale per i log
+func formatOptionalID(id *int) string {
+	if id == nil {
+		return "none"
+	}
+	return fmt.Sprint(*id)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"merendels-backend/models"
+	"merendels-backend/repositories"
+	"strings"
+)
+
+type WorkContractService struct {
+	repository *repositories.WorkContractRepository
+}
+
+// NewWorkContractService crea una nuova istanza del servizio
+func NewWorkContractService() *WorkContractService {
+	return &WorkContractService{
+		repository: repositories.NewWorkContractRepository(),
+	}
+}
+
+// GetWorkContracts restituisce tutti i contratti di lavoro
+func (s *WorkContractService) GetWorkContracts() ([]models.WorkContract, error) {
+	contracts, err := s.repository.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching work contracts: %w", err)
+	}
+	return contracts, nil
+}
+
+// CreateWorkContract crea un nuovo contratto con le sue regole sulle pause
+func (s *WorkContractService) CreateWorkContract(request *models.SaveWorkContractRequest) (*models.WorkContract, error) {
+	contract := &models.WorkContract{}
+	if err := s.save(contract, request); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Work contract %d (%s) created", contract.ID, contract.Name)
+	return contract, nil
+}
+
+// UpdateWorkContract modifica un contratto: le nuove regole valgono per le timbrature successive
+// e per i riepiloghi ore calcolati da quel momento
+func (s *WorkContractService) UpdateWorkContract(id int, request *models.SaveWorkContractRequest) (*models.WorkContract, error) {
+	contract, err := s.repository.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching work contract: %w", err)
+	}
+	if contract == nil {
+		return nil, errors.New("work contract not found")
+	}
+
+	// Il contratto predefinito si cambia impostandone un altro, non togliendo il flag
+	if contract.IsDefault && !request.IsDefault {
+		return nil, errors.New("cannot unset the default work contract")
+	}
+
+	if err := s.save(contract, request); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Work contract %d (%s) updated", contract.ID, contract.Name)
+	return contract, nil
+}
+
+// save valida la request e salva il contratto (nuovo se ID 0)
+func (s *WorkContractService) save(contract *models.WorkContract, request *models.SaveWorkContractRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if request.BreakAfterMinutes < 0 || request.MinBreakMinutes < 0 {
+		return errors.New("minutes cannot be negative")
+	}
+	if request.BreakPolicy != models.BreakPolicyWarn && request.BreakPolicy != models.BreakPolicyEnforce {
+		return errors.New("invalid break policy")
+	}
+
+	exists, err := s.repository.ExistsByName(name, contract.ID)
+	if err != nil {
+		return fmt.Errorf("error checking work contract name: %w", err)
+	}
+	if exists {
+		return errors.New("work contract name already exists")
+	}
+
+	contract.Name = name
+	contract.BreakAfterMinutes = request.BreakAfterMinutes
+	contract.MinBreakMinutes = request.MinBreakMinutes
+	contract.BreakPolicy = request.BreakPolicy
+	contract.IsDefault = request.IsDefault
+
+	if err := s.repository.Save(contract); err != nil {
+		return fmt.Errorf("error saving work contract: %w", err)
+	}
+	return nil
+}
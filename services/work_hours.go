@@ -0,0 +1,292 @@
+package services
+
+import (
+	"fmt"
+	"merendels-backend/models"
+	"sort"
+	"time"
+)
+
+// maxWorkInterval durata massima di una coppia ENTRATA/USCITA (o di una pausa): oltre si considera la timbratura di fine dimenticata
+// e l'intervallo non viene conteggiato. É anche il margine con cui si leggono le timbrature fuori dal
+// periodo richiesto, per accoppiare i turni a cavallo dei suoi estremi
+const maxWorkInterval = 16 * time.Hour
+
+// maxSummaryDays ampiezza massima del periodo di un riepilogo
+const maxSummaryDays = 366
+
+// pairedInterval intervallo tra due timbrature: lavoro da ENTRATA/PAUSA_FINE a USCITA/PAUSA_INIZIO
+// oppure pausa da PAUSA_INIZIO a PAUSA_FINE (uscita nil se ancora in corso)
+type pairedInterval struct {
+	entrata models.Timbrature
+	uscita  *models.Timbrature
+	end     time.Time
+	isBreak bool
+}
+
+// pairTimbrature accoppia le timbrature in ordine cronologico seguendo la sequenza
+// ENTRATA -> (PAUSA_INIZIO -> PAUSA_FINE)* -> USCITA: le pause dividono il turno in piú intervalli di lavoro.
+// Le timbrature fuori sequenza e le coppie piú lunghe di maxWorkInterval vengono segnalate.
+// L'ultimo intervallo aperto da meno di maxWorkInterval é ancora in corso
+func pairTimbrature(punches []models.Timbrature, now time.Time) ([]pairedInterval, []models.UnpairedTimbratura) {
+	var intervals []pairedInterval
+	var unpaired []models.UnpairedTimbratura
+	var open *models.Timbrature      // Inizio del tratto di lavoro in corso (ENTRATA o PAUSA_FINE)
+	var breakOpen *models.Timbrature // PAUSA_INIZIO della pausa in corso
+
+	closePair := func(start, end *models.Timbrature, isBreak bool) {
+		if end.Timestamp.Sub(start.Timestamp) > maxWorkInterval {
+			unpaired = append(unpaired,
+				newUnpaired(*start, models.UnpairedExceedsDuration),
+				newUnpaired(*end, models.UnpairedExceedsDuration),
+			)
+			return
+		}
+		intervals = append(intervals, pairedInterval{entrata: *start, uscita: end, end: end.Timestamp, isBreak: isBreak})
+	}
+
+	for i := range punches {
+		punch := &punches[i]
+
+		switch punch.ActionType {
+		case models.ActionEnter:
+			if open != nil {
+				unpaired = append(unpaired, newUnpaired(*open, models.UnpairedMissingExit))
+			}
+			if breakOpen != nil {
+				unpaired = append(unpaired, newUnpaired(*breakOpen, models.UnpairedMissingBreakEnd))
+			}
+			open, breakOpen = punch, nil
+
+		case models.ActionBreakStart:
+			if open == nil {
+				unpaired = append(unpaired, newUnpaired(*punch, models.UnpairedMissingEnter))
+				continue
+			}
+			closePair(open, punch, false)
+			open, breakOpen = nil, punch
+
+		case models.ActionBreakEnd:
+			if breakOpen == nil {
+				unpaired = append(unpaired, newUnpaired(*punch, models.UnpairedMissingBreakStart))
+				continue
+			}
+			closePair(breakOpen, punch, true)
+			open, breakOpen = punch, nil
+
+		case models.ActionExit:
+			// USCITA durante la pausa: il turno si chiude all'inizio della pausa, senza fine pausa
+			if breakOpen != nil {
+				unpaired = append(unpaired, newUnpaired(*breakOpen, models.UnpairedMissingBreakEnd))
+				breakOpen = nil
+				continue
+			}
+			if open == nil {
+				unpaired = append(unpaired, newUnpaired(*punch, models.UnpairedMissingEnter))
+				continue
+			}
+			closePair(open, punch, false)
+			open = nil
+		}
+	}
+
+	pending := []struct {
+		punch   *models.Timbrature
+		isBreak bool
+		reason  models.UnpairedReason
+	}{
+		{open, false, models.UnpairedMissingExit},
+		{breakOpen, true, models.UnpairedMissingBreakEnd},
+	}
+	for _, p := range pending {
+		if p.punch == nil {
+			continue
+		}
+		if !p.punch.Timestamp.After(now) && now.Sub(p.punch.Timestamp) <= maxWorkInterval {
+			intervals = append(intervals, pairedInterval{entrata: *p.punch, end: now, isBreak: p.isBreak})
+		} else {
+			unpaired = append(unpaired, newUnpaired(*p.punch, p.reason))
+		}
+	}
+
+	sort.SliceStable(unpaired, func(i, j int) bool {
+		return unpaired[i].Timbratura.Timestamp.Before(unpaired[j].Timbratura.Timestamp)
+	})
+
+	return intervals, unpaired
+}
+
+// shiftDurations tempo lavorato e in pausa nel turno corrente (dall'ultima ENTRATA fino a now)
+func shiftDurations(punches []models.Timbrature, now time.Time) (time.Duration, time.Duration) {
+	start := -1
+	for i := range punches {
+		if punches[i].ActionType == models.ActionEnter {
+			start = i
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+
+	intervals, _ := pairTimbrature(punches[start:], now)
+
+	var worked, breaks time.Duration
+	for _, interval := range intervals {
+		if interval.isBreak {
+			breaks += interval.end.Sub(interval.entrata.Timestamp)
+		} else {
+			worked += interval.end.Sub(interval.entrata.Timestamp)
+		}
+	}
+	return worked, breaks
+}
+
+// breakWarning avviso se la giornata supera la soglia del contratto senza la pausa minima, nil altrimenti
+func breakWarning(contract *models.WorkContract, worked, breaks time.Duration) *models.WorkWarning {
+	if contract == nil || contract.MinBreakMinutes <= 0 {
+		return nil
+	}
+	if worked <= time.Duration(contract.BreakAfterMinutes)*time.Minute || breaks >= time.Duration(contract.MinBreakMinutes)*time.Minute {
+		return nil
+	}
+
+	return &models.WorkWarning{
+		Code: models.WarningBreakMissing,
+		Message: fmt.Sprintf("Worked %d minutes with %d minutes of break, contract %s requires at least %d minutes after %d minutes of work",
+			int(worked/time.Minute), int(breaks/time.Minute), contract.Name, contract.MinBreakMinutes, contract.BreakAfterMinutes),
+	}
+}
+
+// dailyDurations tempo lavorato in un giorno, per sede, e tempo in pausa
+type dailyDurations struct {
+	office time.Duration
+	smart  time.Duration
+	breaks time.Duration
+}
+
+func (d *dailyDurations) add(location models.LocationType, duration time.Duration) {
+	if location == models.LocationSmart {
+		d.smart += duration
+	} else {
+		d.office += duration
+	}
+}
+
+func (d dailyDurations) totals() models.WorkTotals {
+	return models.WorkTotals{
+		OfficeMinutes: int(d.office / time.Minute),
+		SmartMinutes:  int(d.smart / time.Minute),
+		TotalMinutes:  int((d.office + d.smart) / time.Minute),
+		BreakMinutes:  int(d.breaks / time.Minute),
+	}
+}
+
+// buildWorkSummary calcola il riepilogo del periodo [periodStart, periodEnd) (estremi a mezzanotte, ora locale).
+// Gli intervalli vengono tagliati agli estremi del periodo e divisi a mezzanotte tra i giorni.
+// Con un contratto, i giorni oltre la soglia senza la pausa minima riportano un avviso
+func buildWorkSummary(userID int, punches []models.Timbrature, periodStart, periodEnd, now time.Time, contract *models.WorkContract) *models.WorkSummary {
+	intervals, unpaired := pairTimbrature(punches, now)
+
+	summary := &models.WorkSummary{
+		UserID:   userID,
+		From:     periodStart.Format("2006-01-02"),
+		To:       periodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:     []models.DailyWorkSummary{},
+		Unpaired: []models.UnpairedTimbratura{},
+	}
+
+	// Una voce per ogni giorno del periodo, anche senza timbrature
+	dayIndex := make(map[string]int)
+	var durations []dailyDurations
+	for day := periodStart; day.Before(periodEnd); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		dayIndex[date] = len(summary.Days)
+		summary.Days = append(summary.Days, models.DailyWorkSummary{Date: date, Intervals: []models.WorkInterval{}, Warnings: []models.WorkWarning{}})
+		durations = append(durations, dailyDurations{})
+	}
+
+	for _, interval := range intervals {
+		start := laterOf(interval.entrata.Timestamp.In(time.Local), periodStart)
+		end := earlierOf(interval.end.In(time.Local), periodEnd)
+
+		for current := start; current.Before(end); {
+			nextMidnight := startOfDay(current).AddDate(0, 0, 1)
+			pieceEnd := earlierOf(end, nextMidnight)
+
+			i := dayIndex[current.Format("2006-01-02")]
+			if interval.isBreak {
+				durations[i].breaks += pieceEnd.Sub(current)
+				current = pieceEnd
+				continue
+			}
+
+			piece := models.WorkInterval{
+				EntrataID:  interval.entrata.ID,
+				Start:      current,
+				End:        pieceEnd,
+				Location:   interval.entrata.Location,
+				Minutes:    int(pieceEnd.Sub(current) / time.Minute),
+				InProgress: interval.uscita == nil,
+			}
+			if interval.uscita != nil {
+				piece.UscitaID = &interval.uscita.ID
+			}
+
+			summary.Days[i].Intervals = append(summary.Days[i].Intervals, piece)
+			durations[i].add(interval.entrata.Location, pieceEnd.Sub(current))
+
+			current = pieceEnd
+		}
+	}
+
+	// Le timbrature fuori dal periodo servono solo per accoppiare quelle dentro
+	for _, entry := range unpaired {
+		timestamp := entry.Timbratura.Timestamp.In(time.Local)
+		if timestamp.Before(periodStart) || !timestamp.Before(periodEnd) {
+			continue
+		}
+		summary.Unpaired = append(summary.Unpaired, entry)
+		summary.Days[dayIndex[timestamp.Format("2006-01-02")]].HasUnpaired = true
+	}
+
+	var period dailyDurations
+	for i := range summary.Days {
+		summary.Days[i].WorkTotals = durations[i].totals()
+		if summary.Days[i].TotalMinutes > 0 {
+			summary.WorkedDays++
+		}
+		if warning := breakWarning(contract, durations[i].office+durations[i].smart, durations[i].breaks); warning != nil {
+			summary.Days[i].Warnings = append(summary.Days[i].Warnings, *warning)
+		}
+		period.office += durations[i].office
+		period.smart += durations[i].smart
+		period.breaks += durations[i].breaks
+	}
+	summary.Totals = period.totals()
+
+	return summary
+}
+
+func newUnpaired(punch models.Timbrature, reason models.UnpairedReason) models.UnpairedTimbratura {
+	return models.UnpairedTimbratura{Timbratura: models.TimbratureResponse(punch), Reason: reason}
+}
+
+// startOfDay mezzanotte (ora locale) del giorno di t
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.In(time.Local).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
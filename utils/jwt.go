@@ -2,61 +2,113 @@ package utils
 
 import (
 	"errors"
-	"fmt"
+	"merendels-backend/config"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Secret key per firmare i token (da spostare in env variable)
-var jwtSecret = []byte("merendels-secret-2025")
-
 // JWTClaims struttura del payload JWT
 type JWTClaims struct {
 	UserID int `json:"user_id"`
 	Email string `json:"email"`
 	RoleID *int `json:"role_id"`
 	HierarchyLevel *int `json:"hierarchy_level"`
+	SessionID string `json:"sid,omitempty"` // Famiglia di refresh token a cui appartiene il token
+	Purpose string `json:"purpose,omitempty"` // Vuoto per i token di accesso, valorizzato per i token monouso (es. "mfa")
+	SecurityVersion int `json:"sv,omitempty"` // Versione di sicurezza dell'utente all'emissione, confrontata dal middleware
+	Actor *ActorClaim `json:"act,omitempty"` // Presente solo se il token é di impersonificazione (RFC 8693)
 	jwt.RegisteredClaims
 }
 
-// GenerateToken crea un nuovo token JWT
-func GenerateToken(userId int, email string, roleID *int, hierarchyLevel *int) (string, error) {
+// ActorClaim chi sta agendo per conto dell'utente del token (operatore del supporto)
+type ActorClaim struct {
+	UserID int `json:"user_id"`
+	Email string `json:"email"`
+	SecurityVersion int `json:"sv,omitempty"` // Versione di sicurezza dell'operatore, anche questa verificata dal middleware
+	ImpersonationID int `json:"impersonation_id"` // Sessione di impersonificazione, per l'audit
+}
+
+// IsImpersonated indica se il token é stato emesso per un'impersonificazione
+func (c *JWTClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// GenerateToken crea un nuovo token JWT di accesso (a breve scadenza)
+func GenerateToken(userId int, email string, roleID *int, hierarchyLevel *int, sessionID string, securityVersion int) (string, time.Time, error) {
+	// jti univoco, serve per poter revocare il singolo token (logout)
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(config.AccessTokenTTL())
+
 	// Claims con i dati utente + scadenza
 	claims := JWTClaims{
 		UserID: userId,
 		Email: email,
 		RoleID: roleID,
 		HierarchyLevel: hierarchyLevel,
+		SessionID: sessionID,
+		SecurityVersion: securityVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt: jwt.NewNumericDate(now),
 			Issuer: "merendels-backend",
+			ID: tokenID,
 		},
 	}
 
-	// Creo il token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Creo e firmo il token con la chiave attiva
+	tokenString, err := signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
 
-	// Firmo il token con la secret key
-	tokenString, err := token.SignedString(jwtSecret)
+// GenerateImpersonationToken crea un token di accesso per l'utente indicato con il claim act
+// dell'operatore. Non appartiene a nessuna famiglia di refresh token e non puó essere rinnovato
+func GenerateImpersonationToken(userId int, email string, roleID *int, hierarchyLevel *int, securityVersion int, actor *ActorClaim, ttl time.Duration) (string, time.Time, error) {
+	tokenID, err := GenerateRandomToken(16)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := JWTClaims{
+		UserID: userId,
+		Email: email,
+		RoleID: roleID,
+		HierarchyLevel: hierarchyLevel,
+		SecurityVersion: securityVersion,
+		Actor: actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt: jwt.NewNumericDate(now),
+			Issuer: "merendels-backend",
+			ID: tokenID,
+		},
+	}
+
+	tokenString, err := signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 // ValidateToken verifica e decodifica il token JWT
 func ValidateToken(tokenString string) (*JWTClaims, error) {
 	// parse + verifica token
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (any, error) {
-		// verifica che sia firmato HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return  nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	// la chiave di verifica viene scelta in base al kid (piú chiavi attive durante la rotazione)
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -73,6 +125,55 @@ func ValidateToken(tokenString string) (*JWTClaims, error) {
 		return nil, errors.New("invalid token claims")
 	}
 
+	// I token con uno scopo specifico (es. challenge MFA) non danno accesso alle API
+	if claims.Purpose != "" {
+		return nil, errors.New("token cannot be used for authentication")
+	}
+
+	return claims, nil
+}
+
+// GeneratePurposeToken crea un token a breve scadenza utilizzabile solo per lo scopo indicato
+// (es. completare il login con il secondo fattore). userID é 0 se il token non riguarda
+// un utente giá esistente (es. invito alla registrazione)
+func GeneratePurposeToken(purpose string, userID int, email string, ttl time.Duration) (string, error) {
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := JWTClaims{
+		UserID: userID,
+		Email: email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt: jwt.NewNumericDate(now),
+			Issuer: "merendels-backend",
+			ID: tokenID,
+		},
+	}
+
+	return signClaims(claims)
+}
+
+// ValidatePurposeToken verifica un token generato da GeneratePurposeToken per lo scopo indicato
+func ValidatePurposeToken(tokenString, purpose string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+
+	if purpose == "" || claims.Purpose != purpose {
+		return nil, errors.New("token purpose mismatch")
+	}
+
 	return claims, nil
 }
 
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"merendels-backend/config"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey rappresenta una chiave di firma/verifica identificata da kid
+type signingKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	SignKey   any // nil per chiavi solo di verifica (es. chiavi in dismissione)
+	VerifyKey any
+}
+
+// keyRing contiene tutte le chiavi accettate in verifica e quella attiva per la firma
+type keyRing struct {
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var signingKeys = &keyRing{keys: make(map[string]*signingKey)}
+
+// JWK chiave pubblica in formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet insieme di chiavi pubbliche esposto su /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadSigningKeys carica le chiavi di firma dalla configurazione.
+//
+// JWT_KEYS contiene una lista separata da virgole di voci "kid:alg:path", dove alg é
+// HS256, RS256 o EdDSA e path punta al segreto (HS256) o alla chiave PEM. Le chiavi
+// PEM pubbliche sono accettate solo in verifica, utile durante la rotazione.
+// JWT_ACTIVE_KID sceglie la chiave usata per firmare (default: la prima).
+// In alternativa JWT_SECRET configura una singola chiave HS256 con kid "default".
+func LoadSigningKeys() error {
+	keys := make(map[string]*signingKey)
+	var order []string
+
+	if entries := strings.TrimSpace(config.GetEnv("JWT_KEYS", "")); entries != "" {
+		for _, entry := range strings.Split(entries, ",") {
+			key, err := parseKeyEntry(strings.TrimSpace(entry))
+			if err != nil {
+				return err
+			}
+			if _, exists := keys[key.KID]; exists {
+				return fmt.Errorf("duplicate JWT key id %q", key.KID)
+			}
+			keys[key.KID] = key
+			order = append(order, key.KID)
+		}
+	} else if secret := config.GetEnv("JWT_SECRET", ""); secret != "" {
+		if len(secret) < 32 {
+			return errors.New("JWT_SECRET must be at least 32 bytes")
+		}
+		keys["default"] = &signingKey{
+			KID:       "default",
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(secret),
+			VerifyKey: []byte(secret),
+		}
+		order = append(order, "default")
+	} else {
+		return errors.New("no JWT signing key configured: set JWT_KEYS or JWT_SECRET")
+	}
+
+	activeKID := config.GetEnv("JWT_ACTIVE_KID", order[0])
+	active, exists := keys[activeKID]
+	if !exists {
+		return fmt.Errorf("JWT_ACTIVE_KID %q not found in configured keys", activeKID)
+	}
+	if active.SignKey == nil {
+		return fmt.Errorf("JWT key %q has no private key and cannot be used for signing", activeKID)
+	}
+
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+	signingKeys.keys = keys
+	signingKeys.active = active
+
+	return nil
+}
+
+// parseKeyEntry interpreta una voce "kid:alg:path" di JWT_KEYS
+func parseKeyEntry(entry string) (*signingKey, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid JWT key entry %q, expected kid:alg:path", entry)
+	}
+	kid, alg, path := parts[0], strings.ToUpper(parts[1]), parts[2]
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWT key %q: %w", kid, err)
+	}
+
+	key := &signingKey{KID: kid}
+
+	switch alg {
+	case "HS256":
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("JWT key %q: HS256 secret must be at least 32 bytes", kid)
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.SignKey = secret
+		key.VerifyKey = secret
+
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+		if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.SignKey = privateKey
+			key.VerifyKey = &privateKey.PublicKey
+		} else if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			key.VerifyKey = publicKey
+		} else {
+			return nil, fmt.Errorf("JWT key %q: invalid RSA PEM key", kid)
+		}
+
+	case "EDDSA":
+		key.Method = jwt.SigningMethodEdDSA
+		if privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			edPrivateKey := privateKey.(ed25519.PrivateKey)
+			key.SignKey = edPrivateKey
+			key.VerifyKey = edPrivateKey.Public()
+		} else if publicKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			key.VerifyKey = publicKey
+		} else {
+			return nil, fmt.Errorf("JWT key %q: invalid Ed25519 PEM key", kid)
+		}
+
+	default:
+		return nil, fmt.Errorf("JWT key %q: unsupported algorithm %q", kid, parts[1])
+	}
+
+	return key, nil
+}
+
+// signClaims firma i claims con la chiave attiva, impostando l'header kid
+func signClaims(claims jwt.Claims) (string, error) {
+	signingKeys.mu.RLock()
+	active := signingKeys.active
+	signingKeys.mu.RUnlock()
+
+	if active == nil {
+		return "", errors.New("JWT signing keys not loaded")
+	}
+
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.KID
+
+	return token.SignedString(active.SignKey)
+}
+
+// verificationKey restituisce la chiave di verifica per il kid del token,
+// controllando che l'algoritmo corrisponda a quello della chiave
+func verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	signingKeys.mu.RLock()
+	key, exists := signingKeys.keys[kid]
+	signingKeys.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.VerifyKey, nil
+}
+
+// GetJWKS restituisce le chiavi pubbliche (RSA/Ed25519) in formato JWKS.
+// Le chiavi HS256 sono simmetriche e non vengono mai esposte
+func GetJWKS() JWKSet {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+
+	kids := make([]string, 0, len(signingKeys.keys))
+	for kid := range signingKeys.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := signingKeys.keys[kid]
+		switch publicKey := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.KID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.KID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"merendels-backend/config"
+	"net"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mailer invia email testuali. Implementazioni diverse per produzione e sviluppo
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailerFromConfig restituisce un SMTPMailer se SMTP_HOST é configurato,
+// altrimenti un LogMailer che stampa le email nel log (sviluppo)
+func NewMailerFromConfig() Mailer {
+	host := config.GetEnv("SMTP_HOST", "")
+	if host == "" {
+		return &LogMailer{}
+	}
+
+	return &SMTPMailer{
+		Host:     host,
+		Port:     config.GetEnv("SMTP_PORT", "25"),
+		Username: config.GetEnv("SMTP_USERNAME", ""),
+		Password: config.GetEnv("SMTP_PASSWORD", ""),
+		From:     config.GetEnv("SMTP_FROM", "noreply@merendels.local"),
+	}
+}
+
+// SMTPMailer invia email tramite un server SMTP (anche un catch-all locale come MailHog)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	// Evito header injection tramite destinatario o oggetto
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+
+	message := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	// Autenticazione solo se configurata (i server catch-all locali non la richiedono)
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{to}, []byte(message))
+}
+
+// linkTokenPattern parametro token dei link di reset e invito
+var linkTokenPattern = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// LogMailer non invia nulla, scrive l'email nel log oscurando i token dei link:
+// chi legge i log non deve poter usare reset o inviti altrui
+type LogMailer struct{}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	body = linkTokenPattern.ReplaceAllString(body, "${1}[REDACTED]")
+	log.Printf("Email (not sent, SMTP_HOST not configured) to %s - %s:\n%s", to, subject, body)
+	return nil
+}
@@ -0,0 +1,366 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcDiscoveryTTL per quanto tempo restano in cache discovery document e chiavi dell'identity provider
+const oidcDiscoveryTTL = time.Hour
+
+// oidcKeyRefreshInterval intervallo minimo tra due download del JWKS per kid sconosciuti
+const oidcKeyRefreshInterval = time.Minute
+
+// OIDCDiscovery sottoinsieme del documento /.well-known/openid-configuration
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIDTokenClaims claims dell'ID token usati per identificare l'utente
+type OIDCIDTokenClaims struct {
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	Name          string       `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool accetta sia true/false che "true"/"false" (alcuni provider usano stringhe)
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	*b = flexibleBool(strings.EqualFold(value, "true"))
+	return nil
+}
+
+// OIDCProvider client verso un identity provider OpenID Connect.
+// Discovery e chiavi pubbliche sono scaricate alla prima richiesta e tenute in cache
+type OIDCProvider struct {
+	issuer     string
+	httpClient *http.Client
+
+	mu            sync.RWMutex
+	discovery     *OIDCDiscovery
+	discoveredAt  time.Time
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider crea il client per l'issuer indicato
+func NewOIDCProvider(issuer string) *OIDCProvider {
+	return &OIDCProvider{
+		issuer:     strings.TrimRight(issuer, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]any),
+	}
+}
+
+// Issuer restituisce l'issuer configurato
+func (p *OIDCProvider) Issuer() string {
+	return p.issuer
+}
+
+// Discover restituisce il discovery document, scaricandolo se assente o scaduto
+func (p *OIDCProvider) Discover() (*OIDCDiscovery, error) {
+	p.mu.RLock()
+	discovery, discoveredAt := p.discovery, p.discoveredAt
+	p.mu.RUnlock()
+
+	if discovery != nil && time.Since(discoveredAt) < oidcDiscoveryTTL {
+		return discovery, nil
+	}
+
+	var fetched OIDCDiscovery
+	if err := p.getJSON(p.issuer+"/.well-known/openid-configuration", &fetched); err != nil {
+		return nil, fmt.Errorf("error fetching OIDC discovery document: %w", err)
+	}
+	if strings.TrimRight(fetched.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer mismatch: %q", fetched.Issuer)
+	}
+	if fetched.AuthorizationEndpoint == "" || fetched.TokenEndpoint == "" || fetched.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is incomplete")
+	}
+
+	p.mu.Lock()
+	p.discovery = &fetched
+	p.discoveredAt = time.Now()
+	p.mu.Unlock()
+
+	return &fetched, nil
+}
+
+// AuthorizationURL costruisce l'URL verso cui mandare il browser (authorization code + PKCE S256)
+func (p *OIDCProvider) AuthorizationURL(clientID, redirectURL string, scopes []string, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", clientID)
+	params.Set("redirect_uri", redirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// ExchangeCode scambia il codice di autorizzazione e restituisce l'ID token grezzo
+func (p *OIDCProvider) ExchangeCode(clientID, clientSecret, redirectURL, code, codeVerifier string) (string, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", clientID)
+
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("error calling OIDC token endpoint: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("error reading OIDC token response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC token endpoint returned %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("error decoding OIDC token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("OIDC token response has no id_token")
+	}
+
+	return tokens.IDToken, nil
+}
+
+// VerifyIDToken verifica firma, issuer, audience, scadenza e nonce dell'ID token
+func (p *OIDCProvider) VerifyIDToken(rawIDToken, clientID, nonce string) (*OIDCIDTokenClaims, error) {
+	claims := &OIDCIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, p.idTokenKey,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id token: missing subject")
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// idTokenKey restituisce la chiave pubblica dell'identity provider per il kid del token.
+// Un kid sconosciuto provoca un nuovo download del JWKS (rotazione delle chiavi lato provider)
+func (p *OIDCProvider) idTokenKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	p.mu.RLock()
+	recentlyFetched := time.Since(p.keysFetchedAt) < oidcKeyRefreshInterval
+	p.mu.RUnlock()
+	if recentlyFetched {
+		return nil, fmt.Errorf("unknown OIDC signing key: %q", kid)
+	}
+
+	if err := p.refreshKeys(); err != nil {
+		return nil, err
+	}
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown OIDC signing key: %q", kid)
+}
+
+// lookupKey cerca la chiave per kid; senza kid va bene solo se il provider ne pubblica una sola
+func (p *OIDCProvider) lookupKey(kid string) any {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if time.Since(p.keysFetchedAt) >= oidcDiscoveryTTL {
+		return nil
+	}
+	if kid == "" {
+		if len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key
+			}
+		}
+		return nil
+	}
+	return p.keys[kid]
+}
+
+// refreshKeys scarica il JWKS dell'identity provider
+func (p *OIDCProvider) refreshKeys() error {
+	discovery, err := p.Discover()
+	if err != nil {
+		return err
+	}
+
+	var set JWKSet
+	if err := p.getJSON(discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("error fetching OIDC JWKS: %w", err)
+	}
+
+	keys := make(map[string]any)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parsePublicJWK(jwk)
+		if err != nil {
+			// Chiavi di tipo non supportato vengono ignorate
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// getJSON esegue una GET e decodifica la risposta JSON
+func (p *OIDCProvider) getJSON(url string, target any) error {
+	response, err := p.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", response.StatusCode, url)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(target)
+}
+
+// parsePublicJWK converte una JWK RSA o EC nella chiave pubblica corrispondente
+func parsePublicJWK(jwk JWK) (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", jwk.Crv)
+		}
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// decodeJWKInt decodifica un intero big-endian in base64url
+func decodeJWKInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("missing JWK parameter")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// PKCEChallenge calcola il code_challenge S256 per il code_verifier (RFC 7636)
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
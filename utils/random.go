@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateRandomToken genera una stringa esadecimale casuale di nBytes byte
+func GenerateRandomToken(nBytes int) (string, error) {
+	tokenBytes := make([]byte, nBytes)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
+
+// HashToken calcola lo SHA-256 di un token opaco, da salvare al posto del token in chiaro
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
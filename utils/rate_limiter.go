@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter limita le richieste per chiave (IP, utente, API key) con un token bucket:
+// ogni chiave ha al massimo limit gettoni, che si ricaricano in modo continuo in period
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	period    time.Duration
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// RateLimitResult esito del controllo, con i valori per gli header RateLimit-*
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Tempo per tornare al bucket pieno
+	RetryAfter time.Duration // Solo se Allowed é false: tempo per il prossimo gettone
+}
+
+// NewRateLimiter crea un limiter con limit richieste ogni period
+func NewRateLimiter(limit int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		period:    period,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow consuma un gettone della chiave, se disponibile
+func (l *RateLimiter) Allow(key string) RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	// Gettoni ricaricati al secondo
+	rate := float64(l.limit) / l.period.Seconds()
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(l.limit), updatedAt: now}
+		l.buckets[key] = bucket
+	} else {
+		elapsed := now.Sub(bucket.updatedAt).Seconds()
+		bucket.tokens = math.Min(float64(l.limit), bucket.tokens+elapsed*rate)
+		bucket.updatedAt = now
+	}
+
+	result := RateLimitResult{Limit: l.limit}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(bucket.tokens))
+	result.Reset = secondsToDuration((float64(l.limit) - bucket.tokens) / rate)
+
+	return result
+}
+
+// sweep elimina i bucket inattivi da almeno un periodo (sarebbero comunque pieni),
+// cosí la mappa non cresce con ogni IP visto
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updatedAt) >= l.period {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}